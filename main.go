@@ -8,9 +8,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"yujian-backend/pkg/biz/achievement"
+	"yujian-backend/pkg/biz/book"
+	"yujian-backend/pkg/biz/post"
+	"yujian-backend/pkg/biz/seat"
 	"yujian-backend/pkg/config"
 	"yujian-backend/pkg/db"
 	"yujian-backend/pkg/es"
+	"yujian-backend/pkg/file"
 	mylog "yujian-backend/pkg/log"
 )
 
@@ -29,6 +34,12 @@ func main() {
 
 	db.InitDB()
 	es.InitESClient()
+	book.BackfillRatings()
+	file.InitMinio()
+	seat.StartNoShowReleaser()
+	achievement.StartEngine()
+	post.StartAttachmentGC()
+	post.StartDraftPurger()
 
 	// 启动app
 	r := gin.Default()
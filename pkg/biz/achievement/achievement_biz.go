@@ -0,0 +1,177 @@
+package achievement
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/config"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+// GetChallenge 获取自己某一年的阅读挑战及进度, 未设置时返回默认目标
+func GetChallenge() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ChallengeResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		year, err := strconv.Atoi(c.Param("year"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ChallengeResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid year"},
+			})
+			return
+		}
+		repository := db.GetAchievementRepository()
+		challenge, err := repository.GetChallenge(user.Id, year)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ChallengeResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get challenge"},
+			})
+			return
+		}
+		finished, err := repository.CountBooksReadInYear(user.Id, year)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ChallengeResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to count books"},
+			})
+			return
+		}
+
+		challengeDTO := &model.ReadingChallengeDTO{Year: year, Goal: config.Config.Achievement.DefaultYearlyGoal, Finished: finished}
+		if challenge != nil {
+			challengeDTO.Goal = challenge.Goal
+			challengeDTO.CompletedAt = challenge.CompletedAt
+		}
+		challengeDTO.Completed = challengeDTO.Goal > 0 && finished >= int64(challengeDTO.Goal)
+		c.JSON(http.StatusOK, model.ChallengeResponse{
+			BaseResp:  model.BaseResp{Code: http.StatusOK},
+			Challenge: challengeDTO,
+		})
+	}
+}
+
+// SetChallenge 设置今年或明年的阅读目标
+func SetChallenge() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		year, err := strconv.Atoi(c.Param("year"))
+		currentYear := time.Now().Year()
+		if err != nil || year < currentYear || year > currentYear+1 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "can only set goal for this year or next year"})
+			return
+		}
+		var req model.SetChallengeRequest
+		if err = c.ShouldBindJSON(&req); err != nil || req.Goal <= 0 || req.Goal > config.Config.Achievement.MaxYearlyGoal {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid goal"})
+			return
+		}
+		if err = db.GetAchievementRepository().SetChallengeGoal(user.Id, year, req.Goal); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to set goal"})
+			return
+		}
+		// 调低目标后可能已经完成
+		go CheckChallenge(user.Id, year)
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// ListBadges 获取全部可获得的徽章
+func ListBadges() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		badges, err := db.GetAchievementRepository().ListBadges()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BadgeListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list badges"},
+			})
+			return
+		}
+		badgeDTOs := make([]*model.BadgeDTO, len(badges))
+		for i, badge := range badges {
+			badgeDTOs[i] = badge.Transfer()
+		}
+		c.JSON(http.StatusOK, model.BadgeListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Badges:   badgeDTOs,
+		})
+	}
+}
+
+// GetUserBadges 个人主页: 获取用户获得的徽章
+func GetUserBadges() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BadgeListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid user ID"},
+			})
+			return
+		}
+		badges, err := db.GetAchievementRepository().ListUserBadges(userId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BadgeListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list badges"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.BadgeListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Badges:   badges,
+		})
+	}
+}
+
+// CreateBadge 创建徽章规则(管理员)
+func CreateBadge() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req model.CreateBadgeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"})
+			return
+		}
+		req.Code = strings.TrimSpace(req.Code)
+		if _, ok := model.MetricEvents[req.Metric]; !ok || req.Code == "" || req.Name == "" || req.Threshold <= 0 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid badge"), Code: http.StatusBadRequest, ErrMsg: "code, name, a known metric and a positive threshold are required"})
+			return
+		}
+		badge := &model.BadgeDO{
+			Code:        req.Code,
+			Name:        req.Name,
+			Description: req.Description,
+			Icon:        req.Icon,
+			Metric:      req.Metric,
+			Threshold:   req.Threshold,
+			Enabled:     true,
+		}
+		repository := db.GetAchievementRepository()
+		if exists, err := repository.ExistsBadgeCode(badge.Code); err != nil || exists {
+			c.JSON(http.StatusConflict, model.BaseResp{Error: err, Code: http.StatusConflict, ErrMsg: "badge code already exists"})
+			return
+		}
+		if _, err := repository.CreateBadge(badge); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create badge"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BadgeListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Badges:   []*model.BadgeDTO{badge.Transfer()},
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package achievement
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"yujian-backend/pkg/biz/notification"
+	"yujian-backend/pkg/config"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+)
+
+var events chan model.AchievementEvent
+
+// StartEngine 启动徽章规则引擎, 按顺序消费业务事件
+func StartEngine() {
+	queueSize := config.Config.Achievement.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+	events = make(chan model.AchievementEvent, queueSize)
+	go func() {
+		for event := range events {
+			evaluate(event)
+		}
+	}()
+}
+
+// Publish 发布成就事件, 队列已满或引擎未启动时丢弃事件, 不阻塞业务请求
+func Publish(userId int64, eventType string) {
+	select {
+	case events <- model.AchievementEvent{Type: eventType, UserId: userId}:
+	default:
+		log.GetLogger().Warnf("achievement event dropped: user %d, type %s", userId, eventType)
+	}
+}
+
+// evaluate 评估与事件相关的徽章规则, 达成时授予徽章并通知
+func evaluate(event model.AchievementEvent) {
+	var metrics []string
+	for metric, eventTypes := range model.MetricEvents {
+		if slices.Contains(eventTypes, event.Type) {
+			metrics = append(metrics, metric)
+		}
+	}
+	if len(metrics) > 0 {
+		evaluateBadges(event.UserId, metrics)
+	}
+	if event.Type == model.EventShelf || event.Type == model.EventProgress {
+		CheckChallenge(event.UserId, time.Now().Year())
+	}
+}
+
+func evaluateBadges(userId int64, metrics []string) {
+	repository := db.GetAchievementRepository()
+	badges, err := repository.ListBadgesByMetrics(metrics)
+	if err != nil {
+		log.GetLogger().Errorf("failed to list badges: %v", err)
+		return
+	}
+	earned, err := repository.GetEarnedBadgeIds(userId)
+	if err != nil {
+		log.GetLogger().Errorf("failed to get badges of user %d: %v", userId, err)
+		return
+	}
+
+	counts := make(map[string]int64)
+	for _, badge := range badges {
+		if earned[badge.Id] {
+			continue
+		}
+		count, ok := counts[badge.Metric]
+		if !ok {
+			if count, err = repository.CountMetric(userId, badge.Metric); err != nil {
+				log.GetLogger().Errorf("failed to count %s of user %d: %v", badge.Metric, userId, err)
+				continue
+			}
+			counts[badge.Metric] = count
+		}
+		if count < badge.Threshold {
+			continue
+		}
+		added, err := repository.AwardBadge(userId, badge.Id)
+		if err != nil {
+			log.GetLogger().Errorf("failed to award badge %d to user %d: %v", badge.Id, userId, err)
+			continue
+		}
+		if added {
+			notification.Send(userId, model.NotifyBadgeEarned, "获得新徽章",
+				fmt.Sprintf("恭喜你获得徽章「%s」: %s", badge.Name, badge.Description), badge.Id)
+		}
+	}
+}
+
+// CheckChallenge 检查年度阅读挑战是否完成, 首次完成时通知
+func CheckChallenge(userId int64, year int) {
+	repository := db.GetAchievementRepository()
+	challenge, err := repository.GetChallenge(userId, year)
+	if err != nil {
+		log.GetLogger().Errorf("failed to get challenge of user %d: %v", userId, err)
+		return
+	}
+	if challenge == nil || challenge.CompletedAt != nil {
+		return
+	}
+	finished, err := repository.CountBooksReadInYear(userId, year)
+	if err != nil {
+		log.GetLogger().Errorf("failed to count books read by user %d: %v", userId, err)
+		return
+	}
+	if finished < int64(challenge.Goal) {
+		return
+	}
+	marked, err := repository.MarkChallengeCompleted(challenge.Id)
+	if err != nil {
+		log.GetLogger().Errorf("failed to complete challenge %d: %v", challenge.Id, err)
+		return
+	}
+	if marked {
+		notification.Send(userId, model.NotifyChallengeCompleted, "完成年度阅读挑战",
+			fmt.Sprintf("你已读完%d本书, 完成了%d年的阅读目标", finished, year), challenge.Id)
+	}
+}
@@ -16,24 +16,22 @@ import (
 // jwt密钥
 var jwtKey = []byte("your_secret_key")
 
+// MiddleWareAuth 校验登录凭证并把当前用户放入上下文
+// 没有Authorization头时按匿名访问继续, 由各接口自行判断是否需要登录; 凭证无效时中止请求
 func MiddleWareAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//验证登录凭证
 		// 从请求头中提取 JWT 令牌
 		authHeader := c.GetHeader("Authorization")
 		if len(authHeader) == 0 {
-			c.JSON(http.StatusUnauthorized, model.BaseResp{
-				Error:  nil,
-				Code:   http.StatusUnauthorized,
-				ErrMsg: "Authorization header is required",
-			})
+			c.Next()
 			return
 		}
 
 		// 检查 Authorization 头的格式
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader || len(tokenString) == 0 {
-			c.JSON(http.StatusUnauthorized, model.BaseResp{
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.BaseResp{
 				Error:  nil,
 				Code:   http.StatusUnauthorized,
 				ErrMsg: "Invalid token format",
@@ -48,14 +46,14 @@ func MiddleWareAuth() gin.HandlerFunc {
 		})
 		if err != nil {
 			if errors.Is(err, jwt.ErrSignatureInvalid) {
-				c.JSON(http.StatusUnauthorized, model.BaseResp{
+				c.AbortWithStatusJSON(http.StatusUnauthorized, model.BaseResp{
 					Error:  err,
 					Code:   http.StatusUnauthorized,
 					ErrMsg: "Invalid token signature",
 				})
 				return
 			}
-			c.JSON(http.StatusUnauthorized, model.BaseResp{
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.BaseResp{
 				Error:  err,
 				Code:   http.StatusUnauthorized,
 				ErrMsg: "Invalid or expired token",
@@ -63,7 +61,7 @@ func MiddleWareAuth() gin.HandlerFunc {
 			return
 		}
 		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, model.BaseResp{
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.BaseResp{
 				Error:  nil,
 				Code:   http.StatusUnauthorized,
 				ErrMsg: "Invalid token",
@@ -74,7 +72,7 @@ func MiddleWareAuth() gin.HandlerFunc {
 		username := claims.Username
 
 		if user, err := db.GetUserRepository().GetUserByName(username); err != nil {
-			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: errors.New("internal server error"), Code: http.StatusInternalServerError, ErrMsg: "internal server error"})
+			c.AbortWithStatusJSON(http.StatusInternalServerError, model.BaseResp{Error: errors.New("internal server error"), Code: http.StatusInternalServerError, ErrMsg: "internal server error"})
 			return
 		} else {
 			c.Set("user", user)
@@ -84,6 +82,31 @@ func MiddleWareAuth() gin.HandlerFunc {
 	}
 }
 
+// RequireRole 校验当前用户是否拥有给定角色之一,需挂在MiddleWareAuth之后
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		user, _ := obj.(*model.UserDTO)
+		if !exists || user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.BaseResp{
+				Error:  nil,
+				Code:   http.StatusUnauthorized,
+				ErrMsg: "unauthorized",
+			})
+			return
+		}
+		if !user.HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.BaseResp{
+				Error:  nil,
+				Code:   http.StatusForbidden,
+				ErrMsg: "permission denied",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
 // UserLogin 返回一个处理用户登录的中间件函数
 // 该函数验证用户身份信息，并在成功验证后返回一个令牌
 func UserLogin() gin.HandlerFunc {
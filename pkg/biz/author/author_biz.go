@@ -0,0 +1,233 @@
+package author
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+// GetAuthorPage 作者页: 作者信息及其全部图书
+func GetAuthorPage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorId, err := strconv.ParseInt(c.Param("authorId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid author ID"},
+			})
+			return
+		}
+
+		repository := db.GetAuthorRepository()
+		author, err := repository.GetAuthorById(authorId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "author not found"},
+			})
+			return
+		}
+		books, err := repository.ListBooksByAuthorId(authorId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list books"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.AuthorPageResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Author:   author,
+			Books:    books,
+		})
+	}
+}
+
+// CreateAuthor 创建作者(馆员)
+func CreateAuthor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req model.AuthorDTO
+		if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+			c.JSON(http.StatusBadRequest, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "author name is required"},
+			})
+			return
+		}
+		req.Id = 0
+		id, err := db.GetAuthorRepository().CreateAuthor(&req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create author"},
+			})
+			return
+		}
+		req.Id = id
+		c.JSON(http.StatusOK, model.AuthorPageResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Author:   &req,
+		})
+	}
+}
+
+// UpdateAuthor 更新作者的名字、别名和简介(馆员)
+func UpdateAuthor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorId, err := strconv.ParseInt(c.Param("authorId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid author ID"},
+			})
+			return
+		}
+		var req model.AuthorDTO
+		if err = c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+			c.JSON(http.StatusBadRequest, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "author name is required"},
+			})
+			return
+		}
+
+		repository := db.GetAuthorRepository()
+		if _, err = repository.GetAuthorById(authorId); err != nil {
+			c.JSON(http.StatusNotFound, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "author not found"},
+			})
+			return
+		}
+		req.Id = authorId
+		if err = repository.UpdateAuthor(&req); err != nil {
+			c.JSON(http.StatusInternalServerError, model.AuthorPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to update author"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.AuthorPageResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Author:   &req,
+		})
+	}
+}
+
+// GetSeriesPage 系列页: 系列信息及按序号排列的图书
+func GetSeriesPage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seriesId, err := strconv.ParseInt(c.Param("seriesId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.SeriesPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid series ID"},
+			})
+			return
+		}
+
+		repository := db.GetAuthorRepository()
+		series, err := repository.GetSeriesById(seriesId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.SeriesPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "series not found"},
+			})
+			return
+		}
+		books, err := repository.ListBooksBySeriesId(seriesId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.SeriesPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list books"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.SeriesPageResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Series:   series,
+			Books:    books,
+		})
+	}
+}
+
+// CreateSeries 创建系列(馆员)
+func CreateSeries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req model.SeriesDTO
+		if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+			c.JSON(http.StatusBadRequest, model.SeriesPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "series name is required"},
+			})
+			return
+		}
+		req.Id = 0
+		id, err := db.GetAuthorRepository().CreateSeries(&req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.SeriesPageResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create series"},
+			})
+			return
+		}
+		req.Id = id
+		c.JSON(http.StatusOK, model.SeriesPageResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Series:   &req,
+		})
+	}
+}
+
+// AddBookToSeries 将图书加入系列(馆员)
+func AddBookToSeries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seriesId, err := strconv.ParseInt(c.Param("seriesId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid series ID"})
+			return
+		}
+		var req model.AddBookToSeriesRequest
+		if err = c.ShouldBindJSON(&req); err != nil || req.BookId <= 0 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid request body"), Code: http.StatusBadRequest, ErrMsg: "book_id is required"})
+			return
+		}
+
+		repository := db.GetAuthorRepository()
+		if _, err = repository.GetSeriesById(seriesId); err != nil {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "series not found"})
+			return
+		}
+		if err = repository.SetBookSeries(req.BookId, seriesId, req.Index); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "book not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to add book to series"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// SetBookWork 修正图书的作品归并(馆员): 归入指定作品, 或不指定作品时拆成独立的新作品
+func SetBookWork() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookId, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid book ID"})
+			return
+		}
+		var req model.SetBookWorkRequest
+		if err = c.ShouldBindJSON(&req); err != nil || req.WorkId < 0 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid request body"), Code: http.StatusBadRequest, ErrMsg: "invalid work_id"})
+			return
+		}
+
+		workId, err := db.GetAuthorRepository().SetBookWork(bookId, req.WorkId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "book or work not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to set book work"})
+			return
+		}
+		c.JSON(http.StatusOK, model.SetBookWorkResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			WorkId:   workId,
+		})
+	}
+}
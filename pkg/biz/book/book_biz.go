@@ -1,11 +1,17 @@
 package book
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"yujian-backend/pkg/biz/quote"
 	"yujian-backend/pkg/biz/recommend"
+	"yujian-backend/pkg/biz/suggestion"
 	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/es"
+	"yujian-backend/pkg/log"
 	"yujian-backend/pkg/model"
 )
 
@@ -32,7 +38,7 @@ func SearchBooks() func(c *gin.Context) {
 			req.PageSize = 10
 		}
 		bookRepository := db.GetBookRepository()
-		books, err := bookRepository.SearchBooks(req.Keyword, req.Category, req.Page, req.PageSize)
+		books, err := bookRepository.SearchBooks(req.Keyword, req.Category, req.Sort, req.Page, req.PageSize)
 		if err != nil {
 			//没查到
 			c.JSON(http.StatusBadRequest, model.SearchResponse{
@@ -61,7 +67,8 @@ func SearchBooks() func(c *gin.Context) {
 				Code:   http.StatusOK,
 				ErrMsg: "",
 			},
-			Books: books,
+			Books:           books,
+			SuggestPurchase: len(books) == 0,
 		})
 	}
 }
@@ -104,6 +111,36 @@ func GetBookDetail() func(c *gin.Context) {
 				recommend.RecordUserAction(user, bookDTO.Id, bookDTO.Name, bookDTO.Category)
 			}()
 		}
+
+		// 作者、系列和其他版本, 查询失败不影响详情返回
+		authorRepository := db.GetAuthorRepository()
+		authors, err := authorRepository.GetAuthorsByBookId(bookId)
+		if err != nil {
+			log.GetLogger().Warnf("failed to get authors of book %d: %v", bookId, err)
+		}
+		var series *model.SeriesDTO
+		if bookDTO.SeriesId != 0 {
+			if series, err = authorRepository.GetSeriesById(bookDTO.SeriesId); err != nil {
+				log.GetLogger().Warnf("failed to get series of book %d: %v", bookId, err)
+			}
+		}
+		editions, err := authorRepository.ListOtherEditions(bookDTO.WorkId, bookId)
+		if err != nil {
+			log.GetLogger().Warnf("failed to get other editions of book %d: %v", bookId, err)
+		}
+		rating, err := bookRepository.GetBookRating(bookId)
+		if err != nil {
+			log.GetLogger().Warnf("failed to get rating of book %d: %v", bookId, err)
+		}
+		var viewer *model.UserDTO
+		if value, exists := c.Get("user"); exists {
+			viewer, _ = value.(*model.UserDTO)
+		}
+		topQuotes, err := quote.TopQuotes(bookId, viewer)
+		if err != nil {
+			log.GetLogger().Warnf("failed to get top quotes of book %d: %v", bookId, err)
+		}
+
 		// 找到
 		c.JSON(http.StatusOK, model.BookDetailResponse{
 			BaseResp: model.BaseResp{
@@ -111,7 +148,85 @@ func GetBookDetail() func(c *gin.Context) {
 				Code:   http.StatusOK,
 				ErrMsg: "",
 			},
-			Data: *bookDTO,
+			Data:          *bookDTO,
+			Rating:        rating,
+			Authors:       authors,
+			Series:        series,
+			OtherEditions: editions,
+			TopQuotes:     topQuotes,
+		})
+	}
+}
+
+// CreateBook 图书入库(馆员), 同时写入ES并完成匹配的荐购
+func CreateBook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req model.BookInfoDTO
+		if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+			c.JSON(http.StatusBadRequest, model.CreateBookResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusBadRequest,
+					ErrMsg: "book name is required",
+				},
+			})
+			return
+		}
+		req.Id = 0
+		if req.CategoryId == 0 {
+			categoryId, err := db.GetCategoryRepository().ResolveCategory(req.Category, true)
+			if err != nil {
+				log.GetLogger().Warnf("failed to resolve category %s: %v", req.Category, err)
+			}
+			req.CategoryId = categoryId
+		}
+
+		bookRepository := db.GetBookRepository()
+		id, err := bookRepository.CreateBook(&req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.CreateBookResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusInternalServerError,
+					ErrMsg: "failed to create book",
+				},
+			})
+			return
+		}
+		req.Id = id
+
+		if err = es.Create(context.Background(), req.TransformToES()); err != nil {
+			// ES写入失败, 回滚数据库
+			if errRollback := bookRepository.DeleteBook(id); errRollback != nil {
+				err = errors.Join(err, errRollback)
+			}
+			c.JSON(http.StatusInternalServerError, model.CreateBookResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusInternalServerError,
+					ErrMsg: "failed to index book",
+				},
+			})
+			return
+		}
+
+		if err = db.GetAuthorRepository().LinkBookEntities(&req); err != nil {
+			log.GetLogger().Errorf("failed to link authors for book %d: %v", id, err)
+		}
+
+		go func() {
+			if err := suggestion.FulfillByBook(&req); err != nil {
+				log.GetLogger().Errorf("failed to fulfill suggestions for book %d: %v", id, err)
+			}
+		}()
+
+		c.JSON(http.StatusOK, model.CreateBookResponse{
+			BaseResp: model.BaseResp{
+				Error:  nil,
+				Code:   http.StatusOK,
+				ErrMsg: "",
+			},
+			BookId: id,
 		})
 	}
 }
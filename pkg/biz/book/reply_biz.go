@@ -0,0 +1,181 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/biz/notification"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+// maxReplyLength 回复内容的最大字数
+const maxReplyLength = 1000
+
+// CreateReply 回复书评或书评下的回复, 并通知书评作者和被回复的人
+func CreateReply() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		value, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ReviewReplyResponse{
+				BaseResp: model.BaseResp{
+					Code:   http.StatusUnauthorized,
+					ErrMsg: "unauthorized",
+				},
+			})
+			return
+		}
+		user, _ := value.(*model.UserDTO)
+
+		var req model.CreateReviewReplyRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.ReviewId <= 0 {
+			c.JSON(http.StatusBadRequest, model.ReviewReplyResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusBadRequest,
+					ErrMsg: "review_id is required",
+				},
+			})
+			return
+		}
+		req.Content = strings.TrimSpace(req.Content)
+		if req.Content == "" || utf8.RuneCountInString(req.Content) > maxReplyLength {
+			c.JSON(http.StatusBadRequest, model.ReviewReplyResponse{
+				BaseResp: model.BaseResp{
+					Error:  errors.New("invalid content"),
+					Code:   http.StatusBadRequest,
+					ErrMsg: "content must be 1-1000 characters",
+				},
+			})
+			return
+		}
+
+		repository := db.GetBookRepository()
+		review, err := repository.GetBookCommentById(req.ReviewId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.ReviewReplyResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusNotFound,
+					ErrMsg: "failed to find review",
+				},
+			})
+			return
+		}
+
+		reply := &model.ReviewReplyDO{
+			ReviewId:      review.Id,
+			Depth:         1,
+			UserId:        user.Id,
+			ReplyToUserId: review.PublisherId,
+			Content:       req.Content,
+			CreatedAt:     time.Now(),
+		}
+		if req.ParentId > 0 {
+			parent, err := repository.GetReviewReplyById(req.ParentId)
+			if err != nil || parent.ReviewId != review.Id {
+				c.JSON(http.StatusNotFound, model.ReviewReplyResponse{
+					BaseResp: model.BaseResp{
+						Error:  err,
+						Code:   http.StatusNotFound,
+						ErrMsg: "failed to find parent reply",
+					},
+				})
+				return
+			}
+			reply.ReplyToUserId = parent.UserId
+			reply.RootId = parent.RootId
+			if reply.RootId == 0 {
+				reply.RootId = parent.Id
+			}
+			// 超过最大层数时挂到父回复的同一层, 通过ReplyToUserId保留回复对象
+			if parent.Depth >= model.MaxReviewReplyDepth {
+				reply.ParentId = parent.ParentId
+				reply.Depth = parent.Depth
+			} else {
+				reply.ParentId = parent.Id
+				reply.Depth = parent.Depth + 1
+			}
+		}
+
+		if err = repository.CreateReviewReply(reply); err != nil {
+			c.JSON(http.StatusInternalServerError, model.ReviewReplyResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusInternalServerError,
+					ErrMsg: "create reply failed",
+				},
+			})
+			return
+		}
+
+		if review.PublisherId != user.Id {
+			notification.Send(review.PublisherId, model.NotifyReviewReply, "书评收到回复",
+				fmt.Sprintf("%s 回复了你的书评", user.Name), review.Id)
+		}
+		if reply.ReplyToUserId != review.PublisherId && reply.ReplyToUserId != user.Id {
+			notification.Send(reply.ReplyToUserId, model.NotifyReviewReply, "回复收到回复",
+				fmt.Sprintf("%s 回复了你", user.Name), review.Id)
+		}
+
+		replyDTO := reply.Transfer()
+		replyDTO.UserName = user.Name
+		c.JSON(http.StatusOK, model.ReviewReplyResponse{
+			BaseResp: model.BaseResp{
+				Code: http.StatusOK,
+			},
+			Reply: replyDTO,
+		})
+	}
+}
+
+// GetReplies 按顶层回复分页获取书评的回复
+func GetReplies() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		reviewId, err := strconv.ParseInt(c.Query("review_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ReviewReplyListResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusBadRequest,
+					ErrMsg: "invalid review id",
+				},
+			})
+			return
+		}
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 || pageSize > maxReviewPageSize {
+			pageSize = 10
+		}
+
+		replies, total, err := db.GetBookRepository().ListReviewReplies(reviewId, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ReviewReplyListResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusInternalServerError,
+					ErrMsg: "failed to get replies",
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ReviewReplyListResponse{
+			BaseResp: model.BaseResp{
+				Code: http.StatusOK,
+			},
+			Replies: replies,
+			Total:   total,
+		})
+	}
+}
@@ -1,16 +1,23 @@
 package book
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"net/http"
 	"strconv"
 	"time"
+	"yujian-backend/pkg/biz/achievement"
 	"yujian-backend/pkg/biz/recommend"
 	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/log"
 	"yujian-backend/pkg/model"
 )
 
-// CreatReview 书评发布
+// maxReviewPageSize 书评列表每页最多条数
+const maxReviewPageSize = 100
+
+// CreatReview 书评发布, 用户已评论过这本书时修改原书评
 func CreatReview() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		value, exists := c.Get("user")
@@ -30,7 +37,7 @@ func CreatReview() func(c *gin.Context) {
 		reviewsRepository := db.GetBookRepository()
 		//解析请求体
 		var ReviewRequest model.CreatReviewRequest
-		if err := c.ShouldBindJSON(ReviewRequest); err != nil {
+		if err := c.ShouldBindJSON(&ReviewRequest); err != nil {
 			//绑定失败
 			c.JSON(http.StatusBadRequest, model.CreatReviewResponse{
 				BaseResp: model.BaseResp{
@@ -41,15 +48,51 @@ func CreatReview() func(c *gin.Context) {
 			})
 			return
 		}
+		if ReviewRequest.Score < model.MinReviewScore || ReviewRequest.Score > model.MaxReviewScore {
+			c.JSON(http.StatusBadRequest, model.CreatReviewResponse{
+				BaseResp: model.BaseResp{
+					Error:  errors.New("invalid score"),
+					Code:   http.StatusBadRequest,
+					ErrMsg: "score must be between 1 and 5",
+				},
+			})
+			return
+		}
+		// 图书不存在时不创建书评和评分汇总
+		if _, err := reviewsRepository.GetBookById(ReviewRequest.BookId); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, model.CreatReviewResponse{
+					BaseResp: model.BaseResp{
+						Error:  err,
+						Code:   http.StatusNotFound,
+						ErrMsg: "book not found",
+					},
+				})
+			} else {
+				c.JSON(http.StatusInternalServerError, model.CreatReviewResponse{
+					BaseResp: model.BaseResp{
+						Error:  err,
+						Code:   http.StatusInternalServerError,
+						ErrMsg: "failed to get book",
+					},
+				})
+			}
+			return
+		}
+		// 剧透标记解析为区间单独存储
+		content, spans := model.ParseSpoilers(ReviewRequest.Content)
 		review := model.BookCommentDTO{
-			Content:     ReviewRequest.Content,
-			BookId:      ReviewRequest.BookId,
-			Score:       ReviewRequest.Score,
-			PublisherId: user.Id,
-			PostTime:    time.Now(),
-		}
-		// CreateBookComment 创建书评
-		if err := reviewsRepository.CreateBookComment(&review); err != nil {
+			Content:      content,
+			Spoiler:      ReviewRequest.Spoiler,
+			SpoilerSpans: spans,
+			BookId:       ReviewRequest.BookId,
+			Score:        ReviewRequest.Score,
+			PublisherId:  user.Id,
+			PostTime:     time.Now(),
+		}
+		// UpsertBookComment 创建或修改书评
+		created, err := reviewsRepository.UpsertBookComment(&review)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.CreatReviewResponse{
 				BaseResp: model.BaseResp{
 					Error:  err,
@@ -62,6 +105,10 @@ func CreatReview() func(c *gin.Context) {
 		go func() {
 			recommend.RecordUserAction(user, ReviewRequest.BookId)
 		}()
+		go syncRatingToES(ReviewRequest.BookId)
+		if created {
+			achievement.Publish(user.Id, model.EventReview)
+		}
 
 		c.JSON(http.StatusOK, model.CreatReviewResponse{
 			BaseResp: model.BaseResp{
@@ -69,11 +116,146 @@ func CreatReview() func(c *gin.Context) {
 				Code:   http.StatusOK,
 				ErrMsg: "",
 			},
+			Review:  &review,
+			Created: created,
+		})
+	}
+}
+
+// UpdateReview 修改自己的书评
+func UpdateReview() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		review, user, ok := loadReview(c)
+		if !ok {
+			return
+		}
+		if review.PublisherId != user.Id {
+			c.JSON(http.StatusForbidden, model.CreatReviewResponse{
+				BaseResp: model.BaseResp{
+					Error:  errors.New("forbidden"),
+					Code:   http.StatusForbidden,
+					ErrMsg: "only the author can edit this review",
+				},
+			})
+			return
+		}
+		var req model.UpdateReviewRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.CreatReviewResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusBadRequest,
+					ErrMsg: "invalid request parameters",
+				},
+			})
+			return
+		}
+		if req.Score < model.MinReviewScore || req.Score > model.MaxReviewScore {
+			c.JSON(http.StatusBadRequest, model.CreatReviewResponse{
+				BaseResp: model.BaseResp{
+					Error:  errors.New("invalid score"),
+					Code:   http.StatusBadRequest,
+					ErrMsg: "score must be between 1 and 5",
+				},
+			})
+			return
+		}
+
+		content, spans := model.ParseSpoilers(req.Content)
+		updated, err := db.GetBookRepository().EditBookComment(review.Id, &model.BookCommentDTO{
+			Content:      content,
+			Spoiler:      req.Spoiler,
+			SpoilerSpans: spans,
+			Score:        req.Score,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.CreatReviewResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusInternalServerError,
+					ErrMsg: "update review failed",
+				},
+			})
+			return
+		}
+		go syncRatingToES(review.BookId)
+
+		c.JSON(http.StatusOK, model.CreatReviewResponse{
+			BaseResp: model.BaseResp{
+				Code: http.StatusOK,
+			},
+			Review: updated,
+		})
+	}
+}
+
+// DeleteReview 删除书评, 仅作者或版主可删除
+func DeleteReview() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		review, user, ok := loadReview(c)
+		if !ok {
+			return
+		}
+		if review.PublisherId != user.Id && !user.HasRole(model.RoleModerator) {
+			c.JSON(http.StatusForbidden, model.BaseResp{
+				Error:  errors.New("forbidden"),
+				Code:   http.StatusForbidden,
+				ErrMsg: "only the author can delete this review",
+			})
+			return
+		}
+		if err := db.GetBookRepository().DeleteBookComment(review.Id); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{
+				Error:  err,
+				Code:   http.StatusInternalServerError,
+				ErrMsg: "delete review failed",
+			})
+			return
+		}
+		go syncRatingToES(review.BookId)
+
+		c.JSON(http.StatusOK, model.BaseResp{
+			Code:   http.StatusOK,
+			ErrMsg: "success",
+		})
+	}
+}
+
+// loadReview 读取路径中的书评和当前用户, 失败时已写回响应
+func loadReview(c *gin.Context) (*model.BookCommentDTO, *model.UserDTO, bool) {
+	value, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{
+			Code:   http.StatusUnauthorized,
+			ErrMsg: "unauthorized",
+		})
+		return nil, nil, false
+	}
+	user, _ := value.(*model.UserDTO)
+
+	reviewId, err := strconv.ParseInt(c.Param("reviewId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{
+			Error:  err,
+			Code:   http.StatusBadRequest,
+			ErrMsg: "invalid review id",
+		})
+		return nil, nil, false
+	}
+	review, err := db.GetBookRepository().GetBookCommentById(reviewId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResp{
+			Error:  err,
+			Code:   http.StatusNotFound,
+			ErrMsg: "failed to find review",
 		})
+		return nil, nil, false
 	}
+	return review, user, true
 }
 
-// GetReviews 根据书的id获取书评
+// GetReviews 根据书的id分页获取书评, 剧透内容默认隐藏
+// 查询参数: sort(newest/highest/lowest/helpful), stars(1-5), cursor(上一页返回的next_cursor), page, page_size, reveal_spoilers
 func GetReviews() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		//获取id
@@ -90,10 +272,22 @@ func GetReviews() func(c *gin.Context) {
 			})
 			return
 		}
-		// GetBookCommentsByBookId 根据书ID获取书评
+		query, err := parseReviewsQuery(c, bookId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ReviewsResponse{
+				BaseResp: model.BaseResp{
+					Error:  err,
+					Code:   http.StatusBadRequest,
+					ErrMsg: err.Error(),
+				},
+				Reviews: nil,
+			})
+			return
+		}
+		// ListBookComments 根据书ID分页获取书评
 		reviewsRepository := db.GetBookRepository()
 		// 查询详情
-		ReviewsDTO, err := reviewsRepository.GetBookCommentsByBookId(bookId)
+		ReviewsDTO, next, total, err := reviewsRepository.ListBookComments(query)
 		if err != nil { //没查到
 			c.JSON(http.StatusNotFound, model.ReviewsResponse{
 				BaseResp: model.BaseResp{
@@ -105,9 +299,35 @@ func GetReviews() func(c *gin.Context) {
 			})
 			return
 		}
-		// 找到
+		// 找到, 登录用户附上自己的投票
+		votes := map[int64]int{}
+		if value, exists := c.Get("user"); exists {
+			user, _ := value.(*model.UserDTO)
+			reviewIds := make([]int64, len(ReviewsDTO))
+			for i, review := range ReviewsDTO {
+				reviewIds[i] = review.Id
+			}
+			if votes, err = reviewsRepository.GetReviewVotes(user.Id, reviewIds); err != nil {
+				c.JSON(http.StatusInternalServerError, model.ReviewsResponse{
+					BaseResp: model.BaseResp{
+						Error:  err,
+						Code:   http.StatusInternalServerError,
+						ErrMsg: "failed to get votes",
+					},
+					Reviews: nil,
+				})
+				return
+			}
+		}
+		nextCursor := ""
+		if next != nil {
+			nextCursor = next.Encode()
+		}
+		reveal := c.Query("reveal_spoilers") == "true"
 		reviews := make([]model.BookCommentDTO, len(ReviewsDTO))
 		for i, review := range ReviewsDTO {
+			review.MyVote = votes[review.Id]
+			review.Content, review.SpoilerSpans = model.SpoilerView(review.Content, review.SpoilerSpans, review.Spoiler, reveal)
 			reviews[i] = *review //解引用指针
 		}
 		c.JSON(http.StatusOK, model.ReviewsResponse{
@@ -116,11 +336,49 @@ func GetReviews() func(c *gin.Context) {
 				Code:   http.StatusOK,
 				ErrMsg: "",
 			},
-			Reviews: reviews,
+			Reviews:    reviews,
+			Total:      total,
+			NextCursor: nextCursor,
 		})
 	}
 }
 
+// parseReviewsQuery 解析书评列表的查询参数
+func parseReviewsQuery(c *gin.Context, bookId int64) (*model.ListReviewsQuery, error) {
+	query := &model.ListReviewsQuery{
+		BookId: bookId,
+		Sort:   c.DefaultQuery("sort", model.ReviewSortNewest),
+	}
+	switch query.Sort {
+	case model.ReviewSortNewest, model.ReviewSortHighest, model.ReviewSortLowest, model.ReviewSortHelpful:
+	default:
+		return nil, errors.New("sort must be newest, highest, lowest or helpful")
+	}
+	if stars := c.Query("stars"); stars != "" {
+		n, err := strconv.Atoi(stars)
+		if err != nil || n < int(model.MinReviewScore) || n > int(model.MaxReviewScore) {
+			return nil, errors.New("stars must be between 1 and 5")
+		}
+		query.Stars = n
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		decoded, err := model.DecodeReviewCursor(cursor)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		query.Cursor = decoded
+	}
+	query.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	query.PageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 || query.PageSize > maxReviewPageSize {
+		query.PageSize = 20
+	}
+	return query, nil
+}
+
 // ClickLike 点赞处理函数
 func ClickLike() func(c *gin.Context) {
 	return func(c *gin.Context) {
@@ -135,7 +393,21 @@ func ClickUnlike() func(c *gin.Context) {
 	}
 }
 
+// updateClick 赞/踩书评, 再次点击同一按钮取消, 点击另一个按钮改票
 func updateClick(c *gin.Context, like bool) {
+	value, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.ClickLikeResponse{
+			BaseResp: model.BaseResp{
+				Error:  nil,
+				Code:   http.StatusUnauthorized,
+				ErrMsg: "unauthorized",
+			},
+		})
+		return
+	}
+	user, _ := value.(*model.UserDTO)
+
 	reviewRepository := db.GetBookRepository()
 	reviewId, err := strconv.ParseInt(c.Param("reviewId"), 10, 64)
 	if err != nil { //绑定失败
@@ -149,9 +421,12 @@ func updateClick(c *gin.Context, like bool) {
 		return
 	}
 
-	//用review_id查到具体的Reviews
-	ReviewDTO, err := reviewRepository.GetBookCommentById(reviewId)
-	if err != nil { // 没查到
+	vote := model.ReviewVoteLike
+	if !like {
+		vote = model.ReviewVoteDislike
+	}
+	ReviewDTO, myVote, err := reviewRepository.VoteBookComment(reviewId, user.Id, vote)
+	if errors.Is(err, gorm.ErrRecordNotFound) { // 没查到
 		c.JSON(http.StatusNotFound, model.ClickLikeResponse{
 			BaseResp: model.BaseResp{
 				Error:  err,
@@ -161,14 +436,7 @@ func updateClick(c *gin.Context, like bool) {
 		})
 		return
 	}
-	ReviewDO := ReviewDTO.Transfer()
-	if like {
-		ReviewDO.Like++
-	} else {
-		ReviewDO.Dislike++
-	}
-
-	if err := reviewRepository.UpdateBookComment(ReviewDO); err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, model.ClickLikeResponse{ //修改失败
 			BaseResp: model.BaseResp{
 				Error:  err,
@@ -179,12 +447,9 @@ func updateClick(c *gin.Context, like bool) {
 		return
 	}
 
-	if value, exists := c.Get("user"); exists {
-		user, _ := value.(*model.UserDTO)
-		go func() {
-			recommend.RecordUserAction(user, ReviewDTO.BookId)
-		}()
-	}
+	go func() {
+		recommend.RecordUserAction(user, ReviewDTO.BookId)
+	}()
 
 	//成功
 	c.JSON(http.StatusOK, model.ClickLikeResponse{
@@ -193,8 +458,27 @@ func updateClick(c *gin.Context, like bool) {
 			Code:   http.StatusOK,
 			ErrMsg: "",
 		},
-		Like:    ReviewDO.Like,
-		Dislike: ReviewDO.Dislike,
+		Like:    ReviewDTO.Like,
+		Dislike: ReviewDTO.Dislike,
+		MyVote:  myVote,
 	})
-	return
+}
+
+// syncRatingToES 评分聚合变化后同步到ES
+func syncRatingToES(bookId int64) {
+	if err := db.GetBookRepository().SyncBookToES(bookId); err != nil {
+		log.GetLogger().Errorf("failed to sync rating of book %d to ES: %v", bookId, err)
+	}
+}
+
+// BackfillRatings 启动时补算历史书评的评分聚合, 并把补算的图书同步到ES, 否则按评分排序时这些图书没有评分; 需在ES初始化之后调用
+func BackfillRatings() {
+	bookIds, err := db.GetBookRepository().BackfillBookRatings()
+	if err != nil {
+		log.GetLogger().Errorf("failed to backfill book ratings: %s", err)
+		return
+	}
+	for _, bookId := range bookIds {
+		syncRatingToES(bookId)
+	}
 }
@@ -0,0 +1,196 @@
+package category
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+// GetCategoryTree 获取分类树, 每个节点带含子分类的图书数
+func GetCategoryTree() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repository := db.GetCategoryRepository()
+		categories, err := repository.ListCategories()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.CategoryTreeResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list categories"},
+			})
+			return
+		}
+		counts, err := repository.CountBooksByCategory()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.CategoryTreeResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to count books"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.CategoryTreeResponse{
+			BaseResp:   model.BaseResp{Code: http.StatusOK},
+			Categories: buildTree(categories, counts),
+		})
+	}
+}
+
+// buildTree 将扁平的分类列表组装为树, 并自底向上累加图书数
+func buildTree(categories []*model.CategoryDO, counts map[int64]int64) []*model.CategoryDTO {
+	nodes := make(map[int64]*model.CategoryDTO, len(categories))
+	for _, category := range categories {
+		nodes[category.Id] = category.Transfer()
+	}
+	var roots []*model.CategoryDTO
+	for _, category := range categories {
+		node := nodes[category.Id]
+		if parent, ok := nodes[category.ParentId]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	var sum func(node *model.CategoryDTO) int64
+	sum = func(node *model.CategoryDTO) int64 {
+		node.BookCount = counts[node.Id]
+		for _, child := range node.Children {
+			node.BookCount += sum(child)
+		}
+		return node.BookCount
+	}
+	for _, root := range roots {
+		sum(root)
+	}
+	return roots
+}
+
+// GetCategoryBooks 分页获取分类(含子分类)下的图书
+func GetCategoryBooks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryId, err := strconv.ParseInt(c.Param("categoryId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.CategoryBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid category ID"},
+			})
+			return
+		}
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 {
+			pageSize = 10
+		}
+
+		repository := db.GetCategoryRepository()
+		category, err := repository.GetCategoryById(categoryId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.CategoryBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "category not found"},
+			})
+			return
+		}
+		books, total, err := repository.ListBooksInCategory(category, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.CategoryBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list books"},
+			})
+			return
+		}
+		categoryDTO := category.Transfer()
+		categoryDTO.BookCount = total
+		c.JSON(http.StatusOK, model.CategoryBooksResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Category: categoryDTO,
+			Books:    books,
+			Total:    total,
+		})
+	}
+}
+
+// CreateCategory 创建分类(馆员)
+func CreateCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req model.CreateCategoryRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+			c.JSON(http.StatusBadRequest, model.CategoryTreeResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "category name is required"},
+			})
+			return
+		}
+		category, err := db.GetCategoryRepository().CreateCategory(&req)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, db.ErrCategoryAliasExists) {
+				code = http.StatusConflict
+			}
+			c.JSON(code, model.CategoryTreeResponse{
+				BaseResp: model.BaseResp{Error: err, Code: model.ErrorCode(code), ErrMsg: "failed to create category"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.CategoryTreeResponse{
+			BaseResp:   model.BaseResp{Code: http.StatusOK},
+			Categories: []*model.CategoryDTO{category.Transfer()},
+		})
+	}
+}
+
+// AddAlias 给分类添加别名(馆员)
+func AddAlias() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryId, err := strconv.ParseInt(c.Param("categoryId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid category ID"})
+			return
+		}
+		var req model.AddCategoryAliasRequest
+		if err = c.ShouldBindJSON(&req); err != nil || req.Alias == "" {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "alias is required"})
+			return
+		}
+
+		repository := db.GetCategoryRepository()
+		if _, err = repository.GetCategoryById(categoryId); err != nil {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "category not found"})
+			return
+		}
+		if err = repository.AddAlias(categoryId, req.Alias); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, db.ErrCategoryAliasExists) {
+				code = http.StatusConflict
+			}
+			c.JSON(code, model.BaseResp{Error: err, Code: model.ErrorCode(code), ErrMsg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// MergeCategory 将路径中的分类合并进目标分类(馆员), 用于整理迁移产生的重复分类
+func MergeCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryId, err := strconv.ParseInt(c.Param("categoryId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid category ID"})
+			return
+		}
+		var req model.MergeCategoryRequest
+		if err = c.ShouldBindJSON(&req); err != nil || req.TargetId <= 0 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "target_id is required"})
+			return
+		}
+		if err = db.GetCategoryRepository().MergeCategory(categoryId, req.TargetId); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, db.ErrInvalidCategoryMove) {
+				code = http.StatusBadRequest
+			}
+			c.JSON(code, model.BaseResp{Error: err, Code: model.ErrorCode(code), ErrMsg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
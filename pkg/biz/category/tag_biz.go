@@ -0,0 +1,205 @@
+package category
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+const maxTagLength = 32
+
+// AddBookTag 给图书打标签
+func AddBookTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		bookId, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid book ID"})
+			return
+		}
+		var req model.AddBookTagRequest
+		if err = c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"})
+			return
+		}
+		name := strings.ToLower(strings.Join(strings.Fields(req.Name), " "))
+		if name == "" || utf8.RuneCountInString(name) > maxTagLength {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid tag"), Code: http.StatusBadRequest, ErrMsg: "tag must be 1-32 characters"})
+			return
+		}
+
+		if _, err = db.GetBookRepository().GetBookById(bookId); err != nil {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "book not found"})
+			return
+		}
+		if _, err = db.GetTagRepository().AddBookTag(bookId, user.Id, name); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, db.ErrTagBanned) {
+				code = http.StatusForbidden
+			}
+			c.JSON(code, model.BaseResp{Error: err, Code: model.ErrorCode(code), ErrMsg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// RemoveBookTag 撤销自己给图书打的标签
+func RemoveBookTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		bookId, errBook := strconv.ParseInt(c.Param("bookId"), 10, 64)
+		tagId, errTag := strconv.ParseInt(c.Param("tagId"), 10, 64)
+		if errBook != nil || errTag != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid ID"), Code: http.StatusBadRequest, ErrMsg: "invalid book or tag ID"})
+			return
+		}
+		if err := db.GetTagRepository().RemoveBookTag(bookId, tagId, user.Id); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to remove tag"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// GetBookTags 获取图书的标签
+func GetBookTags() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookId, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BookTagsResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid book ID"},
+			})
+			return
+		}
+		tags, err := db.GetTagRepository().ListBookTags(bookId, 30)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BookTagsResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list tags"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.BookTagsResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Tags:     tags,
+		})
+	}
+}
+
+// GetTagBooks 标签页: 分页获取带有该标签的图书
+func GetTagBooks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tagId, err := strconv.ParseInt(c.Param("tagId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.TagBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid tag ID"},
+			})
+			return
+		}
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 {
+			pageSize = 10
+		}
+
+		repository := db.GetTagRepository()
+		tag, err := repository.GetTagById(tagId)
+		if err != nil || tag.Banned {
+			c.JSON(http.StatusNotFound, model.TagBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "tag not found"},
+			})
+			return
+		}
+		books, total, err := repository.ListBooksByTag(tagId, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.TagBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list books"},
+			})
+			return
+		}
+		count, _ := repository.CountTagUsers(tagId)
+		c.JSON(http.StatusOK, model.TagBooksResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Tag:      &model.TagDTO{Id: tag.Id, Name: tag.Name, Count: count},
+			Books:    books,
+			Total:    total,
+		})
+	}
+}
+
+// BanTag 全局禁用标签(版主)
+func BanTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setTagBanned(c, true)
+	}
+}
+
+// UnbanTag 解禁标签(版主)
+func UnbanTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setTagBanned(c, false)
+	}
+}
+
+func setTagBanned(c *gin.Context, banned bool) {
+	tagId, err := strconv.ParseInt(c.Param("tagId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid tag ID"})
+		return
+	}
+	if err = db.GetTagRepository().SetTagBanned(tagId, banned); err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to update tag"})
+		return
+	}
+	c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+}
+
+// HideBookTag 隐藏某本书上的某个标签(版主)
+func HideBookTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setBookTagStatus(c, model.BookTagHidden)
+	}
+}
+
+// ShowBookTag 恢复某本书上被隐藏的标签(版主)
+func ShowBookTag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setBookTagStatus(c, model.BookTagVisible)
+	}
+}
+
+func setBookTagStatus(c *gin.Context, status string) {
+	bookId, errBook := strconv.ParseInt(c.Param("bookId"), 10, 64)
+	tagId, errTag := strconv.ParseInt(c.Param("tagId"), 10, 64)
+	if errBook != nil || errTag != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid ID"), Code: http.StatusBadRequest, ErrMsg: "invalid book or tag ID"})
+		return
+	}
+	if err := db.GetTagRepository().SetBookTagStatus(bookId, tagId, status); err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to update tag"})
+		return
+	}
+	c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+}
@@ -0,0 +1,689 @@
+package club
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/biz/notification"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+const (
+	maxClubNameLength = 50
+	// recentEventsNum 活动列表返回的最近活动数
+	recentEventsNum = 20
+)
+
+// CreateClub 创建读书会, 创建者成为群主
+func CreateClub() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.ClubResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		var req model.CreateClubRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"},
+			})
+			return
+		}
+		if err := normalizeClubRequest(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: err.Error()},
+			})
+			return
+		}
+
+		club := &model.ClubDO{
+			Name:          req.Name,
+			Description:   req.Description,
+			OwnerId:       user.Id,
+			JoinPolicy:    req.JoinPolicy,
+			CurrentBookId: req.CurrentBookId,
+			CreatedAt:     time.Now(),
+		}
+		repository := db.GetClubRepository()
+		if _, err := repository.CreateClub(club); err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create club"},
+			})
+			return
+		}
+		clubDTO, err := toClubDTO(club, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get club"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ClubResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Club:     clubDTO,
+		})
+	}
+}
+
+// ListClubs 分页获取读书会
+func ListClubs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 {
+			pageSize = 10
+		}
+
+		clubs, total, err := db.GetClubRepository().ListClubs(page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list clubs"},
+			})
+			return
+		}
+		bookIds := make([]int64, 0, len(clubs))
+		for _, club := range clubs {
+			if club.CurrentBookId > 0 {
+				bookIds = append(bookIds, club.CurrentBookId)
+			}
+		}
+		books, err := db.GetBookRepository().GetBooksByIds(bookIds)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get books"},
+			})
+			return
+		}
+
+		clubDTOs := make([]*model.ClubDTO, len(clubs))
+		for i, club := range clubs {
+			clubDTOs[i] = club.Transfer()
+			clubDTOs[i].CurrentBook = books[club.CurrentBookId]
+		}
+		c.JSON(http.StatusOK, model.ClubListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Clubs:    clubDTOs,
+			Total:    total,
+		})
+	}
+}
+
+// GetClub 获取读书会详情, 包含当前在读的书、阅读计划和当前用户的成员状态
+func GetClub() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		club, ok := loadClub(c)
+		if !ok {
+			return
+		}
+		clubDTO, err := toClubDTO(club, currentUser(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get club"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ClubResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Club:     clubDTO,
+		})
+	}
+}
+
+// UpdateClub 修改读书会信息, 包括当前在读的书, 仅群主和管理员可修改
+func UpdateClub() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		club, user, ok := moderatedClub(c)
+		if !ok {
+			return
+		}
+		var req model.CreateClubRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"},
+			})
+			return
+		}
+		if err := normalizeClubRequest(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: err.Error()},
+			})
+			return
+		}
+		if err := db.GetClubRepository().UpdateClub(club.Id, &req); err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to update club"},
+			})
+			return
+		}
+
+		club.Name = req.Name
+		club.Description = req.Description
+		club.JoinPolicy = req.JoinPolicy
+		club.CurrentBookId = req.CurrentBookId
+		clubDTO, err := toClubDTO(club, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get club"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ClubResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Club:     clubDTO,
+		})
+	}
+}
+
+// JoinClub 加入读书会; 需要审批的读书会提交申请并通知群主和管理员
+func JoinClub() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		club, ok := loadClub(c)
+		if !ok {
+			return
+		}
+		repository := db.GetClubRepository()
+		existing, err := repository.GetMember(club.Id, user.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to join club"})
+			return
+		}
+		if existing != nil {
+			c.JSON(http.StatusConflict, model.BaseResp{Error: errors.New("already joined"), Code: http.StatusConflict, ErrMsg: "already a member or pending approval"})
+			return
+		}
+		member, err := repository.Join(club, user.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to join club"})
+			return
+		}
+
+		clubDTO := club.Transfer()
+		clubDTO.MyRole = member.Role
+		clubDTO.MyStatus = member.Status
+		if member.Status == model.ClubMemberPending {
+			moderatorIds, err := repository.ListMemberIdsByRole(club.Id, model.ClubRoleOwner, model.ClubRoleModerator)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to notify moderators"})
+				return
+			}
+			for _, moderatorId := range moderatorIds {
+				notification.Send(moderatorId, model.NotifyClubJoinRequest, "新的入会申请",
+					fmt.Sprintf("%s 申请加入读书会《%s》", user.Name, club.Name), club.Id)
+			}
+		}
+		c.JSON(http.StatusOK, model.ClubResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Club:     clubDTO,
+		})
+	}
+}
+
+// LeaveClub 退出读书会或撤回入会申请, 群主不能退出
+func LeaveClub() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		club, ok := loadClub(c)
+		if !ok {
+			return
+		}
+		if club.OwnerId == user.Id {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("owner cannot leave"), Code: http.StatusBadRequest, ErrMsg: "owner cannot leave the club"})
+			return
+		}
+		err := db.GetClubRepository().Leave(club.Id, user.Id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "not a member of this club"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to leave club"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// ListMembers 获取成员列表, status=pending时返回入会申请, 仅群主和管理员可查看申请
+func ListMembers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		club, ok := loadClub(c)
+		if !ok {
+			return
+		}
+		status := c.DefaultQuery("status", model.ClubMemberActive)
+		if status != model.ClubMemberActive && status != model.ClubMemberPending {
+			c.JSON(http.StatusBadRequest, model.ClubMemberListResponse{
+				BaseResp: model.BaseResp{Error: errors.New("invalid status"), Code: http.StatusBadRequest, ErrMsg: "status must be active or pending"},
+			})
+			return
+		}
+		repository := db.GetClubRepository()
+		if status == model.ClubMemberPending {
+			user := currentUser(c)
+			if user == nil {
+				c.JSON(http.StatusUnauthorized, model.ClubMemberListResponse{
+					BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+				})
+				return
+			}
+			member, err := repository.GetMember(club.Id, user.Id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.ClubMemberListResponse{
+					BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get membership"},
+				})
+				return
+			}
+			if member == nil || !member.IsModerator() {
+				c.JSON(http.StatusForbidden, model.ClubMemberListResponse{
+					BaseResp: model.BaseResp{Error: errors.New("forbidden"), Code: http.StatusForbidden, ErrMsg: "only moderators can view join requests"},
+				})
+				return
+			}
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 {
+			pageSize = 20
+		}
+		members, total, err := repository.ListMembers(club.Id, status, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubMemberListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list members"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ClubMemberListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Members:  members,
+			Total:    total,
+		})
+	}
+}
+
+// ApproveMember 通过入会申请并通知申请人
+func ApproveMember() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reviewJoinRequest(c, true)
+	}
+}
+
+// RejectMember 拒绝入会申请并通知申请人
+func RejectMember() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reviewJoinRequest(c, false)
+	}
+}
+
+func reviewJoinRequest(c *gin.Context, approve bool) {
+	club, _, ok := moderatedClub(c)
+	if !ok {
+		return
+	}
+	userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid user ID"})
+		return
+	}
+
+	repository := db.GetClubRepository()
+	var done bool
+	if approve {
+		done, err = repository.Approve(club.Id, userId)
+	} else {
+		done, err = repository.Reject(club.Id, userId)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to review join request"})
+		return
+	}
+	if !done {
+		c.JSON(http.StatusNotFound, model.BaseResp{Error: errors.New("request not found"), Code: http.StatusNotFound, ErrMsg: "join request not found"})
+		return
+	}
+
+	if approve {
+		notification.Send(userId, model.NotifyClubJoinResult, "入会申请已通过",
+			fmt.Sprintf("你已加入读书会《%s》", club.Name), club.Id)
+	} else {
+		notification.Send(userId, model.NotifyClubJoinResult, "入会申请未通过",
+			fmt.Sprintf("你加入读书会《%s》的申请未通过", club.Name), club.Id)
+	}
+	c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+}
+
+// SetMemberRole 设置成员为管理员或普通成员, 仅群主可操作
+func SetMemberRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		club, ok := loadClub(c)
+		if !ok {
+			return
+		}
+		if club.OwnerId != user.Id {
+			c.JSON(http.StatusForbidden, model.BaseResp{Error: errors.New("forbidden"), Code: http.StatusForbidden, ErrMsg: "only the owner can change roles"})
+			return
+		}
+		userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid user ID"})
+			return
+		}
+		var req model.SetClubMemberRoleRequest
+		if err = c.ShouldBindJSON(&req); err != nil ||
+			(req.Role != model.ClubRoleModerator && req.Role != model.ClubRoleMember) {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "role must be moderator or member"})
+			return
+		}
+
+		updated, err := db.GetClubRepository().SetMemberRole(club.Id, userId, req.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to set role"})
+			return
+		}
+		if !updated {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: errors.New("member not found"), Code: http.StatusNotFound, ErrMsg: "member not found"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// CreateSchedule 添加阅读计划的一个阶段, 仅群主和管理员可操作
+func CreateSchedule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		club, _, ok := moderatedClub(c)
+		if !ok {
+			return
+		}
+		var req model.CreateClubScheduleRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.BookId <= 0 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "book_id is required"})
+			return
+		}
+		req.Title = strings.TrimSpace(req.Title)
+		if req.StartDate.IsZero() || req.EndDate.Before(req.StartDate) {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid dates"), Code: http.StatusBadRequest, ErrMsg: "end_date must not be before start_date"})
+			return
+		}
+		if _, err := db.GetBookRepository().GetBookById(req.BookId); err != nil {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "book not found"})
+			return
+		}
+
+		schedule := &model.ClubScheduleDO{
+			ClubId:    club.Id,
+			BookId:    req.BookId,
+			Title:     req.Title,
+			StartDate: req.StartDate,
+			EndDate:   req.EndDate,
+		}
+		if _, err := db.GetClubRepository().CreateSchedule(schedule); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create schedule"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// DeleteSchedule 删除阅读计划的一个阶段, 仅群主和管理员可操作
+func DeleteSchedule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		club, _, ok := moderatedClub(c)
+		if !ok {
+			return
+		}
+		scheduleId, err := strconv.ParseInt(c.Param("scheduleId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid schedule ID"})
+			return
+		}
+		if err = db.GetClubRepository().DeleteSchedule(club.Id, scheduleId); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to delete schedule"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// CreateEvent 发布活动公告并通知所有成员, 仅群主和管理员可操作
+func CreateEvent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		club, user, ok := moderatedClub(c)
+		if !ok {
+			return
+		}
+		var req model.CreateClubEventRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"})
+			return
+		}
+		req.Title = strings.TrimSpace(req.Title)
+		if req.Title == "" || req.StartTime.IsZero() {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid event"), Code: http.StatusBadRequest, ErrMsg: "title and start_time are required"})
+			return
+		}
+
+		repository := db.GetClubRepository()
+		event := &model.ClubEventDO{
+			ClubId:    club.Id,
+			Title:     req.Title,
+			Content:   req.Content,
+			Location:  req.Location,
+			StartTime: req.StartTime,
+			CreatedBy: user.Id,
+			CreatedAt: time.Now(),
+		}
+		if _, err := repository.CreateEvent(event); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create event"})
+			return
+		}
+
+		memberIds, err := repository.ListMemberIdsByRole(club.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to notify members"})
+			return
+		}
+		content := fmt.Sprintf("《%s》发布了活动: %s, 时间 %s", club.Name, event.Title, event.StartTime.Format("2006-01-02 15:04"))
+		for _, memberId := range memberIds {
+			if memberId != user.Id {
+				notification.Send(memberId, model.NotifyClubEvent, "读书会活动", content, club.Id)
+			}
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// ListEvents 获取读书会最近的活动, 仅成员可查看
+func ListEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.ClubEventListResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		club, ok := loadClub(c)
+		if !ok {
+			return
+		}
+		repository := db.GetClubRepository()
+		member, err := repository.GetMember(club.Id, user.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubEventListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get membership"},
+			})
+			return
+		}
+		if member == nil || member.Status != model.ClubMemberActive {
+			c.JSON(http.StatusForbidden, model.ClubEventListResponse{
+				BaseResp: model.BaseResp{Error: errors.New("forbidden"), Code: http.StatusForbidden, ErrMsg: "not a member of this club"},
+			})
+			return
+		}
+
+		events, err := repository.ListEvents(club.Id, recentEventsNum)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ClubEventListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list events"},
+			})
+			return
+		}
+		eventDTOs := make([]*model.ClubEventDTO, len(events))
+		for i, event := range events {
+			eventDTOs[i] = event.Transfer()
+		}
+		c.JSON(http.StatusOK, model.ClubEventListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Events:   eventDTOs,
+		})
+	}
+}
+
+func currentUser(c *gin.Context) *model.UserDTO {
+	obj, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+	user, _ := obj.(*model.UserDTO)
+	return user
+}
+
+// loadClub 读取路径中的读书会, 失败时已写回响应
+func loadClub(c *gin.Context) (*model.ClubDO, bool) {
+	clubId, err := strconv.ParseInt(c.Param("clubId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid club ID"})
+		return nil, false
+	}
+	club, err := db.GetClubRepository().GetClubById(clubId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "club not found"})
+		return nil, false
+	}
+	return club, true
+}
+
+// moderatedClub 读取路径中的读书会并校验当前用户是群主或管理员, 失败时已写回响应
+func moderatedClub(c *gin.Context) (*model.ClubDO, *model.UserDTO, bool) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return nil, nil, false
+	}
+	club, ok := loadClub(c)
+	if !ok {
+		return nil, nil, false
+	}
+	member, err := db.GetClubRepository().GetMember(club.Id, user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get membership"})
+		return nil, nil, false
+	}
+	if member == nil || !member.IsModerator() {
+		c.JSON(http.StatusForbidden, model.BaseResp{Error: errors.New("forbidden"), Code: http.StatusForbidden, ErrMsg: "only moderators can manage the club"})
+		return nil, nil, false
+	}
+	return club, user, true
+}
+
+// normalizeClubRequest 校验并规范化创建/修改读书会的参数
+func normalizeClubRequest(req *model.CreateClubRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxClubNameLength {
+		return errors.New("name must be 1-50 characters")
+	}
+	if req.JoinPolicy == "" {
+		req.JoinPolicy = model.ClubJoinOpen
+	}
+	if req.JoinPolicy != model.ClubJoinOpen && req.JoinPolicy != model.ClubJoinApproval {
+		return errors.New("join_policy must be open or approval")
+	}
+	if req.CurrentBookId < 0 {
+		return errors.New("invalid current_book_id")
+	}
+	if req.CurrentBookId > 0 {
+		if _, err := db.GetBookRepository().GetBookById(req.CurrentBookId); err != nil {
+			return errors.New("current book not found")
+		}
+	}
+	return nil
+}
+
+// toClubDTO 组装读书会详情, user为空时不填成员状态
+func toClubDTO(club *model.ClubDO, user *model.UserDTO) (*model.ClubDTO, error) {
+	clubDTO := club.Transfer()
+	repository := db.GetClubRepository()
+
+	schedule, err := repository.ListSchedule(club.Id)
+	if err != nil {
+		return nil, err
+	}
+	bookIds := make([]int64, 0, len(schedule)+1)
+	if club.CurrentBookId > 0 {
+		bookIds = append(bookIds, club.CurrentBookId)
+	}
+	for _, item := range schedule {
+		bookIds = append(bookIds, item.BookId)
+	}
+	books, err := db.GetBookRepository().GetBooksByIds(bookIds)
+	if err != nil {
+		return nil, err
+	}
+
+	clubDTO.CurrentBook = books[club.CurrentBookId]
+	clubDTO.Schedule = make([]*model.ClubScheduleDTO, len(schedule))
+	for i, item := range schedule {
+		clubDTO.Schedule[i] = &model.ClubScheduleDTO{
+			Id:        item.Id,
+			ClubId:    item.ClubId,
+			Book:      books[item.BookId],
+			Title:     item.Title,
+			StartDate: item.StartDate,
+			EndDate:   item.EndDate,
+		}
+	}
+
+	if user != nil {
+		member, err := repository.GetMember(club.Id, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		if member != nil {
+			clubDTO.MyRole = member.Role
+			clubDTO.MyStatus = member.Status
+		}
+	}
+	return clubDTO, nil
+}
@@ -0,0 +1,100 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+)
+
+// Send 给用户发送一条站内通知,失败只记录日志不影响主流程
+func Send(userId int64, notifyType, title, content string, relatedId int64) {
+	notification := &model.NotificationDO{
+		UserId:    userId,
+		Type:      notifyType,
+		Title:     title,
+		Content:   content,
+		RelatedId: relatedId,
+		CreatedAt: time.Now(),
+	}
+	if err := db.GetNotificationRepository().CreateNotification(notification); err != nil {
+		log.GetLogger().Errorf("failed to send notification to user %d: %v", userId, err)
+	}
+}
+
+// List 获取当前用户的通知
+func List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 {
+			pageSize = 20
+		}
+		onlyUnread := c.Query("unread") == "true"
+
+		repository := db.GetNotificationRepository()
+		notifications, err := repository.ListNotifications(user.Id, onlyUnread, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.NotificationListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list notifications"},
+			})
+			return
+		}
+		unread, err := repository.CountUnread(user.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.NotificationListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to count notifications"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.NotificationListResponse{
+			BaseResp:      model.BaseResp{Code: http.StatusOK},
+			Notifications: notifications,
+			Unread:        unread,
+		})
+	}
+}
+
+// MarkRead 将通知标记为已读, 路径中带notificationId时只标记该条
+func MarkRead() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		var ids []int64
+		if idStr := c.Param("notificationId"); idStr != "" {
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid notification ID", Error: err})
+				return
+			}
+			ids = append(ids, id)
+		}
+
+		if err := db.GetNotificationRepository().MarkRead(user.Id, ids); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to mark notifications", Error: err})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
@@ -0,0 +1,342 @@
+package post
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/config"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/file"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+	"yujian-backend/pkg/utils"
+)
+
+// maxAttachmentNameLength 附件文件名的最大字符数
+const maxAttachmentNameLength = 100
+
+// attachmentGCBatchSize 每次回收的附件数
+const attachmentGCBatchSize = 200
+
+// UploadAttachment 上传帖子或评论的附件, 按文件内容校验类型和大小; 返回的Markdown插入正文发布或存入草稿后附件才会被保留
+// 表单字段: file
+func UploadAttachment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		client := file.GetMinioClient()
+		if client == nil {
+			c.JSON(http.StatusServiceUnavailable, model.BaseResp{Code: http.StatusServiceUnavailable, ErrMsg: "file storage is unavailable", Error: errors.New("minio client not initialized")})
+			return
+		}
+
+		attachmentConfig := config.Config.Attachment
+		maxImageSize := int64(attachmentConfig.MaxImageSizeMB) << 20
+		maxFileSize := int64(attachmentConfig.MaxFileSizeMB) << 20
+		// 限制请求体大小, 超大的请求不落盘; 多留1MB给表单的其他部分
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max(maxImageSize, maxFileSize)+1<<20)
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "file is required or too large", Error: err})
+			return
+		}
+		f, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "failed to read file", Error: err})
+			return
+		}
+		defer f.Close()
+
+		// 按文件头识别类型, 不信任客户端给的Content-Type和扩展名
+		head := make([]byte, 512)
+		n, err := io.ReadFull(f, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "failed to read file", Error: err})
+			return
+		}
+		contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+		kind, ok := model.AllowedAttachmentTypes[contentType]
+		if !ok {
+			c.JSON(http.StatusUnsupportedMediaType, model.BaseResp{Code: http.StatusUnsupportedMediaType, ErrMsg: "unsupported file type: " + contentType, Error: errors.New("unsupported file type")})
+			return
+		}
+		limit := maxFileSize
+		if kind == model.AttachmentImage {
+			limit = maxImageSize
+		}
+		if header.Size > limit {
+			c.JSON(http.StatusRequestEntityTooLarge, model.BaseResp{Code: http.StatusRequestEntityTooLarge, ErrMsg: fmt.Sprintf("file exceeds %d MB", limit>>20), Error: errors.New("file too large")})
+			return
+		}
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to read file", Error: err})
+			return
+		}
+
+		ctx := context.Background()
+		attachment := &model.AttachmentDO{
+			Key:         utils.GenerateUUID(),
+			UploaderId:  user.Id,
+			FileName:    attachmentName(header.Filename),
+			ContentType: contentType,
+			Kind:        kind,
+			Size:        header.Size,
+			CreatedAt:   time.Now(),
+		}
+		if err = client.CreateBucket(ctx, attachmentConfig.Bucket); err == nil {
+			err = client.PutObject(ctx, attachmentConfig.Bucket, attachment.Key, f, header.Size, contentType)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to store file", Error: err})
+			return
+		}
+		if err = db.GetAttachmentRepository().CreateAttachment(attachment); err != nil {
+			if errRemove := client.RemoveObject(ctx, attachmentConfig.Bucket, attachment.Key); errRemove != nil {
+				log.GetLogger().Warnf("删除附件%s失败: %v", attachment.Key, errRemove)
+			}
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to save attachment", Error: err})
+			return
+		}
+
+		attachmentDTO := attachment.Transfer()
+		attachmentDTO.PreviewURL = signAttachmentURL(attachment.Key)
+		c.JSON(http.StatusOK, model.AttachmentResponse{
+			BaseResp:   model.BaseResp{Code: http.StatusOK},
+			Attachment: attachmentDTO,
+		})
+	}
+}
+
+// GetAttachment 读取附件; 已引用的附件和所属帖子一样可见, 未引用的只有上传者可见
+// 图片标签无法携带Authorization头, 带有效签名(查询参数expires、sig)的请求直接放行, 签名只发给通过了可见性校验的用户
+func GetAttachment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		attachment, err := db.GetAttachmentRepository().GetAttachmentByKey(c.Param("key"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "attachment not found", Error: err})
+			} else {
+				c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get attachment", Error: err})
+			}
+			return
+		}
+
+		signed := verifyAttachmentSignature(attachment.Key, c.Query("expires"), c.Query("sig"))
+		user := currentUser(c)
+		if attachment.PostId == 0 {
+			if !signed && (user == nil || user.Id != attachment.UploaderId) {
+				c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "attachment not found", Error: errors.New("attachment not found")})
+				return
+			}
+		} else {
+			post, err := db.GetPostRepository().GetPostDOById(attachment.PostId)
+			if err != nil {
+				writePostError(c, err, "failed to get post")
+				return
+			}
+			if post.Deleted {
+				writePostError(c, db.ErrPostDeleted, "")
+				return
+			}
+			if !signed {
+				visible, err := postVisible(post.Id, user)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get post", Error: err})
+					return
+				}
+				if !visible {
+					c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "not a member of this club", Error: errors.New("forbidden")})
+					return
+				}
+			}
+		}
+
+		client := file.GetMinioClient()
+		if client == nil {
+			c.JSON(http.StatusServiceUnavailable, model.BaseResp{Code: http.StatusServiceUnavailable, ErrMsg: "file storage is unavailable", Error: errors.New("minio client not initialized")})
+			return
+		}
+		object, err := client.OpenObject(c, config.Config.Attachment.Bucket, attachment.Key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to fetch file", Error: err})
+			return
+		}
+		defer object.Close()
+
+		// 图片内嵌显示, 其余文件一律下载, 禁止浏览器猜测类型
+		disposition := "attachment"
+		if attachment.Kind == model.AttachmentImage {
+			disposition = "inline"
+		}
+		c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, object, map[string]string{
+			"Content-Disposition":    disposition + "; filename*=UTF-8''" + url.PathEscape(attachment.FileName),
+			"X-Content-Type-Options": "nosniff",
+			"Cache-Control":          "private, max-age=86400",
+		})
+	}
+}
+
+var (
+	attachmentSecretOnce sync.Once
+	attachmentSecret     []byte
+)
+
+// attachmentSignKey 附件地址的签名密钥, 未配置时随机生成, 重启后之前签发的地址失效
+func attachmentSignKey() []byte {
+	attachmentSecretOnce.Do(func() {
+		if secret := config.Config.Attachment.SignSecret; secret != "" {
+			attachmentSecret = []byte(secret)
+			return
+		}
+		attachmentSecret = make([]byte, 32)
+		if _, err := rand.Read(attachmentSecret); err != nil {
+			log.GetLogger().Fatalf("failed to generate attachment sign key: %v", err)
+		}
+	})
+	return attachmentSecret
+}
+
+// attachmentSignature 附件Key和过期时间的签名
+func attachmentSignature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, attachmentSignKey())
+	mac.Write([]byte(key + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signAttachmentURL 生成附件的临时访问地址; 过期时间按有效期取整, 同一时间段内地址不变, 便于浏览器缓存
+func signAttachmentURL(key string) string {
+	ttl := time.Duration(config.Config.Attachment.URLTTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	expires := time.Now().Truncate(ttl).Add(2 * ttl).Unix()
+	return model.AttachmentURLPrefix + key + "?expires=" + strconv.FormatInt(expires, 10) + "&sig=" + attachmentSignature(key, expires)
+}
+
+// verifyAttachmentSignature 校验附件临时地址的签名和有效期
+func verifyAttachmentSignature(key, expires, sig string) bool {
+	if expires == "" || sig == "" {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(attachmentSignature(key, expiresAt)))
+}
+
+// signAttachmentHTML 把渲染后HTML中的附件地址换成临时访问地址, 只能用于已通过可见性校验的内容
+func signAttachmentHTML(contentHTML string) string {
+	return model.RewriteAttachmentURLs(contentHTML, func(key string) string {
+		return html.EscapeString(signAttachmentURL(key))
+	})
+}
+
+// signCommentAttachments 给评论及其子回复中的附件地址签名
+func signCommentAttachments(comments []*model.PostCommentDTO) {
+	for _, comment := range comments {
+		comment.ContentHTML = signAttachmentHTML(comment.ContentHTML)
+		signCommentAttachments(comment.Replies)
+	}
+}
+
+// bindAttachments 将正文中引用的附件关联到帖子或评论, 失败只记录日志, 附件会在过期后被回收
+func bindAttachments(uploaderId int64, content string, postId, commentId int64) {
+	keys := model.ParseAttachmentRefs(content)
+	if err := db.GetAttachmentRepository().BindAttachments(uploaderId, keys, postId, commentId); err != nil {
+		log.GetLogger().Warnf("关联帖子%d的附件失败: %v", postId, err)
+	}
+}
+
+// attachmentName 清理上传的文件名, 去掉路径和会破坏Markdown的字符
+func attachmentName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune("[]()<>\"`", r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > maxAttachmentNameLength {
+		name = string(runes[:maxAttachmentNameLength])
+	}
+	return name
+}
+
+// StartAttachmentGC 启动后台任务, 定期回收上传后一直没有被已发布的帖子或评论引用的附件
+func StartAttachmentGC() {
+	attachmentConfig := config.Config.Attachment
+	interval := time.Duration(attachmentConfig.GCIntervalMin) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			before := time.Now().Add(-time.Duration(attachmentConfig.PendingTTLHours) * time.Hour)
+			if n, err := collectAttachments(before); err != nil {
+				log.GetLogger().Errorf("failed to collect attachments: %v", err)
+			} else if n > 0 {
+				log.GetLogger().Infof("collected %d unused attachments", n)
+			}
+		}
+	}()
+}
+
+// collectAttachments 回收before之前上传且没有被引用的附件, 先删记录再删对象, 避免删掉刚被引用的附件
+func collectAttachments(before time.Time) (int, error) {
+	client := file.GetMinioClient()
+	if client == nil {
+		return 0, nil
+	}
+	repository := db.GetAttachmentRepository()
+	count := 0
+	for {
+		attachments, err := repository.ListExpiredAttachments(before, attachmentGCBatchSize)
+		if err != nil {
+			return count, err
+		}
+		for _, attachment := range attachments {
+			deleted, err := repository.DeletePendingAttachment(attachment.Id)
+			if err != nil {
+				return count, err
+			}
+			if !deleted {
+				continue
+			}
+			if err = client.RemoveObject(context.Background(), config.Config.Attachment.Bucket, attachment.Key); err != nil {
+				log.GetLogger().Warnf("删除附件%s的文件失败: %v", attachment.Key, err)
+			}
+			count++
+		}
+		if len(attachments) < attachmentGCBatchSize {
+			return count, nil
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/config"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/es"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+	"yujian-backend/pkg/utils"
+)
+
+// EditComment 修改评论, 仅作者本人可在发布后的配置时间内修改
+func EditComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		comment, user, ok := loadComment(c)
+		if !ok {
+			return
+		}
+		if comment.AuthorId != user.Id {
+			c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "only the author can edit this comment", Error: errors.New("forbidden")})
+			return
+		}
+		window := config.Config.Forum.CommentEditWindowMinutes
+		if window > 0 && time.Since(comment.EditTime) > time.Duration(window)*time.Minute {
+			c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "edit window has expired", Error: errors.New("edit window has expired")})
+			return
+		}
+
+		var req model.UpdatePostCommentReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid request body", Error: err})
+			return
+		}
+		req.Content = strings.TrimSpace(req.Content)
+		if req.Content == "" {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "content is required", Error: errors.New("content is required")})
+			return
+		}
+
+		prevContent := comment.Content
+		if err := db.GetPostRepository().EditPostComment(comment, req.Content, utils.RenderMarkdown(req.Content)); err != nil {
+			writeCommentError(c, err, "failed to edit comment")
+			return
+		}
+		indexComment(comment)
+		bindAttachments(user.Id, req.Content, comment.PostId, comment.Id)
+		processRefs(user, comment.PostId, comment.Id, req.Content, prevContent)
+		commentDTO := comment.TransformToDTO()
+		if err := fillCommentBooks([]*model.PostCommentDTO{commentDTO}); err != nil {
+			log.GetLogger().Warnf("获取评论%d引用的图书失败: %v", comment.Id, err)
+		}
+		signCommentAttachments([]*model.PostCommentDTO{commentDTO})
+		c.JSON(http.StatusOK, model.PostCommentResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Comment:  commentDTO,
+		})
+	}
+}
+
+// DeleteComment 删除评论, 仅作者或版主可删除; 有回复的评论保留占位
+func DeleteComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		comment, user, ok := loadComment(c)
+		if !ok {
+			return
+		}
+		if comment.AuthorId != user.Id && !user.HasRole(model.RoleModerator) {
+			c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "only the author or a moderator can delete this comment", Error: errors.New("forbidden")})
+			return
+		}
+
+		if err := db.GetPostRepository().DeletePostComment(comment.Id, user.Id); err != nil {
+			writeCommentError(c, err, "failed to delete comment")
+			return
+		}
+		// 留下占位的评论也不再参与搜索
+		if err := es.DeleteArticle(context.Background(), &model.PostCommentEsModel{Id: strconv.FormatInt(comment.Id, 10)}); err != nil {
+			log.GetLogger().Warnf("删除评论%d的索引失败: %v", comment.Id, err)
+		}
+		if err := db.GetTagRepository().RemoveCommentHashtags([]int64{comment.Id}); err != nil {
+			log.GetLogger().Warnf("删除评论%d的话题索引失败: %v", comment.Id, err)
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// PurgeComment 管理员物理删除评论及其全部回复, 用于法律要求的下架, 不保留占位
+func PurgeComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		commentId, err := strconv.ParseInt(c.Param("commentId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid comment ID", Error: err})
+			return
+		}
+
+		removed, err := db.GetPostRepository().HardDeletePostComment(commentId)
+		if err != nil {
+			writeCommentError(c, err, "failed to purge comment")
+			return
+		}
+		log.GetLogger().Infof("admin %d purged comment %d, %d comments removed", currentUser(c).Id, commentId, len(removed))
+		if err = es.DeleteCommentDocs(context.Background(), removed); err != nil {
+			log.GetLogger().Warnf("删除评论索引失败: %v", err)
+		}
+		if err = db.GetTagRepository().RemoveCommentHashtags(removed); err != nil {
+			log.GetLogger().Warnf("删除评论话题索引失败: %v", err)
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// indexComment 更新评论的搜索索引; 评论以数据库为准, 索引失败只记录日志
+func indexComment(comment *model.PostCommentDO) {
+	post, err := db.GetPostRepository().GetPostDOById(comment.PostId)
+	if err == nil {
+		err = es.UpdateArticle(context.Background(), model.NewPostCommentEsModel(comment, post))
+	}
+	if err != nil {
+		log.GetLogger().Warnf("更新评论%d的索引失败: %v", comment.Id, err)
+	}
+}
+
+// loadComment 读取路径中的评论和当前用户, 已删除的评论视为不存在, 失败时已写回响应
+func loadComment(c *gin.Context) (*model.PostCommentDO, *model.UserDTO, bool) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return nil, nil, false
+	}
+
+	commentId, err := strconv.ParseInt(c.Param("commentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid comment ID", Error: err})
+		return nil, nil, false
+	}
+	comment, err := db.GetPostRepository().GetPostCommentById(commentId)
+	if err != nil {
+		writeCommentError(c, err, "failed to get comment")
+		return nil, nil, false
+	}
+	if comment.Deleted {
+		writeCommentError(c, db.ErrCommentDeleted, "")
+		return nil, nil, false
+	}
+	return comment, user, true
+}
+
+// writeCommentError 评论不存在或已删除时返回404, 其余按内部错误处理
+func writeCommentError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "comment not found", Error: err})
+	case errors.Is(err, db.ErrCommentDeleted):
+		c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "comment has been deleted", Error: err})
+	default:
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: msg, Error: err})
+	}
+}
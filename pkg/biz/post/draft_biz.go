@@ -0,0 +1,335 @@
+package post
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/config"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+)
+
+// draftPurgeInterval 过期草稿清理任务的扫描间隔
+const draftPurgeInterval = time.Hour
+
+// draftPurgeBatchSize 每批清理的草稿数
+const draftPurgeBatchSize = 200
+
+// CreateDraft 新建草稿
+func CreateDraft() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		var req model.SavePostDraftRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid request body", Error: err})
+			return
+		}
+		if !checkDraftContent(c, req.Content) {
+			return
+		}
+
+		draft := &model.PostDraftDO{
+			UserId:   user.Id,
+			Title:    req.Title,
+			Content:  req.Content,
+			Category: req.Category,
+			ClubId:   req.ClubId,
+			Spoiler:  req.Spoiler,
+		}
+		if err := db.GetDraftRepository().CreateDraft(draft, config.Config.Forum.MaxDraftsPerUser); err != nil {
+			writeDraftError(c, err, "failed to create draft")
+			return
+		}
+		bindDraftAttachments(user.Id, draft.Content, draft.Id)
+		c.JSON(http.StatusOK, model.PostDraftResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Draft:    draft.Transfer(),
+		})
+	}
+}
+
+// ListDrafts 按最近更新分页获取自己的草稿, 不含正文
+// 查询参数: page, page_size
+func ListDrafts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 || pageSize > 100 {
+			pageSize = 20
+		}
+
+		drafts, total, err := db.GetDraftRepository().ListDrafts(user.Id, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get drafts", Error: err})
+			return
+		}
+		resp := model.PostDraftListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Drafts:   make([]*model.PostDraftDTO, len(drafts)),
+			Total:    total,
+		}
+		for i, draft := range drafts {
+			resp.Drafts[i] = draft.Transfer()
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// GetDraft 获取自己的一篇草稿
+func GetDraft() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft, _, ok := loadDraft(c)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, model.PostDraftResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Draft:    draft.Transfer(),
+		})
+	}
+}
+
+// SaveDraft 整体保存草稿
+func SaveDraft() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft, user, ok := loadDraft(c)
+		if !ok {
+			return
+		}
+		var req model.SavePostDraftRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid request body", Error: err})
+			return
+		}
+		if !checkDraftContent(c, req.Content) {
+			return
+		}
+
+		saved, err := db.GetDraftRepository().SaveDraft(user.Id, draft.Id, map[string]interface{}{
+			"title":    req.Title,
+			"content":  req.Content,
+			"category": req.Category,
+			"club_id":  req.ClubId,
+			"spoiler":  req.Spoiler,
+		}, 0)
+		if err != nil {
+			writeDraftError(c, err, "failed to save draft")
+			return
+		}
+		bindDraftAttachments(user.Id, saved.Content, saved.Id)
+		c.JSON(http.StatusOK, model.PostDraftResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Draft:    saved.Transfer(),
+		})
+	}
+}
+
+// AutosaveDraft 编辑页定时自动保存, 只更新传了的字段; 带version时与其他页面的保存冲突返回409
+func AutosaveDraft() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		draftId, err := strconv.ParseInt(c.Param("draftId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid draft ID", Error: err})
+			return
+		}
+		var req model.AutosavePostDraftRequest
+		if err = c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid request body", Error: err})
+			return
+		}
+
+		fields := map[string]interface{}{}
+		if req.Title != nil {
+			fields["title"] = *req.Title
+		}
+		if req.Content != nil {
+			if !checkDraftContent(c, *req.Content) {
+				return
+			}
+			fields["content"] = *req.Content
+		}
+		if req.Category != nil {
+			fields["category"] = *req.Category
+		}
+		if req.ClubId != nil {
+			fields["club_id"] = *req.ClubId
+		}
+		if req.Spoiler != nil {
+			fields["spoiler"] = *req.Spoiler
+		}
+
+		// 直接按用户和版本更新, 不先读草稿, 减少自动保存的开销
+		saved, err := db.GetDraftRepository().SaveDraft(user.Id, draftId, fields, req.Version)
+		if err != nil {
+			writeDraftError(c, err, "failed to save draft")
+			return
+		}
+		if req.Content != nil {
+			bindDraftAttachments(user.Id, *req.Content, draftId)
+		}
+		c.JSON(http.StatusOK, model.AutosavePostDraftResponse{
+			BaseResp:  model.BaseResp{Code: http.StatusOK},
+			Version:   saved.Version,
+			UpdatedAt: saved.UpdatedAt,
+		})
+	}
+}
+
+// DeleteDraft 删除自己的草稿
+func DeleteDraft() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft, user, ok := loadDraft(c)
+		if !ok {
+			return
+		}
+		if err := db.GetDraftRepository().DeleteDraft(user.Id, draft.Id); err != nil {
+			writeDraftError(c, err, "failed to delete draft")
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// PublishDraft 按正常发帖流程发布草稿, 发布成功后删除草稿
+func PublishDraft() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft, user, ok := loadDraft(c)
+		if !ok {
+			return
+		}
+		// 先锁定草稿再发帖, 重复提交时只有一次能拿到草稿
+		draft, err := db.GetDraftRepository().ClaimDraft(user.Id, draft.Id)
+		if err != nil {
+			writeDraftError(c, err, "failed to publish draft")
+			return
+		}
+
+		resp := createPost(&model.CreatePostRequestDTO{
+			Title:    draft.Title,
+			Content:  draft.Content,
+			Category: draft.Category,
+			ClubId:   draft.ClubId,
+			Spoiler:  draft.Spoiler,
+		}, user)
+		if resp.Code != model.Success {
+			if err = db.GetDraftRepository().ReleaseDraft(user.Id, draft.Id); err != nil {
+				log.GetLogger().Warnf("解除草稿%d的发布锁定失败: %v", draft.Id, err)
+			}
+		}
+		switch resp.Code {
+		case model.Success:
+		case model.UserNotExists:
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "title and content are required", Error: resp.Error})
+			return
+		case http.StatusForbidden:
+			c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "not a member of this club", Error: resp.Error})
+			return
+		default:
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to create post", Error: resp.Error})
+			return
+		}
+
+		// 帖子已经发出, 草稿删除失败只记录日志, 过期后会被清理
+		if err = db.GetDraftRepository().DeleteDraft(user.Id, draft.Id); err != nil {
+			log.GetLogger().Warnf("发布后删除草稿%d失败: %v", draft.Id, err)
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// StartDraftPurger 启动后台任务, 定期清理超过配置天数没有更新的草稿
+func StartDraftPurger() {
+	retention := config.Config.Forum.DraftRetentionDays
+	if retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(draftPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			before := time.Now().AddDate(0, 0, -retention)
+			if n, err := db.GetDraftRepository().PurgeStaleDrafts(before, draftPurgeBatchSize); err != nil {
+				log.GetLogger().Errorf("failed to purge stale drafts: %v", err)
+			} else if n > 0 {
+				log.GetLogger().Infof("purged %d stale post drafts", n)
+			}
+		}
+	}()
+}
+
+// bindDraftAttachments 记录草稿中引用的附件, 失败只记录日志
+func bindDraftAttachments(userId int64, content string, draftId int64) {
+	keys := model.ParseAttachmentRefs(content)
+	if err := db.GetAttachmentRepository().BindDraftAttachments(userId, keys, draftId); err != nil {
+		log.GetLogger().Warnf("关联草稿%d的附件失败: %v", draftId, err)
+	}
+}
+
+// checkDraftContent 校验草稿正文长度, 失败时已写回响应
+func checkDraftContent(c *gin.Context, content string) bool {
+	if utf8.RuneCountInString(content) > model.PostDraftMaxContentLength {
+		c.JSON(http.StatusRequestEntityTooLarge, model.BaseResp{Code: http.StatusRequestEntityTooLarge, ErrMsg: "content is too long", Error: errors.New("content is too long")})
+		return false
+	}
+	return true
+}
+
+// loadDraft 读取路径中当前用户自己的草稿, 失败时已写回响应
+func loadDraft(c *gin.Context) (*model.PostDraftDO, *model.UserDTO, bool) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return nil, nil, false
+	}
+	draftId, err := strconv.ParseInt(c.Param("draftId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid draft ID", Error: err})
+		return nil, nil, false
+	}
+	draft, err := db.GetDraftRepository().GetDraft(user.Id, draftId)
+	if err != nil {
+		writeDraftError(c, err, "failed to get draft")
+		return nil, nil, false
+	}
+	return draft, user, true
+}
+
+// writeDraftError 草稿不存在返回404, 超过数量上限返回400, 版本冲突返回409, 其余按内部错误处理
+func writeDraftError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "draft not found", Error: err})
+	case errors.Is(err, db.ErrDraftLimit):
+		c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "too many drafts", Error: err})
+	case errors.Is(err, db.ErrDraftConflict):
+		c.JSON(http.StatusConflict, model.BaseResp{Code: http.StatusConflict, ErrMsg: "draft has been modified elsewhere", Error: err})
+	case errors.Is(err, db.ErrDraftPublishing):
+		c.JSON(http.StatusConflict, model.BaseResp{Code: http.StatusConflict, ErrMsg: "draft is being published", Error: err})
+	default:
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: msg, Error: err})
+	}
+}
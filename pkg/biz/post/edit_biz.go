@@ -0,0 +1,169 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/es"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+)
+
+// UpdatePost 编辑帖子, 仅作者或版主可编辑, 旧版本写入历史
+func UpdatePost() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		post, user, ok := loadEditablePost(c)
+		if !ok {
+			return
+		}
+
+		var req model.UpdatePostRequestDTO
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid request body", Error: err})
+			return
+		}
+		if req.Title == "" || req.Content == "" {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "title and content are required", Error: errors.New("标题或内容不能为空")})
+			return
+		}
+
+		// 旧正文只在ES中, 先读出来写入历史
+		prevContent, err := getPostContent(post.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get post content", Error: err})
+			return
+		}
+
+		content, spans := model.ParseSpoilers(req.Content)
+		edit := post.TransformToDTO(postAuthor(post))
+		edit.Title = req.Title
+		edit.Spoiler = req.Spoiler
+		edit.SpoilerSpans = spans
+		edit.Preview = postPreview(edit, content)
+		revision := &model.PostRevisionDO{
+			Content:    prevContent,
+			EditorId:   user.Id,
+			EditorName: user.Name,
+		}
+		updated, err := db.GetPostRepository().EditPost(edit, revision, func() error {
+			return es.UpdateArticle(context.Background(), newPostEsModel(edit, content))
+		})
+		if err != nil {
+			writePostError(c, err, "failed to update post")
+			return
+		}
+		bindAttachments(user.Id, req.Content, post.Id, 0)
+		// 版主代为编辑时引用仍记在作者名下
+		processRefs(postAuthor(post), post.Id, 0, content, prevContent)
+
+		c.JSON(http.StatusOK, model.UpdatePostResponseDTO{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Post:     updated.TransformToDTO(postAuthor(updated)),
+		})
+	}
+}
+
+// DeletePost 删除帖子, 仅作者或版主可删除; 帖子保留占位, 正文从ES移除并写入历史
+func DeletePost() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		post, user, ok := loadEditablePost(c)
+		if !ok {
+			return
+		}
+
+		content, err := getPostContent(post.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get post content", Error: err})
+			return
+		}
+
+		revision := &model.PostRevisionDO{
+			Content:    content,
+			EditorId:   user.Id,
+			EditorName: user.Name,
+		}
+		err = db.GetPostRepository().SoftDeletePost(post.Id, revision, func() error {
+			return es.DeleteArticle(context.Background(), &model.PostEsModel{Id: strconv.FormatInt(post.Id, 10)})
+		})
+		if err != nil {
+			writePostError(c, err, "failed to delete post")
+			return
+		}
+		// 评论文档只影响搜索结果, 删除失败不回滚帖子
+		if err = es.DeletePostComments(context.Background(), post.Id); err != nil {
+			log.GetLogger().Warnf("删除帖子%d的评论索引失败: %v", post.Id, err)
+		}
+		if err = db.GetTagRepository().RemovePostHashtags(post.Id); err != nil {
+			log.GetLogger().Warnf("删除帖子%d的话题索引失败: %v", post.Id, err)
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+	}
+}
+
+// GetPostRevisions 获取帖子的历史版本, 仅版主可查看
+func GetPostRevisions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		postId, err := strconv.ParseInt(c.Param("postId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid post ID", Error: err})
+			return
+		}
+
+		revisions, err := db.GetPostRepository().ListPostRevisions(postId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get revisions", Error: err})
+			return
+		}
+		c.JSON(http.StatusOK, model.PostRevisionListResponse{
+			BaseResp:  model.BaseResp{Code: http.StatusOK},
+			Revisions: revisions,
+		})
+	}
+}
+
+// loadEditablePost 读取路径中的帖子并校验当前用户是作者或版主, 失败时已写回响应
+func loadEditablePost(c *gin.Context) (*model.PostDO, *model.UserDTO, bool) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return nil, nil, false
+	}
+
+	postId, err := strconv.ParseInt(c.Param("postId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid post ID", Error: err})
+		return nil, nil, false
+	}
+	post, err := db.GetPostRepository().GetPostDOById(postId)
+	if err != nil {
+		writePostError(c, err, "failed to get post")
+		return nil, nil, false
+	}
+	if post.Deleted {
+		writePostError(c, db.ErrPostDeleted, "")
+		return nil, nil, false
+	}
+	if post.AuthorId != user.Id && !user.HasRole(model.RoleModerator) {
+		c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "only the author or a moderator can modify this post", Error: errors.New("forbidden")})
+		return nil, nil, false
+	}
+	return post, user, true
+}
+
+// writePostError 帖子不存在或已删除时返回404, 其余按内部错误处理
+func writePostError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "post not found", Error: err})
+	case errors.Is(err, db.ErrPostDeleted):
+		c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "post has been deleted", Error: err})
+	default:
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: msg, Error: err})
+	}
+}
@@ -0,0 +1,274 @@
+package post
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+// CreatePoll 给帖子添加投票, 仅作者本人可添加, 每个帖子最多一个
+func CreatePoll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		post, user, ok := loadEditablePost(c)
+		if !ok {
+			return
+		}
+		if post.AuthorId != user.Id {
+			c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "only the author can add a poll", Error: errors.New("forbidden")})
+			return
+		}
+
+		var req model.CreatePostPollRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid request body", Error: err})
+			return
+		}
+		req.Question = strings.TrimSpace(req.Question)
+		if req.Question == "" {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "question is required", Error: errors.New("question is required")})
+			return
+		}
+		if len(req.Options) < model.MinPollOptions || len(req.Options) > model.MaxPollOptions {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "a poll needs 2 to 20 options", Error: errors.New("invalid option count")})
+			return
+		}
+		if req.ClosesAt != nil && !req.ClosesAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "closes_at must be in the future", Error: errors.New("invalid closes_at")})
+			return
+		}
+
+		// 关联图书的选项默认使用书名
+		var bookIds []int64
+		for _, option := range req.Options {
+			if option.BookId > 0 {
+				bookIds = append(bookIds, option.BookId)
+			}
+		}
+		books, err := db.GetBookRepository().GetBooksByIds(bookIds)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get books", Error: err})
+			return
+		}
+		options := make([]*model.PostPollOptionDO, len(req.Options))
+		for i, option := range req.Options {
+			text := strings.TrimSpace(option.Text)
+			if option.BookId > 0 {
+				book, ok := books[option.BookId]
+				if !ok {
+					c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "book not found: " + strconv.FormatInt(option.BookId, 10), Error: errors.New("book not found")})
+					return
+				}
+				if text == "" {
+					text = book.Name
+				}
+			}
+			if text == "" {
+				c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "option text is required", Error: errors.New("option text is required")})
+				return
+			}
+			options[i] = &model.PostPollOptionDO{Text: text, BookId: option.BookId}
+		}
+
+		// 单选只能选一项, 多选不填上限时可以全选
+		maxChoices := 1
+		if req.MultipleChoice {
+			maxChoices = len(options)
+			if req.MaxChoices > 0 && req.MaxChoices < maxChoices {
+				maxChoices = req.MaxChoices
+			}
+		}
+		poll := &model.PostPollDO{
+			PostId:         post.Id,
+			Question:       req.Question,
+			MultipleChoice: req.MultipleChoice,
+			MaxChoices:     maxChoices,
+			Anonymous:      req.Anonymous,
+			ClosesAt:       req.ClosesAt,
+			CreatedAt:      time.Now(),
+		}
+		if err = db.GetPollRepository().CreatePoll(poll, options); err != nil {
+			writePollError(c, err, "failed to create poll")
+			return
+		}
+		writePoll(c, post.Id, user)
+	}
+}
+
+// GetPoll 获取帖子投票的实时结果, 公开投票同时返回投票人
+func GetPoll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		post, user, ok := loadVisiblePost(c)
+		if !ok {
+			return
+		}
+		writePoll(c, post.Id, user)
+	}
+}
+
+// VotePoll 投票, 每人只能投一次, 投票后返回最新结果
+func VotePoll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		post, user, ok := loadVisiblePost(c)
+		if !ok {
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		var req model.VotePostPollRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid request body", Error: err})
+			return
+		}
+
+		repository := db.GetPollRepository()
+		poll, _, err := repository.GetPollByPostId(post.Id)
+		if err != nil {
+			writePollError(c, err, "failed to get poll")
+			return
+		}
+		if err = repository.VotePoll(poll.Id, user, req.OptionIds); err != nil {
+			writePollError(c, err, "failed to vote")
+			return
+		}
+		writePoll(c, post.Id, user)
+	}
+}
+
+// ClosePoll 提前结束投票, 仅作者或版主可操作
+func ClosePoll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		post, user, ok := loadEditablePost(c)
+		if !ok {
+			return
+		}
+		repository := db.GetPollRepository()
+		poll, _, err := repository.GetPollByPostId(post.Id)
+		if err != nil {
+			writePollError(c, err, "failed to get poll")
+			return
+		}
+		if err = repository.ClosePoll(poll.Id); err != nil {
+			writePollError(c, err, "failed to close poll")
+			return
+		}
+		writePoll(c, post.Id, user)
+	}
+}
+
+// writePoll 读取帖子投票的最新结果并写回响应
+func writePoll(c *gin.Context, postId int64, user *model.UserDTO) {
+	poll, err := getPoll(postId, user)
+	if err != nil {
+		writePollError(c, err, "failed to get poll")
+		return
+	}
+	c.JSON(http.StatusOK, model.PostPollResponse{
+		BaseResp: model.BaseResp{Code: http.StatusOK},
+		Poll:     poll,
+	})
+}
+
+// getPoll 组装投票结果: 选项补上图书信息, 公开投票补上投票人, 登录用户补上自己的选择
+func getPoll(postId int64, user *model.UserDTO) (*model.PostPollDTO, error) {
+	repository := db.GetPollRepository()
+	pollDO, options, err := repository.GetPollByPostId(postId)
+	if err != nil {
+		return nil, err
+	}
+	poll := pollDO.Transfer()
+
+	var bookIds []int64
+	for _, option := range options {
+		if option.BookId > 0 {
+			bookIds = append(bookIds, option.BookId)
+		}
+	}
+	books, err := db.GetBookRepository().GetBooksByIds(bookIds)
+	if err != nil {
+		return nil, err
+	}
+	optionMap := make(map[int64]*model.PostPollOptionDTO, len(options))
+	for _, option := range options {
+		dto := option.Transfer()
+		if book, ok := books[option.BookId]; ok {
+			dto.BookName = book.Name
+			dto.BookCover = book.CoverImage
+		}
+		if !poll.Anonymous {
+			dto.Voters = []*model.UserDTO{}
+		}
+		optionMap[option.Id] = dto
+		poll.Options = append(poll.Options, dto)
+	}
+
+	if !poll.Anonymous {
+		votes, err := repository.GetPollVotes(pollDO.Id)
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range votes {
+			if option, ok := optionMap[vote.OptionId]; ok {
+				option.Voters = append(option.Voters, &model.UserDTO{Id: vote.UserId, Name: vote.UserName})
+			}
+		}
+	}
+	if user != nil {
+		if poll.MyVotes, err = repository.GetUserPollChoices(pollDO.Id, user.Id); err != nil {
+			return nil, err
+		}
+	}
+	return poll, nil
+}
+
+// loadVisiblePost 读取路径中当前用户可见且未删除的帖子, 未登录时用户为空, 失败时已写回响应
+func loadVisiblePost(c *gin.Context) (*model.PostDO, *model.UserDTO, bool) {
+	user := currentUser(c)
+	postId, err := strconv.ParseInt(c.Param("postId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid post ID", Error: err})
+		return nil, nil, false
+	}
+	post, err := db.GetPostRepository().GetPostDOById(postId)
+	if err != nil {
+		writePostError(c, err, "failed to get post")
+		return nil, nil, false
+	}
+	if post.Deleted {
+		writePostError(c, db.ErrPostDeleted, "")
+		return nil, nil, false
+	}
+	visible, err := postVisible(post.Id, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get post", Error: err})
+		return nil, nil, false
+	}
+	if !visible {
+		c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "not a member of this club", Error: errors.New("forbidden")})
+		return nil, nil, false
+	}
+	return post, user, true
+}
+
+// writePollError 投票不存在返回404, 重复创建、已投过或已结束返回409, 选项不合法返回400, 其余按帖子错误处理
+func writePollError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "poll not found", Error: err})
+	case errors.Is(err, db.ErrPollExists), errors.Is(err, db.ErrPollVoted), errors.Is(err, db.ErrPollClosed):
+		c.JSON(http.StatusConflict, model.BaseResp{Code: http.StatusConflict, ErrMsg: err.Error(), Error: err})
+	case errors.Is(err, db.ErrPollInvalidChoice):
+		c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: err.Error(), Error: err})
+	default:
+		writePostError(c, err, msg)
+	}
+}
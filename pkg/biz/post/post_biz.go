@@ -5,15 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"yujian-backend/pkg/biz/achievement"
 	"yujian-backend/pkg/db"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"yujian-backend/pkg/es"
 	"yujian-backend/pkg/log"
 	"yujian-backend/pkg/model"
+	"yujian-backend/pkg/utils"
 )
 
 // CreatePost 创建帖子
@@ -30,7 +35,7 @@ func CreatePost() gin.HandlerFunc {
 			return
 		}
 
-		obj, exists := c.Get("userName")
+		obj, exists := c.Get("user")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, model.BaseResp{
 				Code:   http.StatusUnauthorized,
@@ -42,6 +47,14 @@ func CreatePost() gin.HandlerFunc {
 		userDTO := obj.(*model.UserDTO)
 
 		resp := createPost(&req, userDTO)
+		if resp.Code == http.StatusForbidden {
+			c.JSON(http.StatusForbidden, model.BaseResp{
+				Code:   http.StatusForbidden,
+				Error:  resp.Error,
+				ErrMsg: "not a member of this club",
+			})
+			return
+		}
 		if resp.Code != model.Success {
 			c.JSON(http.StatusInternalServerError, model.BaseResp{
 				Code:   http.StatusInternalServerError,
@@ -70,14 +83,42 @@ func createPost(req *model.CreatePostRequestDTO, user *model.UserDTO) *model.Cre
 		return resp
 	}
 
+	// 读书会帖子只有正式成员可以发
+	if req.ClubId > 0 {
+		isMember, err := isActiveMember(req.ClubId, user)
+		if err != nil {
+			resp.Code = model.InternalError
+			resp.Error = fmt.Errorf("查询读书会成员失败: %v", err)
+			return resp
+		}
+		if !isMember {
+			resp.Code = http.StatusForbidden
+			resp.Error = errors.New("不是该读书会成员")
+			return resp
+		}
+	}
+
+	// 分类归一到规范分类, 未知分类只保留原文
+	categoryId, err := db.GetCategoryRepository().ResolveCategory(req.Category, false)
+	if err != nil {
+		log.GetLogger().Warnf("解析帖子分类失败: %v", err)
+	}
+
+	// 解析剧透标记, 区间存数据库, 正文存ES
+	content, spans := model.ParseSpoilers(req.Content)
+
 	// 构建帖子DO
 	postDTO := &model.PostDTO{
-		Title:    req.Title,
-		Author:   user,
-		EditTime: time.Now(),
-		Category: req.Category,
-		Comments: []*model.PostCommentDTO{},
+		Title:        req.Title,
+		Author:       user,
+		EditTime:     time.Now(),
+		Category:     req.Category,
+		CategoryId:   categoryId,
+		ClubId:       req.ClubId,
+		Spoiler:      req.Spoiler,
+		SpoilerSpans: spans,
 	}
+	postDTO.Preview = postPreview(postDTO, content)
 
 	// 保存帖子
 	repository := db.GetPostRepository()
@@ -89,15 +130,11 @@ func createPost(req *model.CreatePostRequestDTO, user *model.UserDTO) *model.Cre
 		return resp
 	} else {
 		resp.PostId = id
+		postDTO.Id = id
 	}
 
 	// 保存到ES
-	postEsModel := &model.PostEsModel{
-		Id:      strconv.FormatInt(id, 10),
-		Title:   req.Title,
-		Content: req.Content,
-	}
-	err = es.Create(context.Background(), postEsModel)
+	err = es.Create(context.Background(), newPostEsModel(postDTO, content))
 	if err != nil {
 		resp.Code = model.InternalError
 		resp.Error = fmt.Errorf("帖子创建失败保存到ES失败: %v", err)
@@ -108,7 +145,10 @@ func createPost(req *model.CreatePostRequestDTO, user *model.UserDTO) *model.Cre
 		}
 		return resp
 	}
+	bindAttachments(user.Id, req.Content, id, 0)
+	processRefs(user, id, 0, content, "")
 
+	achievement.Publish(user.Id, model.EventPost)
 	return resp
 }
 
@@ -121,7 +161,11 @@ func GetPostByTimeLine() gin.HandlerFunc {
 			return
 		}
 
-		resp := getPostByTimeLine(&req)
+		resp := getPostByTimeLine(&req, currentUser(c))
+		if resp.Code == http.StatusForbidden {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error.Error()})
+			return
+		}
 		if resp.Code != model.Success {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error.Error()})
 			return
@@ -131,7 +175,7 @@ func GetPostByTimeLine() gin.HandlerFunc {
 	}
 }
 
-func getPostByTimeLine(req *model.GetPostByTimeLineRequestDTO) *model.GetPostByTimeLineResponseDTO {
+func getPostByTimeLine(req *model.GetPostByTimeLineRequestDTO, user *model.UserDTO) *model.GetPostByTimeLineResponseDTO {
 	resp := &model.GetPostByTimeLineResponseDTO{
 		BaseResp: model.BaseResp{
 			Code: model.Success,
@@ -151,15 +195,34 @@ func getPostByTimeLine(req *model.GetPostByTimeLineRequestDTO) *model.GetPostByT
 		req.PageSize = 10
 	}
 
+	// 读书会帖子只对正式成员可见
+	clubIds, err := visibleClubIds(user)
+	if err != nil {
+		resp.Code = model.InternalError
+		resp.Error = errors.New("获取读书会失败")
+		return resp
+	}
+	if req.ClubId > 0 && !slices.Contains(clubIds, req.ClubId) {
+		resp.Code = http.StatusForbidden
+		resp.Error = errors.New("不是该读书会成员")
+		return resp
+	}
+
 	// 获取帖子
 	repository := db.GetPostRepository()
-	posts, total, err := repository.GetPostByTimeLine(req.StartTime, req.EndTime, req.Category, req.Page, req.PageSize)
+	posts, total, err := repository.GetPostByTimeLine(req.StartTime, req.EndTime, req.Category, req.ClubId, clubIds, req.Page, req.PageSize)
 	if err != nil {
 		resp.Code = model.InternalError
 		resp.Error = errors.New("获取帖子失败")
 		return resp
 	}
 
+	if err = fillPostVotes(posts, user); err != nil {
+		resp.Code = model.InternalError
+		resp.Error = errors.New("获取投票失败")
+		return resp
+	}
+
 	resp.Posts = posts
 	resp.Total = total
 	return resp
@@ -174,7 +237,7 @@ func GetPostByUserId() gin.HandlerFunc {
 			return
 		}
 
-		resp := getPostByUserId(&req)
+		resp := getPostByUserId(&req, currentUser(c))
 		if resp.Code != model.Success {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error.Error()})
 			return
@@ -184,7 +247,7 @@ func GetPostByUserId() gin.HandlerFunc {
 	}
 }
 
-func getPostByUserId(req *model.GetPostByUserIdRequestDTO) *model.GetPostByUserIdResponseDTO {
+func getPostByUserId(req *model.GetPostByUserIdRequestDTO, user *model.UserDTO) *model.GetPostByUserIdResponseDTO {
 	resp := &model.GetPostByUserIdResponseDTO{
 		BaseResp: model.BaseResp{
 			Code: model.Success,
@@ -198,15 +261,28 @@ func getPostByUserId(req *model.GetPostByUserIdRequestDTO) *model.GetPostByUserI
 		return resp
 	}
 
+	clubIds, err := visibleClubIds(user)
+	if err != nil {
+		resp.Code = model.InternalError
+		resp.Error = errors.New("获取读书会失败")
+		return resp
+	}
+
 	// 获取帖子
 	repository := db.GetPostRepository()
-	posts, total, err := repository.GetPostByUserId(req.UserId, req.Page, req.PageSize)
+	posts, total, err := repository.GetPostByUserId(req.UserId, clubIds, req.Page, req.PageSize)
 	if err != nil {
 		resp.Code = model.InternalError
 		resp.Error = errors.New("获取帖子失败")
 		return resp
 	}
 
+	if err = fillPostVotes(posts, user); err != nil {
+		resp.Code = model.InternalError
+		resp.Error = errors.New("获取投票失败")
+		return resp
+	}
+
 	resp.Posts = posts
 	resp.Total = total
 	return resp
@@ -221,7 +297,7 @@ func GetPostById() gin.HandlerFunc {
 			return
 		}
 
-		resp := getPostById(&req)
+		resp := getPostById(&req, currentUser(c))
 		if resp.Code != model.Success {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error.Error()})
 			return
@@ -231,7 +307,7 @@ func GetPostById() gin.HandlerFunc {
 	}
 }
 
-func getPostById(req *model.GetPostByIdRequestDTO) *model.GetPostByIdResponseDTO {
+func getPostById(req *model.GetPostByIdRequestDTO, user *model.UserDTO) *model.GetPostByIdResponseDTO {
 	resp := &model.GetPostByIdResponseDTO{
 		BaseResp: model.BaseResp{
 			Code: model.Success,
@@ -254,7 +330,29 @@ func getPostById(req *model.GetPostByIdRequestDTO) *model.GetPostByIdResponseDTO
 		return resp
 	}
 
-	resp.Posts = posts
+	clubIds, err := visibleClubIds(user)
+	if err != nil {
+		resp.Code = model.InternalError
+		resp.Error = errors.New("获取读书会失败")
+		return resp
+	}
+
+	// 过滤掉看不到的读书会帖子
+	resp.Posts = make([]*model.PostDTO, 0, len(posts))
+	for _, post := range posts {
+		if !canViewPost(post, clubIds) {
+			continue
+		}
+		if post.Deleted {
+			post.Tombstone()
+		}
+		resp.Posts = append(resp.Posts, post)
+	}
+	if err = fillPostVotes(resp.Posts, user); err != nil {
+		resp.Code = model.InternalError
+		resp.Error = errors.New("获取投票失败")
+		return resp
+	}
 
 	return resp
 }
@@ -268,7 +366,11 @@ func GetPostContentByPostId() gin.HandlerFunc {
 			return
 		}
 
-		resp := getPostContentByPostId(&req)
+		resp := getPostContentByPostId(&req, currentUser(c))
+		if resp.Code == http.StatusForbidden {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error.Error()})
+			return
+		}
 		if resp.Code != model.Success {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error.Error()})
 			return
@@ -278,7 +380,7 @@ func GetPostContentByPostId() gin.HandlerFunc {
 	}
 }
 
-func getPostContentByPostId(req *model.GetPostContentByPostIdRequestDTO) *model.GetPostContentByPostIdResponseDTO {
+func getPostContentByPostId(req *model.GetPostContentByPostIdRequestDTO, user *model.UserDTO) *model.GetPostContentByPostIdResponseDTO {
 	resp := &model.GetPostContentByPostIdResponseDTO{
 		BaseResp: model.BaseResp{
 			Code: model.Success,
@@ -292,80 +394,110 @@ func getPostContentByPostId(req *model.GetPostContentByPostIdRequestDTO) *model.
 		return resp
 	}
 
+	visible, err := postVisible(req.PostId, user)
+	if err != nil {
+		resp.Code = model.InternalError
+		resp.Error = errors.New("获取帖子失败")
+		return resp
+	}
+	if !visible {
+		resp.Code = http.StatusForbidden
+		resp.Error = errors.New("不是该读书会成员")
+		return resp
+	}
+
+	post, err := db.GetPostRepository().GetPostDOById(req.PostId)
+	if err != nil {
+		resp.Code = model.InternalError
+		resp.Error = errors.New("获取帖子失败")
+		return resp
+	}
+	if post.Deleted {
+		resp.Content = model.PostTombstoneText
+		resp.SpoilerSpans = []model.SpoilerSpan{}
+		resp.Books = []*model.BookInfoDTO{}
+		resp.Deleted = true
+		return resp
+	}
+
 	// 通过es获取帖子内容
-	content, err := es.GetContentById(context.Background(), "post", strconv.FormatInt(req.PostId, 10))
+	content, contentHTML, err := getPostSource(post.Id)
 	if err != nil {
 		resp.Code = model.InternalError
 		resp.Error = errors.New("获取帖子内容失败")
 		return resp
 	}
 
-	resp.Content = content
+	postDTO := post.TransformToDTO(nil)
+	resp.Content, resp.SpoilerSpans = model.SpoilerView(content, postDTO.SpoilerSpans, post.Spoiler, req.RevealSpoilers)
+	resp.Spoiler = post.Spoiler
+	// 没有隐藏任何内容时直接用保存的HTML, 否则按替换占位后的正文重新渲染
+	if contentHTML != "" && resp.Content == content {
+		resp.ContentHTML = contentHTML
+	} else {
+		resp.ContentHTML = utils.RenderMarkdown(resp.Content)
+	}
+	resp.ContentHTML = signAttachmentHTML(resp.ContentHTML)
+	// 图书卡片只是附加信息, 获取失败不影响正文
+	if resp.Books, err = bookCards(resp.Content); err != nil {
+		log.GetLogger().Warnf("获取帖子%d引用的图书失败: %v", post.Id, err)
+		resp.Books = []*model.BookInfoDTO{}
+	}
 	return resp
 }
 
+// Like 赞帖子, 再次点击取消
 func Like() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		param := c.Param("postId")
-		postId, err := strconv.ParseInt(param, 10, 64)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid post ID", Error: err})
-			return
-		}
-
-		obj, _ := c.Get("user")
-		user := obj.(*model.UserDTO)
-
-		if err := updateLikeNum(postId, true, user.Id); err != nil {
-			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "update like num failed", Error: err})
-			return
-		}
-		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+		votePost(c, model.PostVoteLike)
 	}
 }
 
+// DisLike 踩帖子, 再次点击取消
 func DisLike() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		param := c.Param("postId")
-		postId, err := strconv.ParseInt(param, 10, 64)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid post ID", Error: err})
-			return
-		}
-
-		obj, _ := c.Get("user")
-		user := obj.(*model.UserDTO)
-
-		if err := updateLikeNum(postId, false, user.Id); err != nil {
-			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "update like num failed", Error: err})
-			return
-		}
-		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
+		votePost(c, model.PostVoteDislike)
 	}
 }
 
-func updateLikeNum(postId int64, like bool, userId int64) error {
-	repository := db.GetPostRepository()
-	posts, err := repository.GetPostById([]int64{postId})
+// votePost 赞/踩帖子, 再次点击同一按钮取消, 点击另一个按钮改票
+func votePost(c *gin.Context, value int) {
+	postId, err := strconv.ParseInt(c.Param("postId"), 10, 64)
 	if err != nil {
-		return err
+		c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid post ID", Error: err})
+		return
 	}
-	if len(posts) != 1 {
-		return err
+
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return
 	}
 
-	post := posts[0]
-	if like {
-		post.LikeUserIds = append(post.LikeUserIds, userId)
-	} else {
-		post.UnlikeUserIds = append(post.UnlikeUserIds, userId)
+	visible, err := postVisible(postId, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get post", Error: err})
+		return
 	}
-	if err = repository.UpdatePost(post); err != nil {
-		return err
+	if !visible {
+		c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "not a member of this club", Error: errors.New("forbidden")})
+		return
 	}
-	return nil
+
+	post, myVote, err := db.GetPostRepository().VotePost(postId, user.Id, value)
+	if err != nil {
+		writePostError(c, err, "update like num failed")
+		return
+	}
+	c.JSON(http.StatusOK, model.PostVoteResponse{
+		BaseResp:     model.BaseResp{Code: http.StatusOK, ErrMsg: "success"},
+		LikeCount:    post.LikeCount,
+		DislikeCount: post.DislikeCount,
+		MyVote:       myVote,
+	})
 }
 
+// CreateComment 评论帖子或回复帖子下的评论
 func CreateComment() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		postIdStr := c.Param("postId")
@@ -382,6 +514,25 @@ func CreateComment() gin.HandlerFunc {
 		}
 		user, _ := obj.(*model.UserDTO)
 
+		post, err := db.GetPostRepository().GetPostDOById(postId)
+		if err != nil {
+			writePostError(c, err, "failed to get post")
+			return
+		}
+		if post.Deleted {
+			writePostError(c, db.ErrPostDeleted, "")
+			return
+		}
+		visible, err := postVisible(postId, user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get post", Error: err})
+			return
+		}
+		if !visible {
+			c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "not a member of this club", Error: errors.New("forbidden")})
+			return
+		}
+
 		var req model.CreatePostCommentReq
 
 		if err = c.ShouldBindJSON(&req); err != nil {
@@ -390,75 +541,307 @@ func CreateComment() gin.HandlerFunc {
 		}
 
 		repository := db.GetPostRepository()
-		if err = repository.CreatePostComment(user, postId, req.Content); err != nil {
+		comment := &model.PostCommentDO{
+			PostId:      postId,
+			Depth:       1,
+			AuthorId:    user.Id,
+			AuthorName:  user.Name,
+			EditTime:    time.Now(),
+			Content:     req.Content,
+			ContentHTML: utils.RenderMarkdown(req.Content),
+		}
+		if req.ParentId > 0 {
+			parent, err := repository.GetPostCommentById(req.ParentId)
+			if err != nil || parent.PostId != postId {
+				c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "failed to find parent comment", Error: err})
+				return
+			}
+			comment.RootId = parent.RootId
+			if comment.RootId == 0 {
+				comment.RootId = parent.Id
+			}
+			// 超过最大层数时挂到父评论的同一层
+			if parent.Depth >= model.MaxPostCommentDepth {
+				comment.ParentId = parent.ParentId
+				comment.Depth = parent.Depth
+			} else {
+				comment.ParentId = parent.Id
+				comment.Depth = parent.Depth + 1
+			}
+		}
+
+		if err = repository.CreatePostComment(comment); err != nil {
 			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to create comment", Error: err})
 			return
-		} else {
-			c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
 		}
+		if err = es.Create(context.Background(), model.NewPostCommentEsModel(comment, post)); err != nil {
+			log.GetLogger().Warnf("评论%d写入索引失败: %v", comment.Id, err)
+		}
+		bindAttachments(user.Id, req.Content, postId, comment.Id)
+		processRefs(user, postId, comment.Id, req.Content, "")
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
 	}
 }
 
-func LikeComment() gin.HandlerFunc {
+// GetComments 按顶层评论分页获取帖子的评论
+// 查询参数: sort(oldest/newest/top), page, page_size
+func GetComments() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("commentId")
-		id, err := strconv.ParseInt(idStr, 10, 64)
+		postId, err := strconv.ParseInt(c.Param("postId"), 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid comment ID", Error: err})
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid post ID", Error: err})
 			return
 		}
 
-		obj, _ := c.Get("user")
-		user := obj.(*model.UserDTO)
+		visible, err := postVisible(postId, currentUser(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get post", Error: err})
+			return
+		}
+		if !visible {
+			c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "not a member of this club", Error: errors.New("forbidden")})
+			return
+		}
 
-		if err = updateCommentLikeNum(id, true, user.Id); err != nil {
-			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: err.Error(), Error: err})
+		sortBy := c.DefaultQuery("sort", model.PostCommentSortOldest)
+		if sortBy != model.PostCommentSortOldest && sortBy != model.PostCommentSortNewest && sortBy != model.PostCommentSortTop {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "sort must be oldest, newest or top", Error: errors.New("invalid sort")})
 			return
-		} else {
-			c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
 		}
-	}
-}
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 || pageSize > 100 {
+			pageSize = 20
+		}
 
-func DisLikeComment() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		idStr := c.Param("commentId")
-		id, err := strconv.ParseInt(idStr, 10, 64)
+		comments, total, err := db.GetPostRepository().ListPostComments(postId, sortBy, page, pageSize)
+		if err == nil {
+			err = fillCommentVotes(comments, currentUser(c))
+		}
+		if err == nil {
+			err = fillCommentBooks(comments)
+		}
 		if err != nil {
-			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid comment ID", Error: err})
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get comments", Error: err})
 			return
 		}
+		signCommentAttachments(comments)
+		c.JSON(http.StatusOK, model.PostCommentListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Comments: comments,
+			Total:    total,
+		})
+	}
+}
 
-		obj, _ := c.Get("user")
-		user := obj.(*model.UserDTO)
+// LikeComment 赞帖子评论, 再次点击取消
+func LikeComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		voteComment(c, model.PostVoteLike)
+	}
+}
 
-		if err = updateCommentLikeNum(id, false, user.Id); err != nil {
-			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: err.Error(), Error: err})
-			return
-		} else {
-			c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK, ErrMsg: "success"})
-		}
+// DisLikeComment 踩帖子评论, 再次点击取消
+func DisLikeComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		voteComment(c, model.PostVoteDislike)
 	}
 }
 
-func updateCommentLikeNum(commentId int64, like bool, userId int64) error {
+// voteComment 赞/踩帖子评论, 再次点击同一按钮取消, 点击另一个按钮改票
+func voteComment(c *gin.Context, value int) {
+	id, err := strconv.ParseInt(c.Param("commentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid comment ID", Error: err})
+		return
+	}
+
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return
+	}
+
 	repository := db.GetPostRepository()
-	comments, err := repository.BatchGetPostCommentById([]int64{commentId})
+	target, err := repository.GetPostCommentById(id)
+	if err != nil {
+		writeCommentError(c, err, "failed to get comment")
+		return
+	}
+	visible, err := postVisible(target.PostId, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get post", Error: err})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusForbidden, model.BaseResp{Code: http.StatusForbidden, ErrMsg: "not a member of this club", Error: errors.New("forbidden")})
+		return
+	}
+
+	comment, myVote, err := repository.VotePostComment(id, user.Id, value)
+	if err != nil {
+		writeCommentError(c, err, "update like num failed")
+		return
+	}
+	c.JSON(http.StatusOK, model.PostVoteResponse{
+		BaseResp:     model.BaseResp{Code: http.StatusOK, ErrMsg: "success"},
+		LikeCount:    comment.LikeCount,
+		DislikeCount: comment.DislikeCount,
+		MyVote:       myVote,
+	})
+}
+
+// fillPostVotes 填上当前用户对帖子的投票, 未登录时不填
+func fillPostVotes(posts []*model.PostDTO, user *model.UserDTO) error {
+	if user == nil || len(posts) == 0 {
+		return nil
+	}
+	postIds := make([]int64, len(posts))
+	for i, post := range posts {
+		postIds[i] = post.Id
+	}
+	votes, err := db.GetPostRepository().GetPostVotes(user.Id, postIds)
 	if err != nil {
 		return err
 	}
-	if len(comments) != 1 {
-		return errors.New("comment not found")
+	for _, post := range posts {
+		post.MyVote = votes[post.Id]
 	}
+	return nil
+}
 
-	comment := comments[0]
-	if like {
-		comment.LikeUserIds = append(comment.LikeUserIds, userId)
-	} else {
-		comment.DislikeUserIds = append(comment.DislikeUserIds, userId)
+// fillCommentVotes 填上当前用户对评论及其子回复的投票, 未登录时不填
+func fillCommentVotes(comments []*model.PostCommentDTO, user *model.UserDTO) error {
+	if user == nil || len(comments) == 0 {
+		return nil
+	}
+	var all []*model.PostCommentDTO
+	var collect func([]*model.PostCommentDTO)
+	collect = func(nodes []*model.PostCommentDTO) {
+		for _, node := range nodes {
+			all = append(all, node)
+			collect(node.Replies)
+		}
 	}
-	if err = repository.UpdateComment(comment); err != nil {
+	collect(comments)
+
+	commentIds := make([]int64, len(all))
+	for i, comment := range all {
+		commentIds[i] = comment.Id
+	}
+	votes, err := db.GetPostRepository().GetPostCommentVotes(user.Id, commentIds)
+	if err != nil {
 		return err
 	}
+	for _, comment := range all {
+		comment.MyVote = votes[comment.Id]
+	}
 	return nil
 }
+
+func currentUser(c *gin.Context) *model.UserDTO {
+	obj, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+	user, _ := obj.(*model.UserDTO)
+	return user
+}
+
+// newPostEsModel 构建帖子的ES文档, 同时保存Markdown原文和渲染后的HTML; 搜索字段为不含剧透的纯文本, 整篇剧透的帖子只索引标题
+func newPostEsModel(post *model.PostDTO, content string) *model.PostEsModel {
+	return &model.PostEsModel{
+		Id:          strconv.FormatInt(post.Id, 10),
+		Title:       post.Title,
+		Content:     searchText(post, content),
+		FullContent: content,
+		ContentHTML: utils.RenderMarkdown(content),
+		PostId:      post.Id,
+		AuthorId:    post.Author.Id,
+		ClubId:      post.ClubId,
+		Category:    post.Category,
+		CategoryId:  post.CategoryId,
+		CreatedAt:   post.EditTime,
+	}
+}
+
+// postAuthor 帖子记录中的作者信息
+func postAuthor(post *model.PostDO) *model.UserDTO {
+	return &model.UserDTO{Id: post.AuthorId, Name: post.AuthorName}
+}
+
+// searchText 正文去掉剧透后的纯文本, 整篇剧透的帖子为空
+func searchText(post *model.PostDTO, content string) string {
+	if post.Spoiler {
+		return ""
+	}
+	return utils.MarkdownToText(model.StripSpoilers(content, post.SpoilerSpans))
+}
+
+// postPreview 帖子列表中展示的摘要
+func postPreview(post *model.PostDTO, content string) string {
+	return utils.TruncateText(strings.Join(strings.Fields(searchText(post, content)), " "), model.PostPreviewLength)
+}
+
+// getPostContent 从ES读取帖子的完整正文
+func getPostContent(postId int64) (string, error) {
+	content, _, err := getPostSource(postId)
+	return content, err
+}
+
+// getPostSource 从ES读取帖子的Markdown正文和渲染后的HTML, 旧数据没有full_content时使用content, 没有HTML时为空
+func getPostSource(postId int64) (string, string, error) {
+	source, err := es.GetSourceById(context.Background(), "post", strconv.FormatInt(postId, 10))
+	if err != nil {
+		return "", "", err
+	}
+	content, ok := source["full_content"].(string)
+	if !ok {
+		content, _ = source["content"].(string)
+	}
+	contentHTML, _ := source["content_html"].(string)
+	return content, contentHTML, nil
+}
+
+// visibleClubIds 获取用户可以查看帖子的读书会, 未登录时为空
+func visibleClubIds(user *model.UserDTO) ([]int64, error) {
+	if user == nil {
+		return nil, nil
+	}
+	return db.GetClubRepository().GetActiveClubIds(user.Id)
+}
+
+// isActiveMember 用户是否为读书会的正式成员
+func isActiveMember(clubId int64, user *model.UserDTO) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	member, err := db.GetClubRepository().GetMember(clubId, user.Id)
+	if err != nil {
+		return false, err
+	}
+	return member != nil && member.Status == model.ClubMemberActive, nil
+}
+
+// canViewPost 公开帖子所有人可见, 读书会帖子仅正式成员可见
+func canViewPost(post *model.PostDTO, clubIds []int64) bool {
+	return post.ClubId == 0 || slices.Contains(clubIds, post.ClubId)
+}
+
+// postVisible 用户能否查看帖子, 帖子不存在时交由后续逻辑处理
+func postVisible(postId int64, user *model.UserDTO) (bool, error) {
+	clubId, err := db.GetPostRepository().GetPostClubId(postId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if clubId == 0 {
+		return true, nil
+	}
+	return isActiveMember(clubId, user)
+}
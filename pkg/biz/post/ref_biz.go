@@ -0,0 +1,183 @@
+package post
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/biz/notification"
+	"yujian-backend/pkg/biz/recommend"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+)
+
+// GetHashtagPosts 话题页, 列出当前用户可见的使用了该话题的帖子
+// 查询参数: page, page_size
+func GetHashtagPosts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Param("name")), "#"))
+		tag, err := db.GetTagRepository().GetTagByName(name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "hashtag not found", Error: err})
+			} else {
+				c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get hashtag", Error: err})
+			}
+			return
+		}
+		if tag.Banned {
+			c.JSON(http.StatusNotFound, model.BaseResp{Code: http.StatusNotFound, ErrMsg: "hashtag not found", Error: errors.New("hashtag is banned")})
+			return
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 || pageSize > 100 {
+			pageSize = 20
+		}
+
+		clubIds, err := visibleClubIds(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get clubs", Error: err})
+			return
+		}
+		posts, total, err := db.GetPostRepository().ListPostsByHashtag(tag.Id, clubIds, page, pageSize)
+		if err == nil {
+			err = fillPostVotes(posts, user)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get posts", Error: err})
+			return
+		}
+		c.JSON(http.StatusOK, model.HashtagPostsResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Tag:      &model.TagDTO{Id: tag.Id, Name: tag.Name, Count: total},
+			Posts:    posts,
+			Total:    total,
+		})
+	}
+}
+
+// processRefs 处理正文中的引用: 更新话题索引, 通知新@的用户, 新引用的图书记为author的阅读兴趣
+// author为正文的作者而不是编辑人; 编辑时只处理相对旧正文新增的@和图书引用, 避免重复通知; 引用以正文为准, 失败只记录日志
+func processRefs(author *model.UserDTO, postId, commentId int64, content, prevContent string) {
+	if err := db.GetTagRepository().SetPostHashtags(postId, commentId, model.ParseHashtags(content)); err != nil {
+		log.GetLogger().Warnf("更新帖子%d的话题索引失败: %v", postId, err)
+	}
+
+	prevNames := model.ParseMentions(prevContent)
+	var names []string
+	for _, name := range model.ParseMentions(content) {
+		if name != author.Name && !slices.Contains(prevNames, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		notifyMentions(author, postId, commentId, names)
+	}
+
+	prevBookIds := model.ParseBookRefs(prevContent)
+	var bookIds []int64
+	for _, bookId := range model.ParseBookRefs(content) {
+		if !slices.Contains(prevBookIds, bookId) {
+			bookIds = append(bookIds, bookId)
+		}
+	}
+	books, err := db.GetBookRepository().GetBooksByIds(bookIds)
+	if err != nil {
+		log.GetLogger().Warnf("获取引用的图书失败: %v", err)
+		return
+	}
+	for _, bookId := range bookIds {
+		if book, ok := books[bookId]; ok {
+			go recommend.RecordUserAction(author, book.Id, book.Name, book.Category)
+		}
+	}
+}
+
+// notifyMentions 通知被@的用户, 看不到帖子的用户(如非读书会成员)不通知
+func notifyMentions(author *model.UserDTO, postId, commentId int64, names []string) {
+	post, err := db.GetPostRepository().GetPostDOById(postId)
+	if err != nil {
+		log.GetLogger().Warnf("获取帖子%d失败, 跳过@通知: %v", postId, err)
+		return
+	}
+	where := "帖子"
+	if commentId > 0 {
+		where = "评论"
+	}
+	for _, name := range names {
+		mentioned, err := db.GetUserRepository().GetUserByName(name)
+		if err != nil {
+			continue
+		}
+		visible, err := postVisible(postId, mentioned)
+		if err != nil || !visible {
+			continue
+		}
+		notification.Send(mentioned.Id, model.NotifyForumMention, "有人提到了你",
+			fmt.Sprintf("%s 在《%s》的%s中提到了你", author.Name, post.Title, where), postId)
+	}
+}
+
+// bookCards 按引用顺序返回正文中引用的图书卡片, 不存在的图书忽略
+func bookCards(content string) ([]*model.BookInfoDTO, error) {
+	bookIds := model.ParseBookRefs(content)
+	books, err := db.GetBookRepository().GetBooksByIds(bookIds)
+	if err != nil {
+		return nil, err
+	}
+	cards := []*model.BookInfoDTO{}
+	for _, bookId := range bookIds {
+		if book, ok := books[bookId]; ok {
+			cards = append(cards, book)
+		}
+	}
+	return cards, nil
+}
+
+// fillCommentBooks 填上评论及其子回复中引用的图书卡片, 一次查询全部图书
+func fillCommentBooks(comments []*model.PostCommentDTO) error {
+	var all []*model.PostCommentDTO
+	var collect func([]*model.PostCommentDTO)
+	collect = func(nodes []*model.PostCommentDTO) {
+		for _, node := range nodes {
+			all = append(all, node)
+			collect(node.Replies)
+		}
+	}
+	collect(comments)
+
+	refs := make(map[int64][]int64, len(all))
+	var bookIds []int64
+	for _, comment := range all {
+		refs[comment.Id] = model.ParseBookRefs(comment.Content)
+		bookIds = append(bookIds, refs[comment.Id]...)
+	}
+	if len(bookIds) == 0 {
+		return nil
+	}
+	books, err := db.GetBookRepository().GetBooksByIds(bookIds)
+	if err != nil {
+		return err
+	}
+	for _, comment := range all {
+		for _, bookId := range refs[comment.Id] {
+			if book, ok := books[bookId]; ok {
+				comment.Books = append(comment.Books, book)
+			}
+		}
+	}
+	return nil
+}
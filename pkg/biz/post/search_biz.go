@@ -0,0 +1,217 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/es"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+)
+
+// maxSearchPageSize 论坛搜索每页最多条数
+const maxSearchPageSize = 50
+
+// reindexBatchSize 重建索引时每批处理的条数
+const reindexBatchSize = 200
+
+// Search 论坛全文搜索, 同时搜索帖子和评论, 剧透内容不参与匹配
+// 查询参数: q, type(post/comment), category, author_id, start, end(2006-01-02或RFC3339), sort(relevance/recent), page, page_size
+func Search() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := &model.ForumSearchQuery{
+			Keyword:  strings.TrimSpace(c.Query("q")),
+			Type:     c.Query("type"),
+			Category: strings.TrimSpace(c.Query("category")),
+			Sort:     c.DefaultQuery("sort", model.ForumSortRelevance),
+		}
+		if query.Keyword == "" {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "q is required", Error: errors.New("q is required")})
+			return
+		}
+		if query.Type != "" && query.Type != model.ForumSearchPost && query.Type != model.ForumSearchComment {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "type must be post or comment", Error: errors.New("invalid type")})
+			return
+		}
+		if query.Sort != model.ForumSortRelevance && query.Sort != model.ForumSortRecent {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "sort must be relevance or recent", Error: errors.New("invalid sort")})
+			return
+		}
+		if authorId := c.Query("author_id"); authorId != "" {
+			id, err := strconv.ParseInt(authorId, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid author_id", Error: err})
+				return
+			}
+			query.AuthorId = id
+		}
+		var err error
+		if query.StartTime, err = parseSearchTime(c.Query("start"), false); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid start", Error: err})
+			return
+		}
+		if query.EndTime, err = parseSearchTime(c.Query("end"), true); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "invalid end", Error: err})
+			return
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 || pageSize > maxSearchPageSize {
+			pageSize = 10
+		}
+		query.From = (page - 1) * pageSize
+		query.Size = pageSize
+
+		// 分类归一到规范分类, 未知分类按原文过滤
+		if query.Category != "" {
+			if query.CategoryId, err = db.GetCategoryRepository().ResolveCategory(query.Category, false); err != nil {
+				log.GetLogger().Warnf("解析搜索分类失败: %v", err)
+			}
+		}
+		if query.ClubIds, err = visibleClubIds(currentUser(c)); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get clubs", Error: err})
+			return
+		}
+
+		hits, total, err := es.SearchForum(context.Background(), query)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "search failed", Error: err})
+			return
+		}
+
+		// 评论命中补上帖子标题, 同时去掉索引未及时清理的已删除帖子
+		postIds := make([]int64, len(hits))
+		for i, hit := range hits {
+			postIds[i] = hit.PostId
+		}
+		posts, err := db.GetPostRepository().GetPostDOsByIds(postIds)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Code: http.StatusInternalServerError, ErrMsg: "failed to get posts", Error: err})
+			return
+		}
+		result := make([]*model.ForumSearchHit, 0, len(hits))
+		for _, hit := range hits {
+			post, ok := posts[hit.PostId]
+			if !ok || post.Deleted {
+				continue
+			}
+			if hit.Type == model.ForumSearchComment {
+				hit.Title = post.Title
+			}
+			result = append(result, hit)
+		}
+
+		c.JSON(http.StatusOK, model.ForumSearchResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Hits:     result,
+			Total:    total,
+		})
+	}
+}
+
+// Reindex 后台重建帖子和评论的搜索索引, 用于补齐旧数据的过滤字段、渲染后的HTML和历史评论
+func Reindex() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		go reindexForum()
+		c.JSON(http.StatusAccepted, model.BaseResp{Code: http.StatusAccepted, ErrMsg: "reindex started"})
+	}
+}
+
+func reindexForum() {
+	ctx := context.Background()
+	repository := db.GetPostRepository()
+
+	var postCount, commentCount int
+	for afterId := int64(0); ; {
+		posts, err := repository.ListLivePostsAfter(afterId, reindexBatchSize)
+		if err != nil {
+			log.GetLogger().Errorf("重建帖子索引失败: %v", err)
+			return
+		}
+		if len(posts) == 0 {
+			break
+		}
+		for _, post := range posts {
+			afterId = post.Id
+			content, err := getPostContent(post.Id)
+			if err != nil {
+				log.GetLogger().Warnf("读取帖子%d正文失败: %v", post.Id, err)
+				continue
+			}
+			postDTO := post.TransformToDTO(postAuthor(post))
+			if err = es.UpdateArticle(ctx, newPostEsModel(postDTO, content)); err != nil {
+				log.GetLogger().Warnf("重建帖子%d索引失败: %v", post.Id, err)
+				continue
+			}
+			// 顺带补齐历史帖子的摘要
+			if post.Preview == "" {
+				if err = repository.UpdatePostPreview(post.Id, postPreview(postDTO, content)); err != nil {
+					log.GetLogger().Warnf("更新帖子%d摘要失败: %v", post.Id, err)
+				}
+			}
+			postCount++
+		}
+	}
+
+	for afterId := int64(0); ; {
+		comments, err := repository.ListLiveCommentsAfter(afterId, reindexBatchSize)
+		if err != nil {
+			log.GetLogger().Errorf("重建评论索引失败: %v", err)
+			return
+		}
+		if len(comments) == 0 {
+			break
+		}
+		postIds := make([]int64, len(comments))
+		for i, comment := range comments {
+			postIds[i] = comment.PostId
+		}
+		posts, err := repository.GetPostDOsByIds(postIds)
+		if err != nil {
+			log.GetLogger().Errorf("重建评论索引失败: %v", err)
+			return
+		}
+		for _, comment := range comments {
+			afterId = comment.Id
+			post, ok := posts[comment.PostId]
+			if !ok || post.Deleted {
+				continue
+			}
+			if err = es.UpdateArticle(ctx, model.NewPostCommentEsModel(comment, post)); err != nil {
+				log.GetLogger().Warnf("重建评论%d索引失败: %v", comment.Id, err)
+				continue
+			}
+			commentCount++
+		}
+	}
+	log.GetLogger().Infof("论坛索引重建完成, 帖子%d条, 评论%d条", postCount, commentCount)
+}
+
+// parseSearchTime 解析搜索的时间范围, 只给日期时结束时间取当天最后一刻
+func parseSearchTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
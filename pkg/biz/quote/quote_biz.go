@@ -0,0 +1,371 @@
+package quote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/es"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+)
+
+const (
+	maxContentLength = 2000
+	maxCommentLength = 2000
+	// TopQuotesNum 图书详情页展示的热门书摘数
+	TopQuotesNum = 3
+)
+
+// CreateQuote 摘录书摘, 同时写入ES用于全文搜索
+func CreateQuote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.QuoteResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		var req model.CreateQuoteRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.BookId <= 0 {
+			c.JSON(http.StatusBadRequest, model.QuoteResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "book_id is required"},
+			})
+			return
+		}
+		req.Content = strings.TrimSpace(req.Content)
+		if req.Content == "" || utf8.RuneCountInString(req.Content) > maxContentLength ||
+			utf8.RuneCountInString(req.Comment) > maxCommentLength || req.Page < 0 {
+			c.JSON(http.StatusBadRequest, model.QuoteResponse{
+				BaseResp: model.BaseResp{Error: errors.New("invalid quote"), Code: http.StatusBadRequest, ErrMsg: "content must be 1-2000 characters"},
+			})
+			return
+		}
+		book, err := db.GetBookRepository().GetBookById(req.BookId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.QuoteResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "book not found"},
+			})
+			return
+		}
+
+		repository := db.GetQuoteRepository()
+		quote := &model.QuoteDO{
+			BookId:    book.Id,
+			UserId:    user.Id,
+			Content:   req.Content,
+			Page:      req.Page,
+			Comment:   req.Comment,
+			CreatedAt: time.Now(),
+		}
+		if _, err = repository.CreateQuote(quote); err != nil {
+			c.JSON(http.StatusInternalServerError, model.QuoteResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create quote"},
+			})
+			return
+		}
+		if err = es.Create(context.Background(), toEsModel(quote)); err != nil {
+			// 保存到ES失败, 删除书摘
+			if errRollback := repository.DeleteQuote(quote.Id); errRollback != nil {
+				log.GetLogger().Errorf("failed to rollback quote %d: %v", quote.Id, errRollback)
+			}
+			c.JSON(http.StatusInternalServerError, model.QuoteResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to index quote"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.QuoteResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Quote:    quote.Transfer(book),
+		})
+	}
+}
+
+// GetQuote 获取单条书摘, 带分享文本
+func GetQuote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		quoteId, err := strconv.ParseInt(c.Param("quoteId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.QuoteResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid quote ID"},
+			})
+			return
+		}
+		quote, err := db.GetQuoteRepository().GetQuoteById(quoteId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.QuoteResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "quote not found"},
+			})
+			return
+		}
+		quotes, err := ToQuoteDTOs([]*model.QuoteDO{quote}, currentUser(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.QuoteResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to get quote"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.QuoteResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Quote:    quotes[0],
+		})
+	}
+}
+
+// DeleteQuote 删除书摘, 仅作者或版主可删除
+func DeleteQuote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		quoteId, err := strconv.ParseInt(c.Param("quoteId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid quote ID"})
+			return
+		}
+		repository := db.GetQuoteRepository()
+		quote, err := repository.GetQuoteById(quoteId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "quote not found"})
+			return
+		}
+		if quote.UserId != user.Id && !user.HasRole(model.RoleModerator) {
+			c.JSON(http.StatusForbidden, model.BaseResp{Error: errors.New("forbidden"), Code: http.StatusForbidden, ErrMsg: "not the author of the quote"})
+			return
+		}
+		if err = repository.DeleteQuote(quoteId); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to delete quote"})
+			return
+		}
+		if err = es.DeleteArticle(context.Background(), toEsModel(quote)); err != nil {
+			log.GetLogger().Warnf("failed to delete quote %d from ES: %v", quoteId, err)
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// ListBookQuotes 分页获取图书的书摘, sort=latest按时间, 默认按热度
+func ListBookQuotes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookId, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.QuoteListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid book ID"},
+			})
+			return
+		}
+		page, pageSize := pagination(c)
+		quotes, total, err := db.GetQuoteRepository().ListBookQuotes(bookId, c.Query("sort"), page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.QuoteListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list quotes"},
+			})
+			return
+		}
+		writeQuotes(c, quotes, total)
+	}
+}
+
+// SearchQuotes 全文搜索书摘, 可用book_id限定在某本书内
+func SearchQuotes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keyword := strings.TrimSpace(c.Query("keyword"))
+		if keyword == "" {
+			c.JSON(http.StatusBadRequest, model.QuoteListResponse{
+				BaseResp: model.BaseResp{Error: errors.New("empty keyword"), Code: http.StatusBadRequest, ErrMsg: "keyword is required"},
+			})
+			return
+		}
+		var bookId int64
+		if value := c.Query("book_id"); value != "" {
+			var err error
+			if bookId, err = strconv.ParseInt(value, 10, 64); err != nil {
+				c.JSON(http.StatusBadRequest, model.QuoteListResponse{
+					BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid book ID"},
+				})
+				return
+			}
+		}
+		page, pageSize := pagination(c)
+
+		quoteIds, total, err := es.SearchQuotes(c, keyword, bookId, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.QuoteListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to search quotes"},
+			})
+			return
+		}
+		quotes, err := db.GetQuoteRepository().GetQuotesByIds(quoteIds)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.QuoteListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to search quotes"},
+			})
+			return
+		}
+		writeQuotes(c, quotes, total)
+	}
+}
+
+// ListBookmarks 分页获取自己收藏的书摘
+func ListBookmarks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.QuoteListResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		page, pageSize := pagination(c)
+		quotes, total, err := db.GetQuoteRepository().ListBookmarkedQuotes(user.Id, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.QuoteListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list bookmarks"},
+			})
+			return
+		}
+		writeQuotes(c, quotes, total)
+	}
+}
+
+// Like 点赞书摘
+func Like() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		markQuote(c, db.GetQuoteRepository().Like)
+	}
+}
+
+// Unlike 取消点赞
+func Unlike() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		markQuote(c, db.GetQuoteRepository().Unlike)
+	}
+}
+
+// Bookmark 收藏书摘
+func Bookmark() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		markQuote(c, db.GetQuoteRepository().Bookmark)
+	}
+}
+
+// Unbookmark 取消收藏
+func Unbookmark() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		markQuote(c, db.GetQuoteRepository().Unbookmark)
+	}
+}
+
+func markQuote(c *gin.Context, mark func(quoteId, userId int64) error) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return
+	}
+	quoteId, err := strconv.ParseInt(c.Param("quoteId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid quote ID"})
+		return
+	}
+	if _, err = db.GetQuoteRepository().GetQuoteById(quoteId); err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "quote not found"})
+		return
+	}
+	if err = mark(quoteId, user.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to update quote"})
+		return
+	}
+	c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+}
+
+// ToQuoteDTOs 填充书名、分享文本以及当前用户的点赞收藏状态, user可以为空
+func ToQuoteDTOs(quotes []*model.QuoteDO, user *model.UserDTO) ([]*model.QuoteDTO, error) {
+	quoteIds := make([]int64, len(quotes))
+	bookIds := make([]int64, len(quotes))
+	for i, quote := range quotes {
+		quoteIds[i] = quote.Id
+		bookIds[i] = quote.BookId
+	}
+	books, err := db.GetBookRepository().GetBooksByIds(bookIds)
+	if err != nil {
+		return nil, err
+	}
+	liked, bookmarked := map[int64]bool{}, map[int64]bool{}
+	if user != nil {
+		if liked, bookmarked, err = db.GetQuoteRepository().GetUserQuoteStates(user.Id, quoteIds); err != nil {
+			return nil, err
+		}
+	}
+
+	quoteDTOs := make([]*model.QuoteDTO, len(quotes))
+	for i, quote := range quotes {
+		quoteDTOs[i] = quote.Transfer(books[quote.BookId])
+		quoteDTOs[i].Liked = liked[quote.Id]
+		quoteDTOs[i].Bookmarked = bookmarked[quote.Id]
+	}
+	return quoteDTOs, nil
+}
+
+// TopQuotes 获取图书的热门书摘, 用于图书详情
+func TopQuotes(bookId int64, user *model.UserDTO) ([]*model.QuoteDTO, error) {
+	quotes, _, err := db.GetQuoteRepository().ListBookQuotes(bookId, "", 1, TopQuotesNum)
+	if err != nil {
+		return nil, err
+	}
+	return ToQuoteDTOs(quotes, user)
+}
+
+func writeQuotes(c *gin.Context, quotes []*model.QuoteDO, total int64) {
+	quoteDTOs, err := ToQuoteDTOs(quotes, currentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.QuoteListResponse{
+			BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list quotes"},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, model.QuoteListResponse{
+		BaseResp: model.BaseResp{Code: http.StatusOK},
+		Quotes:   quoteDTOs,
+		Total:    total,
+	})
+}
+
+func toEsModel(quote *model.QuoteDO) *model.QuoteEsModel {
+	return &model.QuoteEsModel{
+		Id:      strconv.FormatInt(quote.Id, 10),
+		BookId:  quote.BookId,
+		Content: quote.Content,
+		Comment: quote.Comment,
+	}
+}
+
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+func currentUser(c *gin.Context) *model.UserDTO {
+	obj, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+	user, _ := obj.(*model.UserDTO)
+	return user
+}
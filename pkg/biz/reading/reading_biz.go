@@ -0,0 +1,368 @@
+package reading
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/biz/achievement"
+	"yujian-backend/pkg/biz/recommend"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+const (
+	maxNoteLength       = 1000
+	favoriteCategoryNum = 5
+)
+
+// LogProgress 记录阅读进度, 读完时图书自动移入"读过"书架
+func LogProgress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ProgressResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		var req model.LogProgressRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.BookId <= 0 {
+			c.JSON(http.StatusBadRequest, model.ProgressResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "book_id is required"},
+			})
+			return
+		}
+		if req.Page < 0 || req.TotalPages < 0 || req.Percent < 0 || req.Percent > 100 ||
+			(req.TotalPages > 0 && req.Page > req.TotalPages) {
+			c.JSON(http.StatusBadRequest, model.ProgressResponse{
+				BaseResp: model.BaseResp{Error: errors.New("invalid progress"), Code: http.StatusBadRequest, ErrMsg: "invalid page or percent"},
+			})
+			return
+		}
+		if req.Page == 0 && req.Percent == 0 && !req.Finished {
+			c.JSON(http.StatusBadRequest, model.ProgressResponse{
+				BaseResp: model.BaseResp{Error: errors.New("empty progress"), Code: http.StatusBadRequest, ErrMsg: "page or percent is required"},
+			})
+			return
+		}
+		if utf8.RuneCountInString(req.Note) > maxNoteLength {
+			c.JSON(http.StatusBadRequest, model.ProgressResponse{
+				BaseResp: model.BaseResp{Error: errors.New("note too long"), Code: http.StatusBadRequest, ErrMsg: "note is too long"},
+			})
+			return
+		}
+		book, err := db.GetBookRepository().GetBookById(req.BookId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.ProgressResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "book not found"},
+			})
+			return
+		}
+
+		progress, err := db.GetProgressRepository().LogProgress(user.Id, &req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ProgressResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to log progress"},
+			})
+			return
+		}
+		go recommend.RecordUserAction(user, book.Id, book.Author)
+		achievement.Publish(user.Id, model.EventProgress)
+		c.JSON(http.StatusOK, model.ProgressResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Progress: []*model.ReadingProgressDTO{progress.Transfer()},
+		})
+	}
+}
+
+// GetBookProgress 获取自己某本书的阅读进度记录
+func GetBookProgress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ProgressResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		bookId, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ProgressResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid book ID"},
+			})
+			return
+		}
+		progress, err := db.GetProgressRepository().ListBookProgress(user.Id, bookId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ProgressResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list progress"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ProgressResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Progress: progress,
+		})
+	}
+}
+
+// GetStats 获取自己的年度阅读统计, 年份由query参数year指定, 默认今年
+func GetStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.ReadingStatsResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		year, err := parseYear(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ReadingStatsResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid year"},
+			})
+			return
+		}
+		summary, err := summarize(user.Id, year)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ReadingStatsResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to compute stats"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ReadingStatsResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Stats:    summary.stats,
+		})
+	}
+}
+
+// GetYearInReview 获取自己的年度阅读总结
+func GetYearInReview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.YearInReviewResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		year, err := parseYear(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.YearInReviewResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid year"},
+			})
+			return
+		}
+		summary, err := summarize(user.Id, year)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.YearInReviewResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to compute stats"},
+			})
+			return
+		}
+
+		stats := summary.stats
+		review := &model.YearInReviewDTO{Stats: stats}
+		if n := len(summary.finished); n > 0 {
+			review.FirstBook = summary.books[summary.finished[0]]
+			review.LastBook = summary.books[summary.finished[n-1]]
+		}
+		topScore := 0.0
+		for _, bookId := range summary.finished {
+			if score, ok := summary.scores[bookId]; ok && score > topScore {
+				topScore = score
+				review.TopRatedBook = summary.books[bookId]
+			}
+		}
+		mostPages := 0
+		for _, month := range stats.Monthly {
+			if month.Pages > mostPages {
+				mostPages = month.Pages
+				review.MostActiveMonth = month.Month
+			}
+		}
+		if len(stats.FavoriteCategory) > 0 {
+			review.TopCategory = stats.FavoriteCategory[0].Category
+		}
+		if stats.ReadingDays > 0 {
+			review.AveragePagesADay = math.Round(float64(stats.TotalPages)/float64(stats.ReadingDays)*10) / 10
+		}
+		c.JSON(http.StatusOK, model.YearInReviewResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Review:   review,
+		})
+	}
+}
+
+func parseYear(c *gin.Context) (int, error) {
+	now := time.Now()
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(now.Year())))
+	if err != nil {
+		return 0, err
+	}
+	if year < 1970 || year > now.Year() {
+		return 0, errors.New("year out of range")
+	}
+	return year, nil
+}
+
+// yearSummary 年度统计及生成年度总结所需的中间结果
+type yearSummary struct {
+	stats    *model.ReadingStatsDTO
+	finished []int64 // 当年读完的图书, 按读完时间排序
+	books    map[int64]*model.BookInfoDTO
+	scores   map[int64]float64
+}
+
+// summarize 根据进度记录计算用户某一年的阅读统计
+func summarize(userId int64, year int) (*yearSummary, error) {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(1, 0, 0)
+	now := time.Now()
+
+	// 连续阅读天数可能跨年, 多取一年的记录
+	repository := db.GetProgressRepository()
+	events, err := repository.ListProgressBetween(userId, start.AddDate(-1, 0, 0), end)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := repository.ListUserScores(userId, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &model.ReadingStatsDTO{Year: year, Monthly: make([]model.MonthlyReading, 12)}
+	for i := range stats.Monthly {
+		stats.Monthly[i].Month = i + 1
+	}
+	var finished []int64
+	finishedSet := make(map[int64]bool)
+	allDays := make(map[time.Time]bool)
+	var yearDays []time.Time
+	for _, event := range events {
+		createdAt := event.CreatedAt.In(time.Local)
+		day := time.Date(createdAt.Year(), createdAt.Month(), createdAt.Day(), 0, 0, 0, 0, time.Local)
+		if !allDays[day] && !day.Before(start) {
+			yearDays = append(yearDays, day)
+		}
+		allDays[day] = true
+		if createdAt.Before(start) {
+			continue
+		}
+
+		month := &stats.Monthly[createdAt.Month()-1]
+		month.Pages += event.PagesRead
+		stats.TotalPages += event.PagesRead
+		// 一年内同一本书只在第一次读完的月份计数
+		if event.Finished && !finishedSet[event.BookId] {
+			finishedSet[event.BookId] = true
+			finished = append(finished, event.BookId)
+			month.Books++
+		}
+	}
+	stats.TotalBooks = len(finished)
+	stats.ReadingDays = len(yearDays)
+	stats.LongestStreak = longestStreak(yearDays)
+	if year == now.Year() {
+		stats.CurrentStreak = currentStreak(allDays, now)
+	}
+
+	if len(scores) > 0 {
+		sum := 0.0
+		for _, score := range scores {
+			sum += score
+		}
+		stats.RatingCount = len(scores)
+		stats.AverageRating = math.Round(sum/float64(len(scores))*10) / 10
+	}
+
+	books, err := db.GetBookRepository().GetBooksByIds(finished)
+	if err != nil {
+		return nil, err
+	}
+	if stats.FavoriteCategory, err = favoriteCategories(finished, books); err != nil {
+		return nil, err
+	}
+
+	return &yearSummary{stats: stats, finished: finished, books: books, scores: scores}, nil
+}
+
+// favoriteCategories 按规范分类统计读完的书最多的分类, 没有归入规范分类的书不计
+func favoriteCategories(bookIds []int64, books map[int64]*model.BookInfoDTO) ([]model.CategoryCount, error) {
+	counts := make(map[int64]int)
+	for _, bookId := range bookIds {
+		if book, ok := books[bookId]; ok && book.CategoryId > 0 {
+			counts[book.CategoryId]++
+		}
+	}
+	categoryIds := make([]int64, 0, len(counts))
+	for categoryId := range counts {
+		categoryIds = append(categoryIds, categoryId)
+	}
+	categoryDOs, err := db.GetCategoryRepository().GetCategoriesByIds(categoryIds)
+	if err != nil {
+		return nil, err
+	}
+	categories := make([]model.CategoryCount, 0, len(counts))
+	for categoryId, count := range counts {
+		if category, ok := categoryDOs[categoryId]; ok {
+			categories = append(categories, model.CategoryCount{CategoryId: categoryId, Category: category.Name, Books: count})
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Books != categories[j].Books {
+			return categories[i].Books > categories[j].Books
+		}
+		return categories[i].Category < categories[j].Category
+	})
+	if len(categories) > favoriteCategoryNum {
+		categories = categories[:favoriteCategoryNum]
+	}
+	return categories, nil
+}
+
+// longestStreak 计算按时间排序的日期中最长的连续天数
+func longestStreak(days []time.Time) int {
+	longest, streak := 0, 0
+	for i, day := range days {
+		if i > 0 && days[i-1].AddDate(0, 0, 1).Equal(day) {
+			streak++
+		} else {
+			streak = 1
+		}
+		if streak > longest {
+			longest = streak
+		}
+	}
+	return longest
+}
+
+// currentStreak 计算截至今天的连续阅读天数, 今天还没读时从昨天开始算
+func currentStreak(days map[time.Time]bool, now time.Time) int {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if !days[day] {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for days[day] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
@@ -1,15 +1,24 @@
 package recommend
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"net/http"
+	"slices"
 	"yujian-backend/pkg/db"
 	"yujian-backend/pkg/log"
 	"yujian-backend/pkg/model"
 	"yujian-backend/pkg/utils"
 )
 
+// RecordUserAction 记录用户浏览图书等一般行为
 func RecordUserAction(user *model.UserDTO, bookId int64, keywords ...string) {
+	RecordWeightedUserAction(user, bookId, model.ActionWeightView, keywords...)
+}
+
+// RecordWeightedUserAction 按权重记录用户对图书的兴趣, 加入书架等强信号使用更高的权重
+func RecordWeightedUserAction(user *model.UserDTO, bookId int64, weight int, keywords ...string) {
 	if user == nil {
 		return
 	}
@@ -23,7 +32,8 @@ func RecordUserAction(user *model.UserDTO, bookId int64, keywords ...string) {
 
 	repository := db.GetRecommendRepository()
 	rec, err := repository.QueryByUserId(user.Id)
-	if err != nil {
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.GetLogger().Errorf("failed to query recommend: %s", err.Error())
 		return
 	}
 	if rec == nil {
@@ -31,37 +41,19 @@ func RecordUserAction(user *model.UserDTO, bookId int64, keywords ...string) {
 			UserId:   user.Id,
 			Category: []string{book.Category},
 			KeyWords: []string{book.Name},
+			Weights:  map[string]int{book.Category: weight, book.Name: weight},
 		}
 		if err = repository.CreateRecRecord(rec); err != nil {
 			log.GetLogger().Errorf("failed to create recommend: %s", err.Error())
 		}
 	} else {
-		categoryExist := false
-		for _, category := range rec.Category {
-			if category == book.Category {
-				categoryExist = true
-				break
-			}
-		}
-		if !categoryExist {
-			rec.Category = append(rec.Category, book.Category)
-		}
-
+		rec.Category = addWeightedTerm(rec, rec.Category, book.Category, weight)
 		keywords = append(keywords, book.Name)
 		for _, keyword := range keywords {
 			if keyword == "" {
 				continue
 			}
-			keyWordExist := false
-			for _, key := range rec.KeyWords {
-				if key == keyword {
-					keyWordExist = true
-					break
-				}
-			}
-			if !keyWordExist {
-				rec.KeyWords = append(rec.KeyWords, keyword)
-			}
+			rec.KeyWords = addWeightedTerm(rec, rec.KeyWords, keyword, weight)
 		}
 
 		if err = repository.UpdateRecommend(rec); err != nil {
@@ -70,10 +62,35 @@ func RecordUserAction(user *model.UserDTO, bookId int64, keywords ...string) {
 	}
 }
 
+// addWeightedTerm 把分类或关键词加入列表并累加权重
+func addWeightedTerm(rec *model.UserRecommendRecordDTO, terms []string, term string, weight int) []string {
+	if slices.Contains(terms, term) {
+		rec.Weights[term] = rec.Weight(term) + weight
+		return terms
+	}
+	rec.Weights[term] = weight
+	return append(terms, term)
+}
+
+// byWeight 按兴趣权重从高到低排列, 权重相同的保持原有顺序
+func byWeight(rec *model.UserRecommendRecordDTO, terms []string) []string {
+	sorted := slices.Clone(terms)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		return rec.Weight(b) - rec.Weight(a)
+	})
+	return sorted
+}
+
 func Personal() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		obj, _ := c.Get("user")
 		user, _ := obj.(*model.UserDTO)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.RecommendResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
 
 		var req model.RecommendPersonalRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -107,12 +124,13 @@ func Personal() gin.HandlerFunc {
 		}
 
 		var retBooks []*model.BookInfoDTO
-		for _, keyword := range rec.KeyWords {
+		// 权重高的兴趣先查, 对应的图书排在前面
+		for _, keyword := range byWeight(rec, rec.KeyWords) {
 			if books, err := bookRepository.SearchBooksWithScore(keyword, req.Page, req.PageSize, "title", "content"); err == nil && len(books) > 0 {
 				retBooks = append(retBooks, books...)
 			}
 		}
-		for _, category := range rec.Category {
+		for _, category := range byWeight(rec, rec.Category) {
 			if books, err := bookRepository.SearchBooksWithScore(category, req.Page, req.PageSize, "category"); err == nil && len(books) > 0 {
 				retBooks = append(retBooks, books...)
 			}
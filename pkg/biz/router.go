@@ -2,20 +2,32 @@ package biz
 
 import (
 	"github.com/gin-gonic/gin"
+	"yujian-backend/pkg/biz/achievement"
 	"yujian-backend/pkg/biz/auth"
+	"yujian-backend/pkg/biz/author"
 	"yujian-backend/pkg/biz/book"
+	"yujian-backend/pkg/biz/category"
+	"yujian-backend/pkg/biz/club"
 	"yujian-backend/pkg/biz/file"
+	"yujian-backend/pkg/biz/notification"
 	"yujian-backend/pkg/biz/post"
+	"yujian-backend/pkg/biz/quote"
+	"yujian-backend/pkg/biz/reading"
 	"yujian-backend/pkg/biz/recommend"
+	"yujian-backend/pkg/biz/seat"
+	"yujian-backend/pkg/biz/shelf"
+	"yujian-backend/pkg/biz/suggestion"
 	"yujian-backend/pkg/biz/user"
+	"yujian-backend/pkg/model"
 )
 
 // SetupRouter 设置路由
 func SetupRouter(r *gin.Engine) {
 
-	r.Use(auth.MiddleWareAuth())
+	// 登录注册在鉴权中间件之前注册, 携带过期令牌的客户端也能重新登录
 	r.POST("/login", auth.UserLogin())       //登录
 	r.POST("/register", auth.UserRegister()) //注册
+	r.Use(auth.MiddleWareAuth())
 
 	// 用户相关的路由
 	userGroup := r.Group("/api/user")
@@ -24,36 +36,112 @@ func SetupRouter(r *gin.Engine) {
 		userGroup.PUT("/update", user.UpdateUser())                  //更新
 		userGroup.PUT("/password/change/:id", user.PasswordChange()) //修改密码
 		userGroup.DELETE("/delete/:id", user.DeleteUser())           //删除用户
+
+		userGroup.PUT("/role/:id", auth.RequireRole(model.RoleAdmin), user.SetUserRole()) // 授予角色
 	}
 
 	bookGroup := r.Group("/api/books")
 	{
-		bookGroup.GET("/search", book.SearchBooks())    // 图书搜索
-		bookGroup.GET("/:bookId", book.GetBookDetail()) // 图书详情获取
+		bookGroup.GET("/search", book.SearchBooks())                                 // 图书搜索
+		bookGroup.GET("/:bookId", book.GetBookDetail())                              // 图书详情获取
+		bookGroup.POST("", auth.RequireRole(model.RoleLibrarian), book.CreateBook()) // 图书入库
+
+		bookGroup.PUT("/:bookId/work", auth.RequireRole(model.RoleLibrarian), author.SetBookWork()) // 修正版本归并
+
+		bookGroup.GET("/:bookId/tags", category.GetBookTags())                                                    // 图书标签
+		bookGroup.POST("/:bookId/tags", category.AddBookTag())                                                    // 打标签
+		bookGroup.DELETE("/:bookId/tags/:tagId", category.RemoveBookTag())                                        // 撤销标签
+		bookGroup.PUT("/:bookId/tags/:tagId/hide", auth.RequireRole(model.RoleModerator), category.HideBookTag()) // 隐藏标签
+		bookGroup.PUT("/:bookId/tags/:tagId/show", auth.RequireRole(model.RoleModerator), category.ShowBookTag()) // 恢复标签
+
+		bookGroup.GET("/:bookId/quotes", quote.ListBookQuotes()) // 图书书摘
+	}
+
+	// 分类与标签
+	categories := r.Group("/api/categories")
+	{
+		categories.GET("", category.GetCategoryTree())
+		categories.GET("/:categoryId/books", category.GetCategoryBooks())
+		categories.POST("", auth.RequireRole(model.RoleLibrarian), category.CreateCategory())
+		categories.POST("/:categoryId/aliases", auth.RequireRole(model.RoleLibrarian), category.AddAlias())
+		categories.POST("/:categoryId/merge", auth.RequireRole(model.RoleLibrarian), category.MergeCategory())
+	}
+
+	tags := r.Group("/api/tags")
+	{
+		tags.GET("/:tagId/books", category.GetTagBooks())
+		tags.PUT("/:tagId/ban", auth.RequireRole(model.RoleModerator), category.BanTag())
+		tags.PUT("/:tagId/unban", auth.RequireRole(model.RoleModerator), category.UnbanTag())
+	}
+
+	// 作者与系列
+	authors := r.Group("/api/authors")
+	{
+		authors.GET("/:authorId", author.GetAuthorPage())
+		authors.POST("", auth.RequireRole(model.RoleLibrarian), author.CreateAuthor())
+		authors.PUT("/:authorId", auth.RequireRole(model.RoleLibrarian), author.UpdateAuthor())
+	}
+
+	seriesGroup := r.Group("/api/series")
+	{
+		seriesGroup.GET("/:seriesId", author.GetSeriesPage())
+		seriesGroup.POST("", auth.RequireRole(model.RoleLibrarian), author.CreateSeries())
+		seriesGroup.POST("/:seriesId/books", auth.RequireRole(model.RoleLibrarian), author.AddBookToSeries())
 	}
 
 	//书评相关路由
 	reviewsGroup := r.Group("/api/reviews")
 	{
-		reviewsGroup.POST("/post", book.CreatReview())  //书评发布接口
-		reviewsGroup.GET("/:bookId", book.GetReviews()) //书评获取接口
+		reviewsGroup.POST("/post", book.CreatReview())         //书评发布接口
+		reviewsGroup.GET("/:bookId", book.GetReviews())        //书评获取接口
+		reviewsGroup.PUT("/:reviewId", book.UpdateReview())    //书评修改接口
+		reviewsGroup.DELETE("/:reviewId", book.DeleteReview()) //书评删除接口
 
 		reviewsGroup.POST("/:reviewId/like", book.ClickLike())      //书评点赞接口
 		reviewsGroup.POST("/:reviewId/dislike", book.ClickUnlike()) //书评点踩接口
 	}
+	reviewReplies := r.Group("/api/review-replies")
+	{
+		reviewReplies.POST("", book.CreateReply()) //回复书评
+		reviewReplies.GET("", book.GetReplies())   //书评回复列表, ?review_id=
+	}
 
 	posts := r.Group("/api/forum")
 	{
+		posts.GET("/search", post.Search())
+		posts.POST("/search/reindex", auth.RequireRole(model.RoleAdmin), post.Reindex())
 		posts.POST("/posts/publish", post.CreatePost())
 		posts.POST("/posts", post.GetPostByTimeLine())
 		posts.GET("/posts/:postId/content", post.GetPostContentByPostId())
 		posts.GET("/posts/:postId", post.GetPostById())
+		posts.PUT("/posts/:postId", post.UpdatePost())
+		posts.DELETE("/posts/:postId", post.DeletePost())
+		posts.GET("/posts/:postId/revisions", auth.RequireRole(model.RoleModerator), post.GetPostRevisions())
+		posts.GET("/posts/:postId/comments", post.GetComments())
 		posts.POST("/posts/:postId/comments/post", post.CreateComment())
+		posts.GET("/hashtags/:name", post.GetHashtagPosts()) // 话题页
+		posts.POST("/posts/:postId/poll", post.CreatePoll())
+		posts.GET("/posts/:postId/poll", post.GetPoll()) // 实时结果
+		posts.POST("/posts/:postId/poll/vote", post.VotePoll())
+		posts.POST("/posts/:postId/poll/close", post.ClosePoll())
 
 		posts.POST("/posts/:postId/like", post.Like())
-		posts.POST("/posts/:postId/like", post.DisLike())
+		posts.POST("/posts/:postId/dislike", post.DisLike())
 		posts.POST("/posts/comments/:commentId/like", post.LikeComment())
 		posts.POST("/posts/comments/:commentId/dislike", post.DisLikeComment())
+		posts.PUT("/posts/comments/:commentId", post.EditComment())
+		posts.DELETE("/posts/comments/:commentId", post.DeleteComment())
+		posts.DELETE("/posts/comments/:commentId/purge", auth.RequireRole(model.RoleAdmin), post.PurgeComment())
+		posts.POST("/attachments", post.UploadAttachment())
+		posts.GET("/attachments/:key", post.GetAttachment())
+
+		posts.GET("/drafts", post.ListDrafts())
+		posts.POST("/drafts", post.CreateDraft())
+		posts.GET("/drafts/:draftId", post.GetDraft())
+		posts.PUT("/drafts/:draftId", post.SaveDraft())
+		posts.PATCH("/drafts/:draftId", post.AutosaveDraft()) // 自动保存
+		posts.DELETE("/drafts/:draftId", post.DeleteDraft())
+		posts.POST("/drafts/:draftId/publish", post.PublishDraft())
 	}
 
 	recom := r.Group("/api/recommendation")
@@ -63,6 +151,113 @@ func SetupRouter(r *gin.Engine) {
 		recom.GET("/hot", recommend.Hot())
 	}
 
+	// 阅览室与座位预约
+	rooms := r.Group("/api/rooms")
+	{
+		rooms.GET("", seat.ListRooms())
+		rooms.POST("", auth.RequireRole(model.RoleLibrarian), seat.CreateRoom())
+		rooms.GET("/:roomId/seats", seat.ListSeats())
+		rooms.POST("/:roomId/seats", auth.RequireRole(model.RoleLibrarian), seat.CreateSeat())
+		rooms.GET("/:roomId/availability", seat.RoomAvailability())
+	}
+
+	seatBookings := r.Group("/api/seat-bookings")
+	{
+		seatBookings.POST("", seat.BookSeat())
+		seatBookings.GET("/mine", seat.MyBookings())
+		seatBookings.POST("/:bookingId/checkin", seat.CheckIn())
+		seatBookings.POST("/:bookingId/cancel", seat.CancelBooking())
+	}
+
+	// 荐购
+	suggestions := r.Group("/api/suggestions")
+	{
+		suggestions.POST("", suggestion.CreateSuggestion())
+		suggestions.GET("", suggestion.ListSuggestions())
+		suggestions.GET("/:suggestionId", suggestion.GetSuggestion())
+		suggestions.POST("/:suggestionId/vote", suggestion.Vote())
+		suggestions.DELETE("/:suggestionId/vote", suggestion.Unvote())
+		suggestions.PUT("/:suggestionId/review", auth.RequireRole(model.RoleLibrarian), suggestion.ReviewSuggestion())
+	}
+
+	// 书架
+	shelves := r.Group("/api/shelves")
+	{
+		shelves.GET("", shelf.ListMyShelves())
+		shelves.GET("/user/:userId", shelf.ListUserShelves())
+		shelves.POST("", shelf.CreateShelf())
+		shelves.PUT("/:shelfId", shelf.UpdateShelf())
+		shelves.DELETE("/:shelfId", shelf.DeleteShelf())
+		shelves.GET("/:shelfId/books", shelf.ListShelfBooks())
+		shelves.POST("/:shelfId/books", shelf.AddBook())
+		shelves.POST("/:shelfId/books/:bookId/move", shelf.MoveBook())
+		shelves.DELETE("/:shelfId/books/:bookId", shelf.RemoveBook())
+		shelves.PUT("/:shelfId/order", shelf.Reorder())
+	}
+
+	// 书摘
+	quotes := r.Group("/api/quotes")
+	{
+		quotes.POST("", quote.CreateQuote())
+		quotes.GET("/search", quote.SearchQuotes())
+		quotes.GET("/bookmarks", quote.ListBookmarks())
+		quotes.GET("/:quoteId", quote.GetQuote())
+		quotes.DELETE("/:quoteId", quote.DeleteQuote())
+		quotes.POST("/:quoteId/like", quote.Like())
+		quotes.DELETE("/:quoteId/like", quote.Unlike())
+		quotes.POST("/:quoteId/bookmark", quote.Bookmark())
+		quotes.DELETE("/:quoteId/bookmark", quote.Unbookmark())
+	}
+
+	// 阅读进度与统计
+	readingGroup := r.Group("/api/reading")
+	{
+		readingGroup.POST("/progress", reading.LogProgress())
+		readingGroup.GET("/progress/:bookId", reading.GetBookProgress())
+		readingGroup.GET("/stats", reading.GetStats())
+		readingGroup.GET("/year-in-review", reading.GetYearInReview())
+	}
+
+	// 阅读挑战与徽章
+	challenges := r.Group("/api/challenges")
+	{
+		challenges.GET("/:year", achievement.GetChallenge())
+		challenges.PUT("/:year", achievement.SetChallenge())
+	}
+	badges := r.Group("/api/badges")
+	{
+		badges.GET("", achievement.ListBadges())
+		badges.POST("", auth.RequireRole(model.RoleAdmin), achievement.CreateBadge())
+		badges.GET("/user/:userId", achievement.GetUserBadges())
+	}
+
+	// 读书会
+	clubs := r.Group("/api/clubs")
+	{
+		clubs.POST("", club.CreateClub())
+		clubs.GET("", club.ListClubs())
+		clubs.GET("/:clubId", club.GetClub())
+		clubs.PUT("/:clubId", club.UpdateClub())
+		clubs.POST("/:clubId/join", club.JoinClub())
+		clubs.POST("/:clubId/leave", club.LeaveClub())
+		clubs.GET("/:clubId/members", club.ListMembers())
+		clubs.POST("/:clubId/members/:userId/approve", club.ApproveMember())
+		clubs.POST("/:clubId/members/:userId/reject", club.RejectMember())
+		clubs.PUT("/:clubId/members/:userId/role", club.SetMemberRole())
+		clubs.POST("/:clubId/schedule", club.CreateSchedule())
+		clubs.DELETE("/:clubId/schedule/:scheduleId", club.DeleteSchedule())
+		clubs.POST("/:clubId/events", club.CreateEvent())
+		clubs.GET("/:clubId/events", club.ListEvents())
+	}
+
+	// 站内通知
+	notifications := r.Group("/api/notifications")
+	{
+		notifications.GET("", notification.List())
+		notifications.POST("/read", notification.MarkRead())
+		notifications.POST("/:notificationId/read", notification.MarkRead())
+	}
+
 	image := r.Group("/image")
 	{
 		image.GET("/:imageId", file.FetchFile())
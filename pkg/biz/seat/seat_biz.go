@@ -0,0 +1,457 @@
+package seat
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/config"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+	"yujian-backend/pkg/utils"
+)
+
+const (
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+)
+
+// ListRooms 获取阅览室列表
+func ListRooms() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rooms, err := db.GetSeatRepository().ListRooms()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ReadingRoomListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list rooms"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ReadingRoomListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Rooms:    rooms,
+		})
+	}
+}
+
+// CreateRoom 创建阅览室(管理员)
+func CreateRoom() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req model.CreateReadingRoomRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"})
+			return
+		}
+		openTime, errOpen := time.Parse(clockLayout, req.OpenTime)
+		closeTime, errClose := time.Parse(clockLayout, req.CloseTime)
+		if req.Name == "" || errOpen != nil || errClose != nil || !closeTime.After(openTime) {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid room"), Code: http.StatusBadRequest, ErrMsg: "name is required and open/close time must be HH:MM"})
+			return
+		}
+
+		room := &model.ReadingRoomDTO{
+			Name:      req.Name,
+			Location:  req.Location,
+			OpenTime:  req.OpenTime,
+			CloseTime: req.CloseTime,
+			Intro:     req.Intro,
+		}
+		id, err := db.GetSeatRepository().CreateRoom(room)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create room"})
+			return
+		}
+		room.Id = id
+		c.JSON(http.StatusOK, model.ReadingRoomListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Rooms:    []*model.ReadingRoomDTO{room},
+		})
+	}
+}
+
+// ListSeats 获取阅览室下的座位
+func ListSeats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomId, err := strconv.ParseInt(c.Param("roomId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.SeatListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid room ID"},
+			})
+			return
+		}
+		seats, err := db.GetSeatRepository().ListSeatsByRoomId(roomId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.SeatListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list seats"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.SeatListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Seats:    seats,
+		})
+	}
+}
+
+// CreateSeat 在阅览室下创建座位(管理员),返回用于张贴的签到码
+func CreateSeat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomId, err := strconv.ParseInt(c.Param("roomId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid room ID"})
+			return
+		}
+		var req model.CreateSeatRequest
+		if err = c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"})
+			return
+		}
+
+		repository := db.GetSeatRepository()
+		if _, err = repository.GetRoomById(roomId); err != nil {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "room not found"})
+			return
+		}
+		seat := &model.SeatDO{
+			RoomId:      roomId,
+			Code:        req.Code,
+			HasPower:    req.HasPower,
+			CheckInCode: utils.GenerateDigitCode(6),
+		}
+		if _, err = repository.CreateSeat(seat); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create seat"})
+			return
+		}
+		c.JSON(http.StatusOK, model.CreateSeatResponse{
+			BaseResp:    model.BaseResp{Code: http.StatusOK},
+			Seat:        seat.Transfer(),
+			CheckInCode: seat.CheckInCode,
+		})
+	}
+}
+
+// RoomAvailability 获取阅览室某天每个座位的占用和空闲时间段
+func RoomAvailability() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomId, err := strconv.ParseInt(c.Param("roomId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.RoomAvailabilityResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid room ID"},
+			})
+			return
+		}
+		date := c.DefaultQuery("date", time.Now().Format(dateLayout))
+		day, err := time.ParseInLocation(dateLayout, date, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.RoomAvailabilityResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "date must be YYYY-MM-DD"},
+			})
+			return
+		}
+
+		repository := db.GetSeatRepository()
+		room, err := repository.GetRoomById(roomId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.RoomAvailabilityResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "room not found"},
+			})
+			return
+		}
+		seats, err := repository.ListSeatsByRoomId(roomId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.RoomAvailabilityResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list seats"},
+			})
+			return
+		}
+
+		open, closing := roomHours(room, day)
+		seatIds := make([]int64, len(seats))
+		for i, seat := range seats {
+			seatIds[i] = seat.Id
+		}
+		bookings, err := repository.ListActiveSeatBookings(seatIds, open, closing)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.RoomAvailabilityResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list bookings"},
+			})
+			return
+		}
+		bookingsBySeat := make(map[int64][]*model.SeatBookingDO)
+		for _, booking := range bookings {
+			bookingsBySeat[booking.SeatId] = append(bookingsBySeat[booking.SeatId], booking)
+		}
+
+		availability := make([]*model.SeatAvailability, len(seats))
+		for i, seat := range seats {
+			availability[i] = buildAvailability(seat, bookingsBySeat[seat.Id], open, closing)
+		}
+		c.JSON(http.StatusOK, model.RoomAvailabilityResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Room:     room,
+			Date:     date,
+			Seats:    availability,
+		})
+	}
+}
+
+// buildAvailability 根据已按开始时间排序的预约计算座位的占用和空闲时间段
+func buildAvailability(seat *model.SeatDTO, bookings []*model.SeatBookingDO, open, closing time.Time) *model.SeatAvailability {
+	result := &model.SeatAvailability{Seat: seat, Occupied: []model.TimeSlot{}, Free: []model.TimeSlot{}}
+	cursor := open
+	for _, booking := range bookings {
+		start, end := booking.StartTime, booking.EndTime
+		if start.Before(open) {
+			start = open
+		}
+		if end.After(closing) {
+			end = closing
+		}
+		result.Occupied = append(result.Occupied, model.TimeSlot{StartTime: start, EndTime: end})
+		if start.After(cursor) {
+			result.Free = append(result.Free, model.TimeSlot{StartTime: cursor, EndTime: start})
+		}
+		if end.After(cursor) {
+			cursor = end
+		}
+	}
+	if closing.After(cursor) {
+		result.Free = append(result.Free, model.TimeSlot{StartTime: cursor, EndTime: closing})
+	}
+	return result
+}
+
+// roomHours 计算阅览室在某天的开放和关闭时间
+func roomHours(room *model.ReadingRoomDTO, day time.Time) (time.Time, time.Time) {
+	year, month, date := day.Date()
+	at := func(clock string) time.Time {
+		t, _ := time.Parse(clockLayout, clock)
+		return time.Date(year, month, date, t.Hour(), t.Minute(), 0, 0, day.Location())
+	}
+	return at(room.OpenTime), at(room.CloseTime)
+}
+
+// BookSeat 预约座位
+func BookSeat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		var req model.BookSeatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.SeatBookingResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"},
+			})
+			return
+		}
+
+		repository := db.GetSeatRepository()
+		seat, err := repository.GetSeatById(req.SeatId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.SeatBookingResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "seat not found"},
+			})
+			return
+		}
+		room, err := repository.GetRoomById(seat.RoomId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.SeatBookingResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "room not found"},
+			})
+			return
+		}
+
+		// 时间段校验: 不早于当前时间, 不跨天, 在开放时间内
+		start, end := req.StartTime.Local(), req.EndTime.Local()
+		open, closing := roomHours(room, start)
+		if !end.After(start) || start.Before(time.Now().Add(-5*time.Minute)) ||
+			start.Before(open) || end.After(closing) {
+			c.JSON(http.StatusBadRequest, model.SeatBookingResponse{
+				BaseResp: model.BaseResp{Error: errors.New("invalid time slot"), Code: http.StatusBadRequest, ErrMsg: "time slot must be in the future and within opening hours"},
+			})
+			return
+		}
+
+		seatConfig := config.Config.Seat
+		dayStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		booking := &model.SeatBookingDO{
+			SeatId:    seat.Id,
+			UserId:    user.Id,
+			StartTime: start,
+			EndTime:   end,
+			Status:    model.SeatBookingBooked,
+			CreatedAt: time.Now(),
+		}
+		err = repository.CreateSeatBooking(booking, dayStart, dayStart.AddDate(0, 0, 1),
+			seatConfig.DailyMaxBookings, time.Duration(seatConfig.DailyMaxHours)*time.Hour)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, db.ErrSeatConflict) || errors.Is(err, db.ErrUserBookingOverlap) || errors.Is(err, db.ErrDailyLimitExceeded) {
+				code = http.StatusConflict
+			}
+			c.JSON(code, model.SeatBookingResponse{
+				BaseResp: model.BaseResp{Error: err, Code: model.ErrorCode(code), ErrMsg: err.Error()},
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.SeatBookingResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Booking:  booking.Transfer(),
+		})
+	}
+}
+
+// MyBookings 获取当前用户的预约
+func MyBookings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 {
+			pageSize = 10
+		}
+
+		bookings, err := db.GetSeatRepository().ListSeatBookingsByUserId(user.Id, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.SeatBookingListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list bookings"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.SeatBookingListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Bookings: bookings,
+		})
+	}
+}
+
+// CheckIn 在签到窗口内凭座位签到码签到
+func CheckIn() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		booking, ok := getOwnBooking(c)
+		if !ok {
+			return
+		}
+		var req model.SeatCheckInRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"})
+			return
+		}
+
+		repository := db.GetSeatRepository()
+		seat, err := repository.GetSeatById(booking.SeatId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "seat not found"})
+			return
+		}
+		if req.Code != seat.CheckInCode {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("wrong check-in code"), Code: http.StatusBadRequest, ErrMsg: "wrong check-in code"})
+			return
+		}
+
+		seatConfig := config.Config.Seat
+		now := time.Now()
+		windowStart := booking.StartTime.Add(-time.Duration(seatConfig.CheckInBeforeMinutes) * time.Minute)
+		windowEnd := booking.StartTime.Add(time.Duration(seatConfig.CheckInAfterMinutes) * time.Minute)
+		if now.Before(windowStart) || now.After(windowEnd) {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("not in check-in window"), Code: http.StatusBadRequest, ErrMsg: "not in check-in window"})
+			return
+		}
+
+		ok, err = repository.TransitSeatBookingStatus(booking.Id, model.SeatBookingBooked, model.SeatBookingCheckedIn,
+			map[string]interface{}{"checked_in_at": now})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to check in"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusConflict, model.BaseResp{Error: errors.New("booking is not pending"), Code: http.StatusConflict, ErrMsg: "booking is not pending"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// CancelBooking 取消尚未签到的预约
+func CancelBooking() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		booking, ok := getOwnBooking(c)
+		if !ok {
+			return
+		}
+		ok, err := db.GetSeatRepository().TransitSeatBookingStatus(booking.Id, model.SeatBookingBooked, model.SeatBookingCancelled, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to cancel booking"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusConflict, model.BaseResp{Error: errors.New("booking is not pending"), Code: http.StatusConflict, ErrMsg: "booking is not pending"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// getOwnBooking 解析路径中的预约ID并校验归属,失败时已写入响应
+func getOwnBooking(c *gin.Context) (*model.SeatBookingDO, bool) {
+	obj, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return nil, false
+	}
+	user, _ := obj.(*model.UserDTO)
+
+	bookingId, err := strconv.ParseInt(c.Param("bookingId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid booking ID"})
+		return nil, false
+	}
+	booking, err := db.GetSeatRepository().GetSeatBookingById(bookingId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "booking not found"})
+		return nil, false
+	}
+	if booking.UserId != user.Id {
+		c.JSON(http.StatusForbidden, model.BaseResp{Error: errors.New("not your booking"), Code: http.StatusForbidden, ErrMsg: "not your booking"})
+		return nil, false
+	}
+	return booking, true
+}
+
+// StartNoShowReleaser 启动后台任务,定期释放超过签到窗口仍未签到的预约
+func StartNoShowReleaser() {
+	seatConfig := config.Config.Seat
+	interval := time.Duration(seatConfig.ReleaseIntervalSec) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			deadline := time.Now().Add(-time.Duration(seatConfig.CheckInAfterMinutes) * time.Minute)
+			if n, err := db.GetSeatRepository().ReleaseNoShowBookings(deadline); err != nil {
+				log.GetLogger().Errorf("failed to release no-show bookings: %v", err)
+			} else if n > 0 {
+				log.GetLogger().Infof("released %d no-show seat bookings", n)
+			}
+		}
+	}()
+}
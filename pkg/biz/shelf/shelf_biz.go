@@ -0,0 +1,343 @@
+package shelf
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"yujian-backend/pkg/biz/achievement"
+	"yujian-backend/pkg/biz/recommend"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+const maxShelfNameLength = 50
+
+// ListMyShelves 获取自己的全部书架, 首次访问时创建自带的状态书架
+func ListMyShelves() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.ShelfListResponse{
+				BaseResp: model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")},
+			})
+			return
+		}
+		repository := db.GetShelfRepository()
+		if err := repository.EnsureBuiltinShelves(user.Id); err != nil {
+			c.JSON(http.StatusInternalServerError, model.ShelfListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to init shelves"},
+			})
+			return
+		}
+		shelves, err := repository.ListShelves(user.Id, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ShelfListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list shelves"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ShelfListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Shelves:  shelves,
+		})
+	}
+}
+
+// ListUserShelves 获取其他用户的公开书架
+func ListUserShelves() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ShelfListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid user ID"},
+			})
+			return
+		}
+		user := currentUser(c)
+		shelves, err := db.GetShelfRepository().ListShelves(userId, user == nil || user.Id != userId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ShelfListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list shelves"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.ShelfListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Shelves:  shelves,
+		})
+	}
+}
+
+// CreateShelf 创建自定义书单
+func CreateShelf() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := currentUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		var req model.CreateShelfRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"})
+			return
+		}
+		name, ok := normalizeName(req.Name)
+		if !ok {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid name"), Code: http.StatusBadRequest, ErrMsg: "name must be 1-50 characters"})
+			return
+		}
+
+		shelf := &model.ShelfDO{UserId: user.Id, Name: name, Kind: model.ShelfCustom, IsPublic: req.IsPublic, CreatedAt: time.Now()}
+		if _, err := db.GetShelfRepository().CreateShelf(shelf); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create shelf"})
+			return
+		}
+		c.JSON(http.StatusOK, model.ShelfListResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Shelves:  []*model.ShelfDTO{shelf.Transfer()},
+		})
+	}
+}
+
+// UpdateShelf 修改书架名称和可见性, 自带书架也可以改名或公开
+func UpdateShelf() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelf, ok := ownedShelf(c)
+		if !ok {
+			return
+		}
+		var req model.CreateShelfRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"})
+			return
+		}
+		name, valid := normalizeName(req.Name)
+		if !valid {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("invalid name"), Code: http.StatusBadRequest, ErrMsg: "name must be 1-50 characters"})
+			return
+		}
+		if err := db.GetShelfRepository().UpdateShelf(shelf.Id, name, req.IsPublic); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to update shelf"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// DeleteShelf 删除自定义书单, 自带书架不能删除
+func DeleteShelf() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelf, ok := ownedShelf(c)
+		if !ok {
+			return
+		}
+		if model.IsBuiltinShelf(shelf.Kind) {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: errors.New("builtin shelf"), Code: http.StatusBadRequest, ErrMsg: "builtin shelf cannot be deleted"})
+			return
+		}
+		if err := db.GetShelfRepository().DeleteShelf(shelf.Id); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to delete shelf"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// ListShelfBooks 分页获取书架中的图书, 非公开书架仅本人可见
+func ListShelfBooks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelfId, err := strconv.ParseInt(c.Param("shelfId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ShelfBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid shelf ID"},
+			})
+			return
+		}
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if page <= 0 {
+			page = 1
+		}
+		if pageSize <= 0 {
+			pageSize = 20
+		}
+
+		repository := db.GetShelfRepository()
+		shelf, err := repository.GetShelfById(shelfId)
+		user := currentUser(c)
+		if err != nil || (!shelf.IsPublic && (user == nil || user.Id != shelf.UserId)) {
+			c.JSON(http.StatusNotFound, model.ShelfBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "shelf not found"},
+			})
+			return
+		}
+		books, total, err := repository.ListShelfBooks(shelf.Id, page, pageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ShelfBooksResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list books"},
+			})
+			return
+		}
+		shelfDTO := shelf.Transfer()
+		shelfDTO.BookCount = total
+		c.JSON(http.StatusOK, model.ShelfBooksResponse{
+			BaseResp: model.BaseResp{Code: http.StatusOK},
+			Shelf:    shelfDTO,
+			Books:    books,
+			Total:    total,
+		})
+	}
+}
+
+// AddBook 将图书加入书架, 加入状态书架时会自动从其他状态书架移出
+func AddBook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelf, ok := ownedShelf(c)
+		if !ok {
+			return
+		}
+		var req model.AddShelfBookRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.BookId <= 0 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "book_id is required"})
+			return
+		}
+		book, err := db.GetBookRepository().GetBookById(req.BookId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "book not found"})
+			return
+		}
+		if err = db.GetShelfRepository().AddBook(shelf, book.Id); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to add book"})
+			return
+		}
+		// 加入书架是比浏览更强的兴趣信号, 按更高的权重记录
+		go recommend.RecordWeightedUserAction(currentUser(c), book.Id, model.ActionWeightShelf, book.Author)
+		achievement.Publish(shelf.UserId, model.EventShelf)
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// MoveBook 将图书移动到自己的另一个书架
+func MoveBook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelf, ok := ownedShelf(c)
+		if !ok {
+			return
+		}
+		bookId, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid book ID"})
+			return
+		}
+		var req model.MoveShelfBookRequest
+		if err = c.ShouldBindJSON(&req); err != nil || req.TargetShelfId <= 0 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "target_shelf_id is required"})
+			return
+		}
+		if req.TargetShelfId == shelf.Id {
+			c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+			return
+		}
+
+		repository := db.GetShelfRepository()
+		target, err := repository.GetShelfById(req.TargetShelfId)
+		if err != nil || target.UserId != shelf.UserId {
+			c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "target shelf not found"})
+			return
+		}
+		if err = repository.MoveBook(shelf, target, bookId); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				code = http.StatusNotFound
+			}
+			c.JSON(code, model.BaseResp{Error: err, Code: model.ErrorCode(code), ErrMsg: "failed to move book"})
+			return
+		}
+		if book, err := db.GetBookRepository().GetBookById(bookId); err == nil {
+			go recommend.RecordWeightedUserAction(currentUser(c), book.Id, model.ActionWeightShelf, book.Author)
+		}
+		achievement.Publish(shelf.UserId, model.EventShelf)
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// RemoveBook 将图书移出书架
+func RemoveBook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelf, ok := ownedShelf(c)
+		if !ok {
+			return
+		}
+		bookId, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid book ID"})
+			return
+		}
+		if err = db.GetShelfRepository().RemoveBook(shelf.Id, bookId); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to remove book"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+// Reorder 调整书架内图书顺序
+func Reorder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shelf, ok := ownedShelf(c)
+		if !ok {
+			return
+		}
+		var req model.ReorderShelfRequest
+		if err := c.ShouldBindJSON(&req); err != nil || len(req.BookIds) == 0 {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "book_ids is required"})
+			return
+		}
+		if err := db.GetShelfRepository().Reorder(shelf.Id, req.BookIds); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to reorder shelf"})
+			return
+		}
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
+
+func currentUser(c *gin.Context) *model.UserDTO {
+	obj, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+	user, _ := obj.(*model.UserDTO)
+	return user
+}
+
+// ownedShelf 读取路径中的书架并校验归属, 失败时已写回响应
+func ownedShelf(c *gin.Context) (*model.ShelfDO, bool) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return nil, false
+	}
+	shelfId, err := strconv.ParseInt(c.Param("shelfId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid shelf ID"})
+		return nil, false
+	}
+	shelf, err := db.GetShelfRepository().GetShelfById(shelfId)
+	if err != nil || shelf.UserId != user.Id {
+		c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "shelf not found"})
+		return nil, false
+	}
+	return shelf, true
+}
+
+func normalizeName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	return name, name != "" && utf8.RuneCountInString(name) <= maxShelfNameLength
+}
@@ -0,0 +1,330 @@
+package suggestion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"yujian-backend/pkg/biz/notification"
+	"yujian-backend/pkg/db"
+	"yujian-backend/pkg/model"
+)
+
+// CreateSuggestion 发起荐购, 同一ISBN已有进行中的荐购时自动附议该荐购
+func CreateSuggestion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+			return
+		}
+		user, _ := obj.(*model.UserDTO)
+
+		var req model.CreateSuggestionRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Title == "" {
+			c.JSON(http.StatusBadRequest, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "title is required"},
+			})
+			return
+		}
+
+		repository := db.GetSuggestionRepository()
+		if req.ISBN != "" {
+			existing, err := repository.FindOpenSuggestionByISBN(req.ISBN)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.SuggestionResponse{
+					BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to query suggestion"},
+				})
+				return
+			}
+			if existing != nil {
+				if existing.RequesterId != user.Id {
+					if added, err := repository.Vote(existing.Id, user.Id); err == nil && added {
+						existing.VoteCount++
+					}
+				}
+				dto := existing.Transfer()
+				dto.Voted = existing.RequesterId != user.Id
+				c.JSON(http.StatusOK, model.SuggestionResponse{
+					BaseResp:   model.BaseResp{Code: http.StatusOK, ErrMsg: "suggestion already exists, voted"},
+					Suggestion: dto,
+				})
+				return
+			}
+		}
+
+		now := time.Now()
+		suggestion := &model.PurchaseSuggestionDO{
+			RequesterId: user.Id,
+			Title:       req.Title,
+			Author:      req.Author,
+			ISBN:        req.ISBN,
+			Reason:      req.Reason,
+			Status:      model.SuggestionPending,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if _, err := repository.CreateSuggestion(suggestion); err != nil {
+			c.JSON(http.StatusInternalServerError, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to create suggestion"},
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.SuggestionResponse{
+			BaseResp:   model.BaseResp{Code: http.StatusOK},
+			Suggestion: suggestion.Transfer(),
+		})
+	}
+}
+
+// ListSuggestions 荐购列表
+func ListSuggestions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req model.SuggestionListRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.SuggestionListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid query"},
+			})
+			return
+		}
+		if req.Page <= 0 {
+			req.Page = 1
+		}
+		if req.PageSize <= 0 {
+			req.PageSize = 10
+		}
+
+		repository := db.GetSuggestionRepository()
+		suggestions, total, err := repository.ListSuggestions(req.Status, req.Sort, req.Page, req.PageSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.SuggestionListResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to list suggestions"},
+			})
+			return
+		}
+
+		ids := make([]int64, len(suggestions))
+		for i, suggestion := range suggestions {
+			ids[i] = suggestion.Id
+		}
+		voted := map[int64]bool{}
+		if obj, exists := c.Get("user"); exists {
+			user, _ := obj.(*model.UserDTO)
+			if voted, err = repository.GetVotedSuggestionIds(user.Id, ids); err != nil {
+				voted = map[int64]bool{}
+			}
+		}
+
+		dtos := make([]*model.PurchaseSuggestionDTO, len(suggestions))
+		for i, suggestion := range suggestions {
+			dtos[i] = suggestion.Transfer()
+			dtos[i].Voted = voted[suggestion.Id]
+		}
+		c.JSON(http.StatusOK, model.SuggestionListResponse{
+			BaseResp:    model.BaseResp{Code: http.StatusOK},
+			Suggestions: dtos,
+			Total:       total,
+		})
+	}
+}
+
+// GetSuggestion 荐购详情
+func GetSuggestion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("suggestionId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid suggestion ID"},
+			})
+			return
+		}
+		repository := db.GetSuggestionRepository()
+		suggestion, err := repository.GetSuggestionById(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "suggestion not found"},
+			})
+			return
+		}
+		dto := suggestion.Transfer()
+		if obj, exists := c.Get("user"); exists {
+			user, _ := obj.(*model.UserDTO)
+			if voted, err := repository.GetVotedSuggestionIds(user.Id, []int64{id}); err == nil {
+				dto.Voted = voted[id]
+			}
+		}
+		c.JSON(http.StatusOK, model.SuggestionResponse{
+			BaseResp:   model.BaseResp{Code: http.StatusOK},
+			Suggestion: dto,
+		})
+	}
+}
+
+// Vote 附议荐购
+func Vote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		updateVote(c, true)
+	}
+}
+
+// Unvote 取消附议
+func Unvote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		updateVote(c, false)
+	}
+}
+
+func updateVote(c *gin.Context, vote bool) {
+	obj, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.BaseResp{Code: http.StatusUnauthorized, ErrMsg: "unauthorized", Error: errors.New("unauthorized")})
+		return
+	}
+	user, _ := obj.(*model.UserDTO)
+
+	id, err := strconv.ParseInt(c.Param("suggestionId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.SuggestionResponse{
+			BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid suggestion ID"},
+		})
+		return
+	}
+	repository := db.GetSuggestionRepository()
+	suggestion, err := repository.GetSuggestionById(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.SuggestionResponse{
+			BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "suggestion not found"},
+		})
+		return
+	}
+	if suggestion.RequesterId == user.Id {
+		c.JSON(http.StatusBadRequest, model.SuggestionResponse{
+			BaseResp: model.BaseResp{Error: errors.New("cannot vote own suggestion"), Code: http.StatusBadRequest, ErrMsg: "cannot vote own suggestion"},
+		})
+		return
+	}
+
+	if vote {
+		_, err = repository.Vote(id, user.Id)
+	} else {
+		_, err = repository.Unvote(id, user.Id)
+	}
+	if errors.Is(err, db.ErrSuggestionClosed) {
+		c.JSON(http.StatusConflict, model.SuggestionResponse{
+			BaseResp: model.BaseResp{Error: err, Code: http.StatusConflict, ErrMsg: "only pending suggestions can be voted"},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.SuggestionResponse{
+			BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to update vote"},
+		})
+		return
+	}
+
+	if suggestion, err = repository.GetSuggestionById(id); err != nil {
+		c.JSON(http.StatusInternalServerError, model.SuggestionResponse{
+			BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to query suggestion"},
+		})
+		return
+	}
+	dto := suggestion.Transfer()
+	dto.Voted = vote
+	c.JSON(http.StatusOK, model.SuggestionResponse{
+		BaseResp:   model.BaseResp{Code: http.StatusOK},
+		Suggestion: dto,
+	})
+}
+
+// ReviewSuggestion 馆员审核荐购, 审核结果通知发起人
+func ReviewSuggestion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, _ := c.Get("user")
+		reviewer, _ := obj.(*model.UserDTO)
+
+		id, err := strconv.ParseInt(c.Param("suggestionId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid suggestion ID"},
+			})
+			return
+		}
+		var req model.ReviewSuggestionRequest
+		if err = c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "invalid request body"},
+			})
+			return
+		}
+
+		repository := db.GetSuggestionRepository()
+		suggestion, err := repository.GetSuggestionById(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "suggestion not found"},
+			})
+			return
+		}
+		if !canTransit(suggestion.Status, req.Status) {
+			c.JSON(http.StatusBadRequest, model.SuggestionResponse{
+				BaseResp: model.BaseResp{
+					Error:  errors.New("invalid status transition"),
+					Code:   http.StatusBadRequest,
+					ErrMsg: fmt.Sprintf("cannot change status from %s to %s", suggestion.Status, req.Status),
+				},
+			})
+			return
+		}
+
+		ok, err := repository.ReviewSuggestion(id, suggestion.Status, req.Status, reviewer.Id, req.Comment)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "failed to review suggestion"},
+			})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusConflict, model.SuggestionResponse{
+				BaseResp: model.BaseResp{Error: errors.New("suggestion status changed"), Code: http.StatusConflict, ErrMsg: "suggestion status changed, please retry"},
+			})
+			return
+		}
+
+		suggestion.Status = req.Status
+		suggestion.ReviewerId = reviewer.Id
+		suggestion.ReviewComment = req.Comment
+		go notification.Send(suggestion.RequesterId, model.NotifySuggestionReviewed,
+			fmt.Sprintf("你的荐购《%s》状态更新为 %s", suggestion.Title, req.Status), req.Comment, suggestion.Id)
+
+		c.JSON(http.StatusOK, model.SuggestionResponse{
+			BaseResp:   model.BaseResp{Code: http.StatusOK},
+			Suggestion: suggestion.Transfer(),
+		})
+	}
+}
+
+func canTransit(from, to string) bool {
+	for _, status := range model.SuggestionTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// FulfillByBook 图书入库后调用, 将匹配的荐购标记为已到馆并通知发起人
+func FulfillByBook(book *model.BookInfoDTO) error {
+	suggestions, err := db.GetSuggestionRepository().FulfillSuggestions(book)
+	if err != nil {
+		return err
+	}
+	for _, suggestion := range suggestions {
+		notification.Send(suggestion.RequesterId, model.NotifySuggestionFulfilled,
+			fmt.Sprintf("你荐购的《%s》已到馆", suggestion.Title), "可以在图书详情页查看", book.Id)
+	}
+	return nil
+}
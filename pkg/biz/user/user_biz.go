@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"yujian-backend/pkg/db"
 	"yujian-backend/pkg/model"
@@ -139,3 +140,43 @@ func PasswordChange() gin.HandlerFunc {
 		})
 	}
 }
+
+// SetUserRole 管理员修改用户角色的处理函数
+func SetUserRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRepository := db.GetUserRepository()
+		userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "Invalid user ID"})
+			return
+		}
+
+		var req model.SetUserRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Error: err, Code: http.StatusBadRequest, ErrMsg: "Invalid request body"})
+			return
+		}
+		if !model.IsValidRole(req.Role) {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "Invalid role"})
+			return
+		}
+
+		// 不允许修改自己的角色,避免唯一的管理员把自己降级
+		obj, _ := c.Get("user")
+		if operator, ok := obj.(*model.UserDTO); ok && operator.Id == userId {
+			c.JSON(http.StatusBadRequest, model.BaseResp{Code: http.StatusBadRequest, ErrMsg: "Cannot change your own role"})
+			return
+		}
+
+		if err := userRepository.SetUserRole(userId, req.Role); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, model.BaseResp{Error: err, Code: http.StatusNotFound, ErrMsg: "User not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, model.BaseResp{Error: err, Code: http.StatusInternalServerError, ErrMsg: "Failed to update role"})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.BaseResp{Code: http.StatusOK})
+	}
+}
@@ -40,6 +40,52 @@ func initESConfig() {
 	esConfig.Password = viper.GetString("es.password")
 }
 
+func initMinioConfig() {
+	minioConfig := Config.Minio
+	minioConfig.Endpoint = viper.GetString("minio.endpoint")
+	minioConfig.AccessKeyID = viper.GetString("minio.accessKeyID")
+	minioConfig.SecretAccessKey = viper.GetString("minio.secretAccessKey")
+}
+
+func initSeatConfig() {
+	seatConfig := Config.Seat
+	seatConfig.CheckInBeforeMinutes = viper.GetInt("seat.checkInBeforeMinutes")
+	seatConfig.CheckInAfterMinutes = viper.GetInt("seat.checkInAfterMinutes")
+	seatConfig.DailyMaxBookings = viper.GetInt("seat.dailyMaxBookings")
+	seatConfig.DailyMaxHours = viper.GetInt("seat.dailyMaxHours")
+	seatConfig.ReleaseIntervalSec = viper.GetInt("seat.releaseIntervalSec")
+}
+
+func initAchievementConfig() {
+	achievementConfig := Config.Achievement
+	achievementConfig.DefaultYearlyGoal = viper.GetInt("achievement.defaultYearlyGoal")
+	achievementConfig.MaxYearlyGoal = viper.GetInt("achievement.maxYearlyGoal")
+	achievementConfig.QueueSize = viper.GetInt("achievement.queueSize")
+}
+
+func initForumConfig() {
+	forumConfig := Config.Forum
+	forumConfig.CommentEditWindowMinutes = viper.GetInt("forum.commentEditWindowMinutes")
+	forumConfig.MaxDraftsPerUser = viper.GetInt("forum.maxDraftsPerUser")
+	forumConfig.DraftRetentionDays = viper.GetInt("forum.draftRetentionDays")
+}
+
+func initAttachmentConfig() {
+	attachmentConfig := Config.Attachment
+	attachmentConfig.Bucket = viper.GetString("attachment.bucket")
+	attachmentConfig.MaxImageSizeMB = viper.GetInt("attachment.maxImageSizeMB")
+	attachmentConfig.MaxFileSizeMB = viper.GetInt("attachment.maxFileSizeMB")
+	attachmentConfig.PendingTTLHours = viper.GetInt("attachment.pendingTTLHours")
+	attachmentConfig.GCIntervalMin = viper.GetInt("attachment.gcIntervalMin")
+	attachmentConfig.SignSecret = viper.GetString("attachment.signSecret")
+	attachmentConfig.URLTTLMinutes = viper.GetInt("attachment.urlTTLMinutes")
+}
+
+func initAuthConfig() {
+	authConfig := Config.Auth
+	authConfig.Admins = viper.GetStringSlice("auth.admins")
+}
+
 func InitConfig() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -49,10 +95,16 @@ func InitConfig() {
 	}()
 
 	Config = model.AppConfig{
-		DB:     &model.DBConfig{},
-		ES:     &model.ESConfig{},
-		Log:    &model.LogConfig{},
-		Server: &model.ServerConfig{},
+		DB:          &model.DBConfig{},
+		ES:          &model.ESConfig{},
+		Minio:       &model.MinioConfig{},
+		Log:         &model.LogConfig{},
+		Server:      &model.ServerConfig{},
+		Seat:        &model.SeatConfig{},
+		Achievement: &model.AchievementConfig{},
+		Forum:       &model.ForumConfig{},
+		Attachment:  &model.AttachmentConfig{},
+		Auth:        &model.AuthConfig{},
 	}
 
 	// 初始化 viper
@@ -72,10 +124,22 @@ func InitConfig() {
 
 	initESConfig()
 
+	initMinioConfig()
+
 	initLogConfig()
 
 	initServerConfig()
 
+	initSeatConfig()
+
+	initAchievementConfig()
+
+	initForumConfig()
+
+	initAttachmentConfig()
+
+	initAuthConfig()
+
 	for _, v := range viper.AllKeys() {
 		log.Printf("%s = %v\n", v, viper.Get(v))
 	}
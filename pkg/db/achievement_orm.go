@@ -0,0 +1,197 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var achievementRepository AchievementRepository
+
+type AchievementRepository struct {
+	DB *gorm.DB
+}
+
+func GetAchievementRepository() *AchievementRepository {
+	return &achievementRepository
+}
+
+// SeedBadges 创建缺少的内置徽章, 已存在的(包括被管理员修改过的)保持不变
+func (r *AchievementRepository) SeedBadges() error {
+	for _, badge := range model.DefaultBadges {
+		badge := badge
+		badge.Enabled = true
+		if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&badge).Error; err != nil {
+			return err
+		}
+	}
+	// 借阅模块接入前借阅徽章无法获得, 停用之前内置的借阅徽章
+	return r.DB.Model(&model.BadgeDO{}).Where("metric = ?", model.MetricLoans).Update("enabled", false).Error
+}
+
+// ListBadges 获取全部启用的徽章
+func (r *AchievementRepository) ListBadges() ([]*model.BadgeDO, error) {
+	var badges []*model.BadgeDO
+	if err := r.DB.Where("enabled = ?", true).Order("id").Find(&badges).Error; err != nil {
+		return nil, err
+	}
+	return badges, nil
+}
+
+// ListBadgesByMetrics 获取基于给定指标的启用徽章
+func (r *AchievementRepository) ListBadgesByMetrics(metrics []string) ([]*model.BadgeDO, error) {
+	var badges []*model.BadgeDO
+	if err := r.DB.Where("enabled = ? AND metric IN ?", true, metrics).Find(&badges).Error; err != nil {
+		return nil, err
+	}
+	return badges, nil
+}
+
+// ExistsBadgeCode 徽章编码是否已被使用
+func (r *AchievementRepository) ExistsBadgeCode(code string) (bool, error) {
+	var count int64
+	if err := r.DB.Model(&model.BadgeDO{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateBadge 创建徽章
+func (r *AchievementRepository) CreateBadge(badge *model.BadgeDO) (int64, error) {
+	if err := r.DB.Create(badge).Error; err != nil {
+		return 0, err
+	}
+	return badge.Id, nil
+}
+
+// GetEarnedBadgeIds 获取用户已获得的徽章ID集合
+func (r *AchievementRepository) GetEarnedBadgeIds(userId int64) (map[int64]bool, error) {
+	var ids []int64
+	if err := r.DB.Model(&model.UserBadgeDO{}).Where("user_id = ?", userId).Pluck("badge_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	earned := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		earned[id] = true
+	}
+	return earned, nil
+}
+
+// AwardBadge 授予徽章, 返回本次是否新获得
+func (r *AchievementRepository) AwardBadge(userId, badgeId int64) (bool, error) {
+	userBadge := &model.UserBadgeDO{UserId: userId, BadgeId: badgeId, EarnedAt: time.Now()}
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(userBadge)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListUserBadges 获取用户获得的徽章, 按获得时间排序
+func (r *AchievementRepository) ListUserBadges(userId int64) ([]*model.BadgeDTO, error) {
+	var userBadges []*model.UserBadgeDO
+	if err := r.DB.Where("user_id = ?", userId).Order("earned_at, id").Find(&userBadges).Error; err != nil {
+		return nil, err
+	}
+	if len(userBadges) == 0 {
+		return []*model.BadgeDTO{}, nil
+	}
+	badgeIds := make([]int64, len(userBadges))
+	for i, userBadge := range userBadges {
+		badgeIds[i] = userBadge.BadgeId
+	}
+	var badges []*model.BadgeDO
+	if err := r.DB.Where("id IN ?", badgeIds).Find(&badges).Error; err != nil {
+		return nil, err
+	}
+	badgeMap := make(map[int64]*model.BadgeDO, len(badges))
+	for _, badge := range badges {
+		badgeMap[badge.Id] = badge
+	}
+
+	badgeDTOs := make([]*model.BadgeDTO, 0, len(userBadges))
+	for _, userBadge := range userBadges {
+		if badge, ok := badgeMap[userBadge.BadgeId]; ok {
+			badgeDTO := badge.Transfer()
+			badgeDTO.EarnedAt = &userBadge.EarnedAt
+			badgeDTOs = append(badgeDTOs, badgeDTO)
+		}
+	}
+	return badgeDTOs, nil
+}
+
+// CountMetric 统计用户某项指标的当前值
+func (r *AchievementRepository) CountMetric(userId int64, metric string) (int64, error) {
+	var count int64
+	var err error
+	switch metric {
+	case model.MetricReviews:
+		err = r.DB.Model(&model.BookCommentDO{}).Where("publisher_id = ?", userId).
+			Distinct("book_id").Count(&count).Error
+	case model.MetricPosts:
+		err = r.DB.Model(&model.PostDO{}).Where("author_id = ?", userId).Count(&count).Error
+	case model.MetricBooksRead:
+		err = r.readShelfItems(userId).Distinct("shelf_item.book_id").Count(&count).Error
+	case model.MetricCategories:
+		err = r.readShelfItems(userId).Joins("JOIN book_info ON book_info.id = shelf_item.book_id").
+			Where("book_info.category_id > 0").Distinct("book_info.category_id").Count(&count).Error
+	default:
+		return 0, fmt.Errorf("unknown metric: %s", metric)
+	}
+	return count, err
+}
+
+func (r *AchievementRepository) readShelfItems(userId int64) *gorm.DB {
+	return r.DB.Model(&model.ShelfItemDO{}).
+		Joins("JOIN shelf ON shelf.id = shelf_item.shelf_id").
+		Where("shelf_item.user_id = ? AND shelf.kind = ?", userId, model.ShelfRead)
+}
+
+// CountBooksReadInYear 统计用户某一年放入"读过"书架的书数
+func (r *AchievementRepository) CountBooksReadInYear(userId int64, year int) (int64, error) {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	var count int64
+	err := r.readShelfItems(userId).
+		Where("shelf_item.added_at >= ? AND shelf_item.added_at < ?", start, start.AddDate(1, 0, 0)).
+		Count(&count).Error
+	return count, err
+}
+
+// GetChallenge 获取用户某一年的阅读挑战, 未设置时返回nil
+func (r *AchievementRepository) GetChallenge(userId int64, year int) (*model.ReadingChallengeDO, error) {
+	var challenge model.ReadingChallengeDO
+	err := r.DB.Where("user_id = ? AND year = ?", userId, year).First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// SetChallengeGoal 设置年度目标, 修改目标后重新判断是否完成
+func (r *AchievementRepository) SetChallengeGoal(userId int64, year, goal int) error {
+	challenge := &model.ReadingChallengeDO{UserId: userId, Year: year, Goal: goal}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "year"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"goal":         goal,
+			"completed_at": nil,
+		}),
+	}).Create(challenge).Error
+}
+
+// MarkChallengeCompleted 标记挑战完成, 仅在尚未完成时更新, 返回本次是否标记
+func (r *AchievementRepository) MarkChallengeCompleted(id int64) (bool, error) {
+	result := r.DB.Model(&model.ReadingChallengeDO{}).Where("id = ? AND completed_at IS NULL", id).
+		Update("completed_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+)
+
+var attachmentRepository AttachmentRepository
+
+type AttachmentRepository struct {
+	DB *gorm.DB
+}
+
+func GetAttachmentRepository() *AttachmentRepository {
+	return &attachmentRepository
+}
+
+// CreateAttachment 记录上传的附件
+func (r *AttachmentRepository) CreateAttachment(attachment *model.AttachmentDO) error {
+	return r.DB.Create(attachment).Error
+}
+
+// GetAttachmentByKey 根据Key获取附件
+func (r *AttachmentRepository) GetAttachmentByKey(key string) (*model.AttachmentDO, error) {
+	var attachment model.AttachmentDO
+	if err := r.DB.Where("`key` = ?", key).First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// BindAttachments 将上传者本人还未被引用的附件关联到帖子或评论; 已关联的附件和别人的附件保持不变
+func (r *AttachmentRepository) BindAttachments(uploaderId int64, keys []string, postId, commentId int64) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.DB.Model(&model.AttachmentDO{}).
+		Where("`key` IN ? AND uploader_id = ? AND post_id = 0", keys, uploaderId).
+		Updates(map[string]interface{}{
+			"post_id":    postId,
+			"comment_id": commentId,
+			"bound_at":   time.Now(),
+		}).Error
+}
+
+// BindDraftAttachments 记录草稿中引用的上传者本人还未发布的附件, 避免在草稿保留期间被回收
+func (r *AttachmentRepository) BindDraftAttachments(uploaderId int64, keys []string, draftId int64) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.DB.Model(&model.AttachmentDO{}).
+		Where("`key` IN ? AND uploader_id = ? AND post_id = 0", keys, uploaderId).
+		Update("draft_id", draftId).Error
+}
+
+// ListExpiredAttachments 获取在before之前上传且一直没有被引用、也不在草稿中的附件
+func (r *AttachmentRepository) ListExpiredAttachments(before time.Time, limit int) ([]*model.AttachmentDO, error) {
+	var attachments []*model.AttachmentDO
+	err := r.DB.Where("post_id = 0 AND draft_id = 0 AND created_at < ?", before).Order("id").Limit(limit).Find(&attachments).Error
+	return attachments, err
+}
+
+// DeletePendingAttachment 删除没有被引用的附件记录; 删除前刚被引用或存入草稿的不删, 返回是否删除
+func (r *AttachmentRepository) DeletePendingAttachment(id int64) (bool, error) {
+	result := r.DB.Where("id = ? AND post_id = 0 AND draft_id = 0", id).Delete(&model.AttachmentDO{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var authorRepository AuthorRepository
+
+type AuthorRepository struct {
+	DB *gorm.DB
+}
+
+func GetAuthorRepository() *AuthorRepository {
+	return &authorRepository
+}
+
+var (
+	// 多个作者之间的分隔符
+	authorSeparator = regexp.MustCompile(`\s*[,，、;；/&]\s*`)
+	// 作者名前的国籍标注, 如 [美] (英)
+	nationalityPrefix = regexp.MustCompile(`^\s*[\[【(（][^\]】)）]{1,4}[\]】)）]\s*`)
+	// 作者名后的著作方式, 如 著 编著 译
+	contributionSuffix = regexp.MustCompile(`\s*(编著|主编|著|编|译)$`)
+)
+
+// splitAuthorNames 将图书的作者字符串拆分为规范化的作者名列表
+func splitAuthorNames(author string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range authorSeparator.Split(author, -1) {
+		name := nationalityPrefix.ReplaceAllString(part, "")
+		name = strings.TrimSpace(contributionSuffix.ReplaceAllString(name, ""))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// 作者
+
+// CreateAuthor 创建作者
+func (r *AuthorRepository) CreateAuthor(authorDTO *model.AuthorDTO) (int64, error) {
+	authorDO := authorDTO.Transfer()
+	if err := r.DB.Create(authorDO).Error; err != nil {
+		return 0, err
+	}
+	return authorDO.Id, nil
+}
+
+// UpdateAuthor 更新作者
+func (r *AuthorRepository) UpdateAuthor(authorDTO *model.AuthorDTO) error {
+	return r.DB.Save(authorDTO.Transfer()).Error
+}
+
+// GetAuthorById 根据ID获取作者
+func (r *AuthorRepository) GetAuthorById(id int64) (*model.AuthorDTO, error) {
+	var author model.AuthorDO
+	if err := r.DB.First(&author, id).Error; err != nil {
+		return nil, err
+	}
+	return author.Transfer(), nil
+}
+
+// GetAuthorsByBookId 获取图书的作者, 按署名顺序
+func (r *AuthorRepository) GetAuthorsByBookId(bookId int64) ([]*model.AuthorDTO, error) {
+	var authors []*model.AuthorDO
+	if err := r.DB.Table("author").Select("author.*").
+		Joins("JOIN book_author ON book_author.author_id = author.id").
+		Where("book_author.book_id = ?", bookId).
+		Order("book_author.position").Find(&authors).Error; err != nil {
+		return nil, err
+	}
+	authorDTOs := make([]*model.AuthorDTO, len(authors))
+	for i, author := range authors {
+		authorDTOs[i] = author.Transfer()
+	}
+	return authorDTOs, nil
+}
+
+// ListBooksByAuthorId 获取作者的全部图书
+func (r *AuthorRepository) ListBooksByAuthorId(authorId int64) ([]*model.BookInfoDTO, error) {
+	var books []*model.BookInfoDO
+	if err := r.DB.Table("book_info").Select("book_info.*").
+		Joins("JOIN book_author ON book_author.book_id = book_info.id").
+		Where("book_author.author_id = ?", authorId).
+		Order("book_info.publish_year DESC").Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return transferBooks(books), nil
+}
+
+// findOrCreateAuthor 按名字或别名查找作者, 不存在时创建
+func findOrCreateAuthor(tx *gorm.DB, name string) (int64, error) {
+	var authors []*model.AuthorDO
+	// 别名以json数组存储, 按json编码后带引号的子串匹配, 名字中的LIKE通配符需转义
+	quoted, _ := json.Marshal(name)
+	if err := tx.Where("name = ? OR aliases LIKE ?", name, "%"+escapeLike(string(quoted))+"%").Limit(1).Find(&authors).Error; err != nil {
+		return 0, err
+	}
+	if len(authors) > 0 {
+		return authors[0].Id, nil
+	}
+
+	author := &model.AuthorDO{Name: name, Aliases: "[]"}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(author).Error; err != nil {
+		return 0, err
+	}
+	if author.Id == 0 {
+		// 并发创建时被其他请求抢先插入
+		if err := tx.Where("name = ?", name).First(author).Error; err != nil {
+			return 0, err
+		}
+	}
+	return author.Id, nil
+}
+
+// 作品与版本
+
+// LinkBookEntities 根据图书的作者字符串关联作者, 并将图书归入(或新建)对应作品
+func (r *AuthorRepository) LinkBookEntities(book *model.BookInfoDTO) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var authorIds []int64
+		for i, name := range splitAuthorNames(book.Author) {
+			authorId, err := findOrCreateAuthor(tx, name)
+			if err != nil {
+				return err
+			}
+			authorIds = append(authorIds, authorId)
+			link := &model.BookAuthorDO{BookId: book.Id, AuthorId: authorId, Position: i}
+			if err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(link).Error; err != nil {
+				return err
+			}
+		}
+
+		if book.WorkId != 0 {
+			return nil
+		}
+		var firstAuthorId int64
+		if len(authorIds) > 0 {
+			firstAuthorId = authorIds[0]
+		}
+		var works []*model.WorkDO
+		if err := tx.Where("title = ? AND author_id = ?", book.Name, firstAuthorId).Limit(1).Find(&works).Error; err != nil {
+			return err
+		}
+		work := &model.WorkDO{Title: book.Name, AuthorId: firstAuthorId}
+		if len(works) > 0 {
+			work = works[0]
+		} else if err := tx.Create(work).Error; err != nil {
+			return err
+		}
+		book.WorkId = work.Id
+		return tx.Model(&model.BookInfoDO{}).Where("id = ?", book.Id).Update("work_id", work.Id).Error
+	})
+}
+
+// MigrateBookEntities 将尚未归入作品的历史图书的作者字符串迁移为作者和作品实体, 可重复执行
+func (r *AuthorRepository) MigrateBookEntities() error {
+	var books []*model.BookInfoDO
+	migrated := 0
+	err := r.DB.Where("work_id = 0 OR work_id IS NULL").FindInBatches(&books, 200, func(tx *gorm.DB, batch int) error {
+		for _, book := range books {
+			if err := r.LinkBookEntities(book.Transfer()); err != nil {
+				return err
+			}
+			migrated++
+		}
+		return nil
+	}).Error
+	if migrated > 0 {
+		log.GetLogger().Infof("migrated %d books into author/work entities", migrated)
+	}
+	return err
+}
+
+// ListOtherEditions 获取同一作品的其他版本
+func (r *AuthorRepository) ListOtherEditions(workId, excludeBookId int64) ([]*model.BookInfoDTO, error) {
+	var books []*model.BookInfoDO
+	if workId == 0 {
+		return []*model.BookInfoDTO{}, nil
+	}
+	if err := r.DB.Where("work_id = ? AND id <> ?", workId, excludeBookId).
+		Order("publish_year DESC").Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return transferBooks(books), nil
+}
+
+// SetBookWork 将图书改归入已有作品, 用于修正自动归并的错误; workId为0时拆成以该书第一作者为作者的新作品。
+// 原作品没有其他版本时一并删除, 返回图书新的作品ID
+func (r *AuthorRepository) SetBookWork(bookId, workId int64) (int64, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var book model.BookInfoDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, bookId).Error; err != nil {
+			return err
+		}
+		if workId > 0 {
+			if err := tx.Select("id").First(&model.WorkDO{}, workId).Error; err != nil {
+				return err
+			}
+		} else {
+			var authorIds []int64
+			if err := tx.Model(&model.BookAuthorDO{}).Where("book_id = ?", bookId).
+				Order("position").Limit(1).Pluck("author_id", &authorIds).Error; err != nil {
+				return err
+			}
+			work := &model.WorkDO{Title: book.Name}
+			if len(authorIds) > 0 {
+				work.AuthorId = authorIds[0]
+			}
+			if err := tx.Create(work).Error; err != nil {
+				return err
+			}
+			workId = work.Id
+		}
+		if err := tx.Model(&book).Update("work_id", workId).Error; err != nil {
+			return err
+		}
+
+		if book.WorkId == 0 || book.WorkId == workId {
+			return nil
+		}
+		var remaining int64
+		if err := tx.Model(&model.BookInfoDO{}).Where("work_id = ?", book.WorkId).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return nil
+		}
+		return tx.Delete(&model.WorkDO{}, book.WorkId).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return workId, nil
+}
+
+// 系列
+
+// CreateSeries 创建系列
+func (r *AuthorRepository) CreateSeries(seriesDTO *model.SeriesDTO) (int64, error) {
+	seriesDO := seriesDTO.Transfer()
+	if err := r.DB.Create(seriesDO).Error; err != nil {
+		return 0, err
+	}
+	return seriesDO.Id, nil
+}
+
+// GetSeriesById 根据ID获取系列
+func (r *AuthorRepository) GetSeriesById(id int64) (*model.SeriesDTO, error) {
+	var series model.SeriesDO
+	if err := r.DB.First(&series, id).Error; err != nil {
+		return nil, err
+	}
+	return series.Transfer(), nil
+}
+
+// ListBooksBySeriesId 获取系列内的图书, 按序号排列
+func (r *AuthorRepository) ListBooksBySeriesId(seriesId int64) ([]*model.BookInfoDTO, error) {
+	var books []*model.BookInfoDO
+	if err := r.DB.Where("series_id = ?", seriesId).Order("series_index, id").Find(&books).Error; err != nil {
+		return nil, err
+	}
+	return transferBooks(books), nil
+}
+
+// SetBookSeries 设置图书所属系列及序号
+func (r *AuthorRepository) SetBookSeries(bookId, seriesId int64, index int) error {
+	if err := r.DB.Select("id").First(&model.BookInfoDO{}, bookId).Error; err != nil {
+		return err
+	}
+	return r.DB.Model(&model.BookInfoDO{}).Where("id = ?", bookId).
+		Updates(map[string]interface{}{"series_id": seriesId, "series_index": index}).Error
+}
+
+// escapeLike 转义LIKE模式中的通配符和转义符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func transferBooks(books []*model.BookInfoDO) []*model.BookInfoDTO {
+	bookDTOs := make([]*model.BookInfoDTO, len(books))
+	for i, book := range books {
+		bookDTOs[i] = book.Transfer()
+	}
+	return bookDTOs
+}
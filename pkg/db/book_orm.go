@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
 	"sort"
+	"sync"
+	"time"
 	"yujian-backend/pkg/es"
 	"yujian-backend/pkg/log"
 	"yujian-backend/pkg/model"
@@ -40,6 +44,22 @@ func (r *BookRepository) GetBookById(id int64) (*model.BookInfoDTO, error) {
 	return book.Transfer(), nil
 }
 
+// GetBooksByIds 批量获取图书, 按图书ID索引, 不存在的ID会被忽略
+func (r *BookRepository) GetBooksByIds(ids []int64) (map[int64]*model.BookInfoDTO, error) {
+	books := make(map[int64]*model.BookInfoDTO, len(ids))
+	if len(ids) == 0 {
+		return books, nil
+	}
+	var bookDOs []*model.BookInfoDO
+	if err := r.DB.Where("id IN ?", ids).Find(&bookDOs).Error; err != nil {
+		return nil, err
+	}
+	for _, bookDO := range bookDOs {
+		books[bookDO.Id] = bookDO.Transfer()
+	}
+	return books, nil
+}
+
 // UpdateBook 更新书
 func (r *BookRepository) UpdateBook(bookDTO *model.BookInfoDTO) error {
 	bookDO := bookDTO.TransformToDO()
@@ -53,13 +73,250 @@ func (r *BookRepository) DeleteBook(id int64) error {
 
 // 书评
 
-// CreateBookComment 创建书评
-func (r *BookRepository) CreateBookComment(commentDTO *model.BookCommentDTO) error {
+// UpsertBookComment 发布书评, 用户已评论过这本书时改为修改原书评, 同一事务内更新评分聚合; 返回是否为新建
+func (r *BookRepository) UpsertBookComment(commentDTO *model.BookCommentDTO) (bool, error) {
 	commentDO := commentDTO.Transfer()
-	if err := r.DB.Create(commentDO).Error; err != nil {
+	created := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(commentDO)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			created = true
+			return applyRating(tx, commentDO.BookId, func(rating *model.BookRatingDO, priorMean float64) {
+				rating.Apply(commentDO.Score, 1, priorMean)
+			})
+		}
+
+		edit := *commentDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("book_id = ? AND publisher_id = ?", commentDO.BookId, commentDO.PublisherId).
+			First(commentDO).Error; err != nil {
+			return err
+		}
+		return editComment(tx, commentDO, &edit)
+	})
+	if err != nil {
+		return false, err
+	}
+	*commentDTO = *commentDO.TransformToDTO()
+	return created, nil
+}
+
+// EditBookComment 修改书评内容、剧透标记和评分, 同一事务内更新评分聚合
+func (r *BookRepository) EditBookComment(id int64, edit *model.BookCommentDTO) (*model.BookCommentDTO, error) {
+	var comment model.BookCommentDO
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&comment, id).Error; err != nil {
+			return err
+		}
+		return editComment(tx, &comment, edit.Transfer())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment.TransformToDTO(), nil
+}
+
+// editComment 用edit中的内容、剧透标记和评分修改已加锁的书评并记录修改时间, 用新旧评分的差更新评分聚合
+func editComment(tx *gorm.DB, comment *model.BookCommentDO, edit *model.BookCommentDO) error {
+	oldScore := comment.Score
+	now := time.Now()
+	comment.Content = edit.Content
+	comment.Spoiler = edit.Spoiler
+	comment.SpoilerSpans = edit.SpoilerSpans
+	comment.Score = edit.Score
+	comment.EditedAt = &now
+	if err := tx.Model(comment).Updates(map[string]interface{}{
+		"content":       comment.Content,
+		"spoiler":       comment.Spoiler,
+		"spoiler_spans": comment.SpoilerSpans,
+		"score":         comment.Score,
+		"edited_at":     now,
+	}).Error; err != nil {
 		return err
 	}
-	return nil
+	return applyRating(tx, comment.BookId, func(rating *model.BookRatingDO, priorMean float64) {
+		rating.Apply(oldScore, -1, priorMean)
+		rating.Apply(comment.Score, 1, priorMean)
+	})
+}
+
+// applyRating 锁住图书的评分聚合行(不存在则创建), 用全站均分作为先验执行update, 并同步图书的Score
+func applyRating(tx *gorm.DB, bookId int64, update func(rating *model.BookRatingDO, priorMean float64)) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.BookRatingDO{BookId: bookId}).Error; err != nil {
+		return err
+	}
+	var rating model.BookRatingDO
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rating, bookId).Error; err != nil {
+		return err
+	}
+	priorMean, err := globalMeanScore(tx)
+	if err != nil {
+		return err
+	}
+	update(&rating, priorMean)
+	rating.UpdatedAt = time.Now()
+	if err = tx.Save(&rating).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.BookInfoDO{}).Where("id = ?", bookId).
+		Update("score", math.Round(rating.Mean*10)/10).Error
+}
+
+// priorMeanTTL 全站均分的缓存时间; 先验只需大致准确, 不必每次写书评都全表汇总
+const priorMeanTTL = 10 * time.Minute
+
+// priorMeanCache 缓存的全站均分
+var priorMeanCache struct {
+	sync.Mutex
+	value    float64
+	loadedAt time.Time
+}
+
+// globalMeanScore 全站所有评分的均分, 作为贝叶斯加权的先验; 缓存priorMeanTTL, 过期后再汇总
+func globalMeanScore(tx *gorm.DB) (float64, error) {
+	priorMeanCache.Lock()
+	defer priorMeanCache.Unlock()
+	if !priorMeanCache.loadedAt.IsZero() && time.Since(priorMeanCache.loadedAt) < priorMeanTTL {
+		return priorMeanCache.value, nil
+	}
+	value, err := loadGlobalMeanScore(tx)
+	if err != nil {
+		return 0, err
+	}
+	priorMeanCache.value = value
+	priorMeanCache.loadedAt = time.Now()
+	return value, nil
+}
+
+// loadGlobalMeanScore 汇总全站所有评分的均分
+func loadGlobalMeanScore(tx *gorm.DB) (float64, error) {
+	var total struct {
+		Sum   float64
+		Count int64
+	}
+	if err := tx.Model(&model.BookRatingDO{}).Select("COALESCE(SUM(sum), 0) AS sum, COALESCE(SUM(count), 0) AS count").
+		Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	if total.Count == 0 {
+		return model.DefaultPriorMean, nil
+	}
+	return total.Sum / float64(total.Count), nil
+}
+
+// dedupeBookComments 同一用户对同一本书有多条书评时只保留最新的一条, 并从评分聚合中扣除;
+// 需在建(book_id, publisher_id)唯一索引之前执行, 可重复执行
+func dedupeBookComments(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&model.BookCommentDO{}) {
+		return nil
+	}
+	var duplicates []*model.BookCommentDO
+	if err := db.Table("book_comment AS c").Select("DISTINCT c.*").
+		Joins("JOIN book_comment AS n ON n.book_id = c.book_id AND n.publisher_id = c.publisher_id AND " +
+			"(n.post_time > c.post_time OR (n.post_time = c.post_time AND n.id > c.id))").
+		Find(&duplicates).Error; err != nil {
+		return err
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	// 评分聚合表还不存在时, 由BackfillBookRatings按剩下的书评补算
+	hasRating := db.Migrator().HasTable(&model.BookRatingDO{})
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, comment := range duplicates {
+			if err := tx.Delete(&model.BookCommentDO{}, comment.Id).Error; err != nil {
+				return err
+			}
+			if !hasRating {
+				continue
+			}
+			if err := applyRating(tx, comment.BookId, func(rating *model.BookRatingDO, priorMean float64) {
+				rating.Apply(comment.Score, -1, priorMean)
+			}); err != nil {
+				return err
+			}
+		}
+		log.GetLogger().Infof("removed %d duplicate book reviews", len(duplicates))
+		return nil
+	})
+}
+
+// GetBookRating 获取图书的评分聚合, 没有评分时返回零值
+func (r *BookRepository) GetBookRating(bookId int64) (*model.BookRatingDTO, error) {
+	var ratings []*model.BookRatingDO
+	if err := r.DB.Where("book_id = ?", bookId).Limit(1).Find(&ratings).Error; err != nil {
+		return nil, err
+	}
+	if len(ratings) == 0 {
+		return &model.BookRatingDTO{BookId: bookId}, nil
+	}
+	return ratings[0].Transfer(), nil
+}
+
+// BackfillBookRatings 为有书评但还没有评分聚合的图书补算聚合, 可重复执行; 返回补算的图书ID, 需由调用方同步到ES
+func (r *BookRepository) BackfillBookRatings() ([]int64, error) {
+	var comments []*model.BookCommentDO
+	if err := r.DB.Select("book_id, score").
+		Where("book_id NOT IN (?)", r.DB.Model(&model.BookRatingDO{}).Select("book_id")).
+		Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, nil
+	}
+
+	priorMean, err := globalMeanScore(r.DB)
+	if err != nil {
+		return nil, err
+	}
+	ratings := make(map[int64]*model.BookRatingDO)
+	for _, comment := range comments {
+		rating, ok := ratings[comment.BookId]
+		if !ok {
+			rating = &model.BookRatingDO{BookId: comment.BookId, UpdatedAt: time.Now()}
+			ratings[comment.BookId] = rating
+		}
+		rating.Apply(comment.Score, 1, priorMean)
+	}
+	bookIds := make([]int64, 0, len(ratings))
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, rating := range ratings {
+			bookIds = append(bookIds, rating.BookId)
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(rating).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.BookInfoDO{}).Where("id = ?", rating.BookId).
+				Update("score", math.Round(rating.Mean*10)/10).Error; err != nil {
+				return err
+			}
+		}
+		log.GetLogger().Infof("backfilled rating aggregates for %d books", len(ratings))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bookIds, nil
+}
+
+// SyncBookToES 将图书及其评分聚合写回ES, 用于按评分排序
+func (r *BookRepository) SyncBookToES(bookId int64) error {
+	book, err := r.GetBookById(bookId)
+	if err != nil {
+		return err
+	}
+	rating, err := r.GetBookRating(bookId)
+	if err != nil {
+		return err
+	}
+	bookES := book.TransformToES()
+	bookES.RatingCount = rating.Count
+	bookES.WeightedScore = rating.WeightedScore
+	return es.UpdateArticle(context.Background(), bookES)
 }
 
 // GetBookCommentById 根据书评ID获取书评
@@ -71,6 +328,90 @@ func (r *BookRepository) GetBookCommentById(id int64) (*model.BookCommentDTO, er
 	return comment.TransformToDTO(), nil
 }
 
+// ListBookComments 按排序方式和星级分页获取书评; 带游标时从游标之后取(keyset分页), 返回下一页的游标(没有更多时为nil)和总数
+func (r *BookRepository) ListBookComments(query *model.ListReviewsQuery) ([]*model.BookCommentDTO, *model.ReviewCursor, int64, error) {
+	db := r.DB.Model(&model.BookCommentDO{}).Where("book_id = ?", query.BookId)
+	if query.Stars > 0 {
+		// 与评分分布一致, 按四舍五入归入星级
+		db = db.Where("score >= ? AND score < ?", float64(query.Stars)-0.5, float64(query.Stars)+0.5)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, nil, 0, err
+	}
+
+	// 排序列和方向, 排序值相同时按ID倒序; 最新直接按ID倒序
+	column, desc := "", true
+	switch query.Sort {
+	case model.ReviewSortHighest:
+		column = "score"
+	case model.ReviewSortLowest:
+		column, desc = "score", false
+	case model.ReviewSortHelpful:
+		column = "helpfulness"
+	}
+	if column == "" {
+		db = db.Order("id DESC")
+		if query.Cursor != nil {
+			db = db.Where("id < ?", query.Cursor.Id)
+		}
+	} else {
+		direction, op := "DESC", "<"
+		if !desc {
+			direction, op = "ASC", ">"
+		}
+		db = db.Order(column + " " + direction).Order("id DESC")
+		if query.Cursor != nil {
+			db = db.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND id < ?))", column, op, column),
+				query.Cursor.Value, query.Cursor.Value, query.Cursor.Id)
+		}
+	}
+	if query.Cursor == nil && query.Page > 1 {
+		db = db.Offset((query.Page - 1) * query.PageSize)
+	}
+
+	// 多取一条判断是否还有下一页
+	var commentDOs []*model.BookCommentDO
+	if err := db.Limit(query.PageSize + 1).Find(&commentDOs).Error; err != nil {
+		return nil, nil, 0, err
+	}
+	var next *model.ReviewCursor
+	if len(commentDOs) > query.PageSize {
+		commentDOs = commentDOs[:query.PageSize]
+		last := commentDOs[len(commentDOs)-1]
+		next = &model.ReviewCursor{Id: last.Id}
+		switch column {
+		case "score":
+			next.Value = last.Score
+		case "helpfulness":
+			next.Value = last.Helpfulness
+		}
+	}
+
+	commentDTOs := make([]*model.BookCommentDTO, len(commentDOs))
+	for i, commentDO := range commentDOs {
+		commentDTOs[i] = commentDO.TransformToDTO()
+	}
+	return commentDTOs, next, total, nil
+}
+
+// BackfillReviewHelpfulness 为有赞但还没有计算Wilson下界的历史书评补算, 可重复执行
+func (r *BookRepository) BackfillReviewHelpfulness() error {
+	var comments []*model.BookCommentDO
+	if err := r.DB.Select("id, `like`, dislike").Where("`like` > 0 AND helpfulness = 0").Find(&comments).Error; err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if err := r.DB.Model(comment).Update("helpfulness", model.WilsonLowerBound(comment.Like, comment.Dislike)).Error; err != nil {
+			return err
+		}
+	}
+	if len(comments) > 0 {
+		log.GetLogger().Infof("backfilled helpfulness for %d reviews", len(comments))
+	}
+	return nil
+}
+
 // GetBookCommentsByBookId 根据书ID获取书评
 func (r *BookRepository) GetBookCommentsByBookId(bookId int64) ([]*model.BookCommentDTO, error) {
 	var commentDOs []*model.BookCommentDO
@@ -89,16 +430,185 @@ func (r *BookRepository) UpdateBookComment(comment *model.BookCommentDO) error {
 	return r.DB.Save(comment).Error
 }
 
-// DeleteBookComment 删除书评
+// VoteBookComment 对书评投票: 没投过则投票, 重复投同一票则取消, 投相反的票则改票;
+// 锁住书评行串行化同一书评的投票, 返回更新后的书评和用户当前的投票(0为未投)
+func (r *BookRepository) VoteBookComment(reviewId, userId int64, value int) (*model.BookCommentDTO, int, error) {
+	var comment model.BookCommentDO
+	myVote := 0
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&comment, reviewId).Error; err != nil {
+			return err
+		}
+		var votes []*model.ReviewVoteDO
+		if err := tx.Where("review_id = ? AND user_id = ?", reviewId, userId).Limit(1).Find(&votes).Error; err != nil {
+			return err
+		}
+
+		// 计算赞/踩计数的变化
+		var likeDelta, dislikeDelta int64
+		count := func(v int, delta int64) {
+			if v == model.ReviewVoteLike {
+				likeDelta += delta
+			} else {
+				dislikeDelta += delta
+			}
+		}
+		switch {
+		case len(votes) == 0:
+			vote := &model.ReviewVoteDO{ReviewId: reviewId, UserId: userId, Value: value, CreatedAt: time.Now()}
+			if err := tx.Create(vote).Error; err != nil {
+				return err
+			}
+			count(value, 1)
+			myVote = value
+		case votes[0].Value == value:
+			if err := tx.Delete(votes[0]).Error; err != nil {
+				return err
+			}
+			count(value, -1)
+		default:
+			if err := tx.Model(votes[0]).Update("value", value).Error; err != nil {
+				return err
+			}
+			count(votes[0].Value, -1)
+			count(value, 1)
+			myVote = value
+		}
+
+		if err := tx.Model(&comment).Updates(map[string]interface{}{
+			"like":    gorm.Expr("`like` + ?", likeDelta),
+			"dislike": gorm.Expr("dislike + ?", dislikeDelta),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&comment, reviewId).Error; err != nil {
+			return err
+		}
+		comment.Helpfulness = model.WilsonLowerBound(comment.Like, comment.Dislike)
+		return tx.Model(&comment).Update("helpfulness", comment.Helpfulness).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return comment.TransformToDTO(), myVote, nil
+}
+
+// GetReviewVotes 获取用户对一批书评的投票, 按书评ID索引, 未投票的不在结果中
+func (r *BookRepository) GetReviewVotes(userId int64, reviewIds []int64) (map[int64]int, error) {
+	votes := make(map[int64]int, len(reviewIds))
+	if len(reviewIds) == 0 {
+		return votes, nil
+	}
+	var voteDOs []*model.ReviewVoteDO
+	if err := r.DB.Where("user_id = ? AND review_id IN ?", userId, reviewIds).Find(&voteDOs).Error; err != nil {
+		return nil, err
+	}
+	for _, vote := range voteDOs {
+		votes[vote.ReviewId] = vote.Value
+	}
+	return votes, nil
+}
+
+// GetReviewReplyById 根据ID获取书评回复
+func (r *BookRepository) GetReviewReplyById(id int64) (*model.ReviewReplyDO, error) {
+	var reply model.ReviewReplyDO
+	if err := r.DB.First(&reply, id).Error; err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// CreateReviewReply 回复书评, 同一事务内增加书评的回复数
+func (r *BookRepository) CreateReviewReply(reply *model.ReviewReplyDO) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(reply).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.BookCommentDO{}).Where("id = ?", reply.ReviewId).
+			UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error
+	})
+}
+
+// ListReviewReplies 按顶层回复分页获取书评的回复, 每条顶层回复带上全部子回复; 返回顶层回复总数
+func (r *BookRepository) ListReviewReplies(reviewId int64, page, pageSize int) ([]*model.ReviewReplyDTO, int64, error) {
+	var roots []*model.ReviewReplyDO
+	var total int64
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.ReviewReplyDO{}).Where("review_id = ? AND root_id = 0", reviewId).
+		Count(&total).Order("id").Offset(offset).Limit(pageSize).Find(&roots).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(roots) == 0 {
+		return []*model.ReviewReplyDTO{}, total, nil
+	}
+
+	rootIds := make([]int64, len(roots))
+	for i, root := range roots {
+		rootIds[i] = root.Id
+	}
+	var children []*model.ReviewReplyDO
+	if err := r.DB.Where("review_id = ? AND root_id IN ?", reviewId, rootIds).Order("id").Find(&children).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 补上作者名
+	all := append(roots, children...)
+	userIds := make([]int64, len(all))
+	for i, reply := range all {
+		userIds[i] = reply.UserId
+	}
+	var users []*model.UserDO
+	if err := r.DB.Select("id, name").Where("id IN ?", userIds).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	names := make(map[int64]string, len(users))
+	for _, user := range users {
+		names[user.Id] = user.Name
+	}
+
+	// 按ID升序, 父回复一定先于子回复出现
+	nodes := make(map[int64]*model.ReviewReplyDTO, len(all))
+	replies := make([]*model.ReviewReplyDTO, 0, len(roots))
+	for _, reply := range all {
+		node := reply.Transfer()
+		node.UserName = names[reply.UserId]
+		nodes[reply.Id] = node
+		if reply.ParentId == 0 {
+			replies = append(replies, node)
+		} else if parent, ok := nodes[reply.ParentId]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+	return replies, total, nil
+}
+
+// DeleteBookComment 删除书评, 同一事务内更新评分聚合
 func (r *BookRepository) DeleteBookComment(id int64) error {
-	return r.DB.Delete(&model.BookCommentDO{}, id).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var comment model.BookCommentDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&comment, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&comment).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_id = ?", comment.Id).Delete(&model.ReviewVoteDO{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_id = ?", comment.Id).Delete(&model.ReviewReplyDO{}).Error; err != nil {
+			return err
+		}
+		return applyRating(tx, comment.BookId, func(rating *model.BookRatingDO, priorMean float64) {
+			rating.Apply(comment.Score, -1, priorMean)
+		})
+	})
 }
 
 // SearchBooks 搜索书
-func (r *BookRepository) SearchBooks(keyword, category string, page, pageSize int) ([]*model.BookInfoDTO, error) {
+func (r *BookRepository) SearchBooks(keyword, category, sortBy string, page, pageSize int) ([]*model.BookInfoDTO, error) {
 	// 调用es查询符合条件的book_id
 	ctx := context.Background()
-	bookIDs, err := es.SearchBooks(ctx, keyword, category, page, pageSize)
+	bookIDs, err := es.SearchBooks(ctx, keyword, category, sortBy, page, pageSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search books in ES: %v", err)
 	}
@@ -114,9 +624,16 @@ func (r *BookRepository) SearchBooks(keyword, category string, page, pageSize in
 		return nil, fmt.Errorf("failed to search books in DB: %v", err)
 	}
 
-	bookDTOs := make([]*model.BookInfoDTO, len(bookDOs))
-	for i, bookDO := range bookDOs {
-		bookDTOs[i] = bookDO.Transfer()
+	// 按ES返回的顺序排列
+	bookMap := make(map[int64]*model.BookInfoDO, len(bookDOs))
+	for _, bookDO := range bookDOs {
+		bookMap[bookDO.Id] = bookDO
+	}
+	bookDTOs := make([]*model.BookInfoDTO, 0, len(bookDOs))
+	for _, id := range bookIDs {
+		if bookDO, ok := bookMap[id]; ok {
+			bookDTOs = append(bookDTOs, bookDO.Transfer())
+		}
 	}
 	return bookDTOs, nil
 }
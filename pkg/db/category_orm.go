@@ -0,0 +1,250 @@
+package db
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"yujian-backend/pkg/log"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCategoryAliasExists = errors.New("category alias already exists")
+	ErrInvalidCategoryMove = errors.New("cannot merge a category into itself or its descendant")
+)
+
+var categoryRepository CategoryRepository
+
+type CategoryRepository struct {
+	DB *gorm.DB
+}
+
+func GetCategoryRepository() *CategoryRepository {
+	return &categoryRepository
+}
+
+// normalizeCategoryAlias 别名归一化: 去首尾空白、合并连续空白、转小写
+func normalizeCategoryAlias(alias string) string {
+	return strings.ToLower(strings.Join(strings.Fields(alias), " "))
+}
+
+// CreateCategory 创建分类, 名字本身也作为一个别名
+func (r *CategoryRepository) CreateCategory(req *model.CreateCategoryRequest) (*model.CategoryDO, error) {
+	category := &model.CategoryDO{
+		Name:     req.Name,
+		ParentId: req.ParentId,
+		ClcCode:  req.ClcCode,
+		Sort:     req.Sort,
+	}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		parentPath := "/"
+		if req.ParentId != 0 {
+			var parent model.CategoryDO
+			if err := tx.First(&parent, req.ParentId).Error; err != nil {
+				return err
+			}
+			parentPath = parent.Path
+		}
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		category.Path = parentPath + strconv.FormatInt(category.Id, 10) + "/"
+		if err := tx.Model(category).Update("path", category.Path).Error; err != nil {
+			return err
+		}
+		for _, alias := range append([]string{req.Name}, req.Aliases...) {
+			if err := addCategoryAlias(tx, category.Id, alias); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// AddAlias 给分类添加别名
+func (r *CategoryRepository) AddAlias(categoryId int64, alias string) error {
+	return addCategoryAlias(r.DB, categoryId, alias)
+}
+
+func addCategoryAlias(tx *gorm.DB, categoryId int64, alias string) error {
+	alias = normalizeCategoryAlias(alias)
+	if alias == "" {
+		return nil
+	}
+	var existing []*model.CategoryAliasDO
+	if err := tx.Where("alias = ?", alias).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		if existing[0].CategoryId == categoryId {
+			return nil
+		}
+		return ErrCategoryAliasExists
+	}
+	return tx.Create(&model.CategoryAliasDO{Alias: alias, CategoryId: categoryId}).Error
+}
+
+// ResolveCategory 根据分类名或别名找到分类ID, create为true时不存在则创建顶级分类, 否则返回0
+func (r *CategoryRepository) ResolveCategory(name string, create bool) (int64, error) {
+	alias := normalizeCategoryAlias(name)
+	if alias == "" {
+		return 0, nil
+	}
+	var aliases []*model.CategoryAliasDO
+	if err := r.DB.Where("alias = ?", alias).Limit(1).Find(&aliases).Error; err != nil {
+		return 0, err
+	}
+	if len(aliases) > 0 {
+		return aliases[0].CategoryId, nil
+	}
+	if !create {
+		return 0, nil
+	}
+	category, err := r.CreateCategory(&model.CreateCategoryRequest{Name: strings.TrimSpace(name)})
+	if err != nil {
+		return 0, err
+	}
+	return category.Id, nil
+}
+
+// GetCategoryById 根据ID获取分类
+func (r *CategoryRepository) GetCategoryById(id int64) (*model.CategoryDO, error) {
+	var category model.CategoryDO
+	if err := r.DB.First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetCategoriesByIds 批量获取分类, 按分类ID索引, 不存在的ID会被忽略
+func (r *CategoryRepository) GetCategoriesByIds(ids []int64) (map[int64]*model.CategoryDO, error) {
+	categories := make(map[int64]*model.CategoryDO, len(ids))
+	if len(ids) == 0 {
+		return categories, nil
+	}
+	var categoryDOs []*model.CategoryDO
+	if err := r.DB.Where("id IN ?", ids).Find(&categoryDOs).Error; err != nil {
+		return nil, err
+	}
+	for _, category := range categoryDOs {
+		categories[category.Id] = category
+	}
+	return categories, nil
+}
+
+// ListCategories 获取全部分类
+func (r *CategoryRepository) ListCategories() ([]*model.CategoryDO, error) {
+	var categories []*model.CategoryDO
+	if err := r.DB.Order("sort, id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// CountBooksByCategory 统计每个分类(不含子分类)直接挂的图书数
+func (r *CategoryRepository) CountBooksByCategory() (map[int64]int64, error) {
+	var rows []struct {
+		CategoryId int64
+		Count      int64
+	}
+	if err := r.DB.Model(&model.BookInfoDO{}).Select("category_id, COUNT(*) AS count").
+		Where("category_id <> 0").Group("category_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryId] = row.Count
+	}
+	return counts, nil
+}
+
+// ListBooksInCategory 分页获取分类及其子分类下的图书
+func (r *CategoryRepository) ListBooksInCategory(category *model.CategoryDO, page, pageSize int) ([]*model.BookInfoDTO, int64, error) {
+	var books []*model.BookInfoDO
+	var total int64
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.BookInfoDO{}).
+		Joins("JOIN category ON category.id = book_info.category_id").
+		Where("category.path LIKE ?", category.Path+"%").
+		Count(&total).Order("book_info.id DESC").Offset(offset).Limit(pageSize).
+		Select("book_info.*").Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	return transferBooks(books), total, nil
+}
+
+// MergeCategory 将source合并进target: 别名、图书、帖子和子分类全部转到target后删除source
+func (r *CategoryRepository) MergeCategory(sourceId, targetId int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		locking := clause.Locking{Strength: "UPDATE"}
+		var source, target model.CategoryDO
+		if err := tx.Clauses(locking).First(&source, sourceId).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(locking).First(&target, targetId).Error; err != nil {
+			return err
+		}
+		if strings.HasPrefix(target.Path, source.Path) {
+			return ErrInvalidCategoryMove
+		}
+
+		if err := tx.Model(&model.CategoryAliasDO{}).Where("category_id = ?", sourceId).
+			Update("category_id", targetId).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.BookInfoDO{}).Where("category_id = ?", sourceId).
+			Update("category_id", targetId).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.PostDO{}).Where("category_id = ?", sourceId).
+			Update("category_id", targetId).Error; err != nil {
+			return err
+		}
+
+		// 子分类挂到target下, 并整体替换子树路径前缀
+		if err := tx.Model(&model.CategoryDO{}).Where("parent_id = ?", sourceId).
+			Update("parent_id", targetId).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.CategoryDO{}).
+			Where("path LIKE ? AND id <> ?", source.Path+"%", sourceId).
+			Update("path", gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", target.Path, len(source.Path)+1)).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.CategoryDO{}, sourceId).Error
+	})
+}
+
+// MigrateCategories 将图书和帖子上的自由文本分类迁移为规范分类, 可重复执行
+func (r *CategoryRepository) MigrateCategories() error {
+	migrated := 0
+	for _, table := range []interface{}{&model.BookInfoDO{}, &model.PostDO{}} {
+		var names []string
+		if err := r.DB.Model(table).Where("category_id = 0 AND category <> ''").
+			Distinct().Pluck("category", &names).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			categoryId, err := r.ResolveCategory(name, true)
+			if err != nil {
+				return err
+			}
+			result := r.DB.Model(table).Where("category_id = 0 AND category = ?", name).Update("category_id", categoryId)
+			if result.Error != nil {
+				return result.Error
+			}
+			migrated += int(result.RowsAffected)
+		}
+	}
+	if migrated > 0 {
+		log.GetLogger().Infof("migrated %d books/posts into category taxonomy", migrated)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package db
+
+import (
+	"errors"
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var clubRepository ClubRepository
+
+type ClubRepository struct {
+	DB *gorm.DB
+}
+
+func GetClubRepository() *ClubRepository {
+	return &clubRepository
+}
+
+// CreateClub 创建读书会, 创建者成为群主
+func (r *ClubRepository) CreateClub(club *model.ClubDO) (int64, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		club.MemberCount = 1
+		if err := tx.Create(club).Error; err != nil {
+			return err
+		}
+		owner := &model.ClubMemberDO{
+			ClubId:   club.Id,
+			UserId:   club.OwnerId,
+			Role:     model.ClubRoleOwner,
+			Status:   model.ClubMemberActive,
+			JoinedAt: club.CreatedAt,
+		}
+		return tx.Create(owner).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return club.Id, nil
+}
+
+// GetClubById 根据ID获取读书会
+func (r *ClubRepository) GetClubById(id int64) (*model.ClubDO, error) {
+	var club model.ClubDO
+	if err := r.DB.First(&club, id).Error; err != nil {
+		return nil, err
+	}
+	return &club, nil
+}
+
+// UpdateClub 修改读书会信息
+func (r *ClubRepository) UpdateClub(id int64, req *model.CreateClubRequest) error {
+	return r.DB.Model(&model.ClubDO{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":            req.Name,
+		"description":     req.Description,
+		"join_policy":     req.JoinPolicy,
+		"current_book_id": req.CurrentBookId,
+	}).Error
+}
+
+// ListClubs 分页获取读书会, 按成员数排序
+func (r *ClubRepository) ListClubs(page, pageSize int) ([]*model.ClubDO, int64, error) {
+	var clubs []*model.ClubDO
+	var total int64
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.ClubDO{}).Count(&total).
+		Order("member_count DESC, id DESC").Offset(offset).Limit(pageSize).Find(&clubs).Error; err != nil {
+		return nil, 0, err
+	}
+	return clubs, total, nil
+}
+
+// GetMember 获取成员记录, 不是成员时返回nil
+func (r *ClubRepository) GetMember(clubId, userId int64) (*model.ClubMemberDO, error) {
+	var member model.ClubMemberDO
+	err := r.DB.Where("club_id = ? AND user_id = ?", clubId, userId).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetActiveClubIds 获取用户已正式加入的读书会ID
+func (r *ClubRepository) GetActiveClubIds(userId int64) ([]int64, error) {
+	var clubIds []int64
+	if err := r.DB.Model(&model.ClubMemberDO{}).Where("user_id = ? AND status = ?", userId, model.ClubMemberActive).
+		Pluck("club_id", &clubIds).Error; err != nil {
+		return nil, err
+	}
+	return clubIds, nil
+}
+
+// Join 加入或申请加入读书会, 返回成员记录; 已是成员或已申请时返回已有记录
+func (r *ClubRepository) Join(club *model.ClubDO, userId int64) (*model.ClubMemberDO, error) {
+	member := &model.ClubMemberDO{
+		ClubId:   club.Id,
+		UserId:   userId,
+		Role:     model.ClubRoleMember,
+		Status:   model.ClubMemberActive,
+		JoinedAt: time.Now(),
+	}
+	if club.JoinPolicy == model.ClubJoinApproval {
+		member.Status = model.ClubMemberPending
+	}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(member)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return tx.Where("club_id = ? AND user_id = ?", club.Id, userId).First(member).Error
+		}
+		if member.Status != model.ClubMemberActive {
+			return nil
+		}
+		return tx.Model(&model.ClubDO{}).Where("id = ?", club.Id).
+			UpdateColumn("member_count", gorm.Expr("member_count + 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// Approve 通过加入申请, 仅当仍在申请中时生效, 返回是否通过
+func (r *ClubRepository) Approve(clubId, userId int64) (bool, error) {
+	approved := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ClubMemberDO{}).
+			Where("club_id = ? AND user_id = ? AND status = ?", clubId, userId, model.ClubMemberPending).
+			Updates(map[string]interface{}{"status": model.ClubMemberActive, "joined_at": time.Now()})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		approved = true
+		return tx.Model(&model.ClubDO{}).Where("id = ?", clubId).
+			UpdateColumn("member_count", gorm.Expr("member_count + 1")).Error
+	})
+	return approved, err
+}
+
+// Reject 拒绝加入申请, 返回是否删除了申请
+func (r *ClubRepository) Reject(clubId, userId int64) (bool, error) {
+	result := r.DB.Where("club_id = ? AND user_id = ? AND status = ?", clubId, userId, model.ClubMemberPending).
+		Delete(&model.ClubMemberDO{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Leave 退出读书会或撤回申请, 群主不能退出
+func (r *ClubRepository) Leave(clubId, userId int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var member model.ClubMemberDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("club_id = ? AND user_id = ? AND role <> ?", clubId, userId, model.ClubRoleOwner).
+			First(&member).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+		if member.Status != model.ClubMemberActive {
+			return nil
+		}
+		return tx.Model(&model.ClubDO{}).Where("id = ? AND member_count > 0", clubId).
+			UpdateColumn("member_count", gorm.Expr("member_count - 1")).Error
+	})
+}
+
+// SetMemberRole 设置正式成员的角色, 不能修改群主; 返回成员是否存在, 角色未变时也视为成功
+func (r *ClubRepository) SetMemberRole(clubId, userId int64, role string) (bool, error) {
+	found := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var member model.ClubMemberDO
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("club_id = ? AND user_id = ? AND status = ? AND role <> ?", clubId, userId, model.ClubMemberActive, model.ClubRoleOwner).
+			First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if member.Role == role {
+			return nil
+		}
+		return tx.Model(&member).Update("role", role).Error
+	})
+	return found, err
+}
+
+// ListMembers 分页获取某状态的成员, 按加入时间
+func (r *ClubRepository) ListMembers(clubId int64, status string, page, pageSize int) ([]*model.ClubMemberDTO, int64, error) {
+	var members []*model.ClubMemberDO
+	var total int64
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.ClubMemberDO{}).Where("club_id = ? AND status = ?", clubId, status).
+		Count(&total).Order("joined_at, id").Offset(offset).Limit(pageSize).Find(&members).Error; err != nil {
+		return nil, 0, err
+	}
+
+	userIds := make([]int64, len(members))
+	for i, member := range members {
+		userIds[i] = member.UserId
+	}
+	var users []*model.UserDO
+	if len(userIds) > 0 {
+		if err := r.DB.Select("id, name").Where("id IN ?", userIds).Find(&users).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+	names := make(map[int64]string, len(users))
+	for _, user := range users {
+		names[user.Id] = user.Name
+	}
+
+	memberDTOs := make([]*model.ClubMemberDTO, len(members))
+	for i, member := range members {
+		memberDTOs[i] = &model.ClubMemberDTO{
+			UserId:   member.UserId,
+			Name:     names[member.UserId],
+			Role:     member.Role,
+			Status:   member.Status,
+			JoinedAt: member.JoinedAt,
+		}
+	}
+	return memberDTOs, total, nil
+}
+
+// ListMemberIdsByRole 获取某些角色的正式成员ID, roles为空时返回全部正式成员
+func (r *ClubRepository) ListMemberIdsByRole(clubId int64, roles ...string) ([]int64, error) {
+	var userIds []int64
+	query := r.DB.Model(&model.ClubMemberDO{}).Where("club_id = ? AND status = ?", clubId, model.ClubMemberActive)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	if err := query.Pluck("user_id", &userIds).Error; err != nil {
+		return nil, err
+	}
+	return userIds, nil
+}
+
+// CreateSchedule 添加阅读计划
+func (r *ClubRepository) CreateSchedule(schedule *model.ClubScheduleDO) (int64, error) {
+	if err := r.DB.Create(schedule).Error; err != nil {
+		return 0, err
+	}
+	return schedule.Id, nil
+}
+
+// DeleteSchedule 删除阅读计划
+func (r *ClubRepository) DeleteSchedule(clubId, scheduleId int64) error {
+	return r.DB.Where("id = ? AND club_id = ?", scheduleId, clubId).Delete(&model.ClubScheduleDO{}).Error
+}
+
+// ListSchedule 获取读书会的阅读计划, 按开始日期
+func (r *ClubRepository) ListSchedule(clubId int64) ([]*model.ClubScheduleDO, error) {
+	var schedule []*model.ClubScheduleDO
+	if err := r.DB.Where("club_id = ?", clubId).Order("start_date, id").Find(&schedule).Error; err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// CreateEvent 发布活动
+func (r *ClubRepository) CreateEvent(event *model.ClubEventDO) (int64, error) {
+	if err := r.DB.Create(event).Error; err != nil {
+		return 0, err
+	}
+	return event.Id, nil
+}
+
+// ListEvents 获取读书会最近的活动, 按开始时间倒序
+func (r *ClubRepository) ListEvents(clubId int64, limit int) ([]*model.ClubEventDO, error) {
+	var events []*model.ClubEventDO
+	if err := r.DB.Where("club_id = ?", clubId).Order("start_time DESC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
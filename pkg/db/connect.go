@@ -11,7 +11,26 @@ import (
 
 func InitDB() {
 	db := createConnect(config.Config.DB)
-	if err := db.AutoMigrate(&model.UserDO{}, &model.PostDO{}, &model.PostCommentDO{}, &model.BookInfoDO{}, &model.BookCommentDO{}, &model.UserRecommendRecordDO{}); err != nil {
+	// 同一用户对同一本书只保留一条书评, 需在建唯一索引前执行
+	if err := dedupeBookComments(db); err != nil {
+		log.GetLogger().Errorf("failed to dedupe book reviews: %s", err)
+	}
+	if err := db.AutoMigrate(
+		&model.UserDO{}, &model.PostDO{}, &model.PostCommentDO{}, &model.BookInfoDO{}, &model.BookCommentDO{}, &model.UserRecommendRecordDO{},
+		&model.PostRevisionDO{}, &model.PostVoteDO{}, &model.PostCommentVoteDO{},
+		&model.ReadingRoomDO{}, &model.SeatDO{}, &model.SeatBookingDO{},
+		&model.NotificationDO{}, &model.PurchaseSuggestionDO{}, &model.PurchaseSuggestionVoteDO{},
+		&model.AuthorDO{}, &model.BookAuthorDO{}, &model.WorkDO{}, &model.SeriesDO{},
+		&model.CategoryDO{}, &model.CategoryAliasDO{}, &model.TagDO{}, &model.BookTagDO{},
+		&model.BookRatingDO{}, &model.ReviewVoteDO{}, &model.ReviewReplyDO{},
+		&model.ShelfDO{}, &model.ShelfItemDO{}, &model.ReadingProgressDO{},
+		&model.QuoteDO{}, &model.QuoteLikeDO{}, &model.QuoteBookmarkDO{},
+		&model.BadgeDO{}, &model.UserBadgeDO{}, &model.ReadingChallengeDO{},
+		&model.ClubDO{}, &model.ClubMemberDO{}, &model.ClubScheduleDO{}, &model.ClubEventDO{},
+		&model.AttachmentDO{}, &model.PostDraftDO{},
+		&model.PostPollDO{}, &model.PostPollOptionDO{}, &model.PostPollBallotDO{}, &model.PostPollVoteDO{},
+		&model.PostHashtagDO{},
+	); err != nil {
 		log.GetLogger().Fatalf("failed to migrate database: %s", err)
 	} else {
 		log.GetLogger().Info("Successfully migrated database...")
@@ -21,6 +40,53 @@ func InitDB() {
 	postRepository = PostRepository{DB: db}
 	bookRepository = BookRepository{DB: db}
 	recommendRepository = RecommendRepository{DB: db}
+	seatRepository = SeatRepository{DB: db}
+	notificationRepository = NotificationRepository{DB: db}
+	suggestionRepository = SuggestionRepository{DB: db}
+	authorRepository = AuthorRepository{DB: db}
+	categoryRepository = CategoryRepository{DB: db}
+	tagRepository = TagRepository{DB: db}
+	shelfRepository = ShelfRepository{DB: db}
+	progressRepository = ProgressRepository{DB: db}
+	quoteRepository = QuoteRepository{DB: db}
+	achievementRepository = AchievementRepository{DB: db}
+	clubRepository = ClubRepository{DB: db}
+	attachmentRepository = AttachmentRepository{DB: db}
+	draftRepository = DraftRepository{DB: db}
+	pollRepository = PollRepository{DB: db}
+
+	// 配置中的管理员
+	if err := userRepository.GrantAdmins(config.Config.Auth.Admins); err != nil {
+		log.GetLogger().Errorf("failed to grant admins: %s", err)
+	}
+	// 历史图书的作者字符串迁移为作者/作品实体
+	if err := authorRepository.MigrateBookEntities(); err != nil {
+		log.GetLogger().Errorf("failed to migrate book authors: %s", err)
+	}
+	// 补算历史书评的有用度
+	if err := bookRepository.BackfillReviewHelpfulness(); err != nil {
+		log.GetLogger().Errorf("failed to backfill review helpfulness: %s", err)
+	}
+	// 帖子和评论的赞踩迁移到投票表
+	if err := postRepository.MigrateLegacyVotes(); err != nil {
+		log.GetLogger().Errorf("failed to migrate legacy post votes: %s", err)
+	}
+	// 补算历史帖子的评论数
+	if err := postRepository.BackfillPostCommentCounts(); err != nil {
+		log.GetLogger().Errorf("failed to backfill post comment counts: %s", err)
+	}
+	// 历史评论渲染Markdown
+	if err := postRepository.BackfillPostCommentHTML(); err != nil {
+		log.GetLogger().Errorf("failed to backfill comment html: %s", err)
+	}
+	// 图书和帖子的自由文本分类迁移为规范分类
+	if err := categoryRepository.MigrateCategories(); err != nil {
+		log.GetLogger().Errorf("failed to migrate categories: %s", err)
+	}
+	// 内置徽章
+	if err := achievementRepository.SeedBadges(); err != nil {
+		log.GetLogger().Errorf("failed to seed badges: %s", err)
+	}
 }
 
 func createConnect(config *model.DBConfig) *gorm.DB {
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"errors"
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var draftRepository DraftRepository
+
+var (
+	ErrDraftLimit      = errors.New("too many drafts")
+	ErrDraftConflict   = errors.New("draft has been modified elsewhere")
+	ErrDraftPublishing = errors.New("draft is being published")
+)
+
+// draftPublishTimeout 发布中断(如进程退出)后, 超过该时间的草稿可以重新发布或修改
+const draftPublishTimeout = 5 * time.Minute
+
+type DraftRepository struct {
+	DB *gorm.DB
+}
+
+func GetDraftRepository() *DraftRepository {
+	return &draftRepository
+}
+
+// CreateDraft 新建草稿, 超过每人上限时返回ErrDraftLimit
+func (r *DraftRepository) CreateDraft(draft *model.PostDraftDO, maxDrafts int) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if maxDrafts > 0 {
+			var count int64
+			if err := tx.Model(&model.PostDraftDO{}).Where("user_id = ?", draft.UserId).Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(maxDrafts) {
+				return ErrDraftLimit
+			}
+		}
+		now := time.Now()
+		draft.Version = 1
+		draft.CreatedAt = now
+		draft.UpdatedAt = now
+		return tx.Create(draft).Error
+	})
+}
+
+// GetDraft 获取用户自己的草稿, 不是本人的草稿按不存在处理
+func (r *DraftRepository) GetDraft(userId, draftId int64) (*model.PostDraftDO, error) {
+	var draft model.PostDraftDO
+	if err := r.DB.Where("id = ? AND user_id = ?", draftId, userId).First(&draft).Error; err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// ListDrafts 按最近更新分页获取用户的草稿, 不取正文
+func (r *DraftRepository) ListDrafts(userId int64, page, pageSize int) ([]*model.PostDraftDO, int64, error) {
+	var drafts []*model.PostDraftDO
+	var total int64
+	query := r.DB.Model(&model.PostDraftDO{}).Where("user_id = ?", userId)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Omit("content").Order("updated_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&drafts).Error
+	return drafts, total, err
+}
+
+// SaveDraft 更新草稿的部分字段并递增版本; version大于0时只在版本一致时更新, 否则返回ErrDraftConflict
+func (r *DraftRepository) SaveDraft(userId, draftId int64, fields map[string]interface{}, version int64) (*model.PostDraftDO, error) {
+	var draft model.PostDraftDO
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		}
+		for k, v := range fields {
+			updates[k] = v
+		}
+		query := tx.Model(&model.PostDraftDO{}).Where("id = ? AND user_id = ?", draftId, userId).
+			Where("publishing_at IS NULL OR publishing_at < ?", time.Now().Add(-draftPublishTimeout))
+		if version > 0 {
+			query = query.Where("version = ?", version)
+		}
+		result := query.Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := tx.Select("id", "publishing_at").Where("id = ? AND user_id = ?", draftId, userId).First(&draft).Error; err != nil {
+				return err
+			}
+			if draft.PublishingAt != nil {
+				return ErrDraftPublishing
+			}
+			return ErrDraftConflict
+		}
+		return tx.Where("id = ?", draftId).First(&draft).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// ClaimDraft 锁定草稿准备发布并递增版本, 草稿正在发布时返回ErrDraftPublishing, 保证重复提交只发布一次
+func (r *DraftRepository) ClaimDraft(userId, draftId int64) (*model.PostDraftDO, error) {
+	var draft model.PostDraftDO
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", draftId, userId).First(&draft).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if draft.PublishingAt != nil && now.Sub(*draft.PublishingAt) < draftPublishTimeout {
+			return ErrDraftPublishing
+		}
+		draft.PublishingAt = &now
+		draft.Version++
+		return tx.Model(&draft).UpdateColumns(map[string]interface{}{
+			"publishing_at": now,
+			"version":       draft.Version,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// ReleaseDraft 发布失败时解除草稿的发布锁定, 草稿可以继续编辑
+func (r *DraftRepository) ReleaseDraft(userId, draftId int64) error {
+	return r.DB.Model(&model.PostDraftDO{}).Where("id = ? AND user_id = ?", draftId, userId).
+		UpdateColumn("publishing_at", nil).Error
+}
+
+// DeleteDraft 删除用户自己的草稿, 草稿中未发布的附件交给附件回收
+func (r *DraftRepository) DeleteDraft(userId, draftId int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", draftId, userId).Delete(&model.PostDraftDO{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return releaseDraftAttachments(tx, []int64{draftId})
+	})
+}
+
+// PurgeStaleDrafts 清理before之前最后更新的草稿, 返回清理的数量
+func (r *DraftRepository) PurgeStaleDrafts(before time.Time, batchSize int) (int64, error) {
+	var purged int64
+	for {
+		var ids []int64
+		err := r.DB.Transaction(func(tx *gorm.DB) error {
+			// 锁住要清理的草稿, 避免清理期间刚被保存的草稿被删
+			if err := tx.Model(&model.PostDraftDO{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("updated_at < ?", before).Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			if err := tx.Where("id IN ?", ids).Delete(&model.PostDraftDO{}).Error; err != nil {
+				return err
+			}
+			return releaseDraftAttachments(tx, ids)
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += int64(len(ids))
+		if len(ids) < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// releaseDraftAttachments 解除附件与草稿的关联, 已发布的附件不受影响
+func releaseDraftAttachments(tx *gorm.DB, draftIds []int64) error {
+	return tx.Model(&model.AttachmentDO{}).Where("draft_id IN ? AND post_id = 0", draftIds).
+		Update("draft_id", 0).Error
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+)
+
+var notificationRepository NotificationRepository
+
+type NotificationRepository struct {
+	DB *gorm.DB
+}
+
+func GetNotificationRepository() *NotificationRepository {
+	return &notificationRepository
+}
+
+// CreateNotification 创建通知
+func (r *NotificationRepository) CreateNotification(notification *model.NotificationDO) error {
+	return r.DB.Create(notification).Error
+}
+
+// ListNotifications 分页获取用户通知,onlyUnread为true时只返回未读
+func (r *NotificationRepository) ListNotifications(userId int64, onlyUnread bool, page, pageSize int) ([]*model.NotificationDTO, error) {
+	var notifications []*model.NotificationDO
+	query := r.DB.Where("user_id = ?", userId)
+	if onlyUnread {
+		query = query.Where("is_read = ?", false)
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	notificationDTOs := make([]*model.NotificationDTO, len(notifications))
+	for i, notification := range notifications {
+		notificationDTOs[i] = notification.Transfer()
+	}
+	return notificationDTOs, nil
+}
+
+// CountUnread 获取用户未读通知数
+func (r *NotificationRepository) CountUnread(userId int64) (int64, error) {
+	var count int64
+	err := r.DB.Model(&model.NotificationDO{}).Where("user_id = ? AND is_read = ?", userId, false).Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将用户的通知标记为已读,ids为空时标记全部
+func (r *NotificationRepository) MarkRead(userId int64, ids []int64) error {
+	query := r.DB.Model(&model.NotificationDO{}).Where("user_id = ? AND is_read = ?", userId, false)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	return query.Update("is_read", true).Error
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"errors"
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var pollRepository PollRepository
+
+var (
+	ErrPollExists        = errors.New("post already has a poll")
+	ErrPollClosed        = errors.New("poll has been closed")
+	ErrPollVoted         = errors.New("already voted in this poll")
+	ErrPollInvalidChoice = errors.New("invalid poll choice")
+)
+
+type PollRepository struct {
+	DB *gorm.DB
+}
+
+func GetPollRepository() *PollRepository {
+	return &pollRepository
+}
+
+// CreatePoll 给帖子添加投票, 每个帖子最多一个, 已删除的帖子不能添加
+func (r *PollRepository) CreatePoll(poll *model.PostPollDO, options []*model.PostPollOptionDO) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var post model.PostDO
+		if err := lockLivePost(tx, poll.PostId, &post); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&model.PostPollDO{}).Where("post_id = ?", poll.PostId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrPollExists
+		}
+		if err := tx.Create(poll).Error; err != nil {
+			return err
+		}
+		for i, option := range options {
+			option.PollId = poll.Id
+			option.Position = i
+		}
+		if err := tx.Create(options).Error; err != nil {
+			return err
+		}
+		return tx.Model(&post).UpdateColumn("has_poll", true).Error
+	})
+}
+
+// GetPollByPostId 获取帖子的投票和按顺序排列的选项
+func (r *PollRepository) GetPollByPostId(postId int64) (*model.PostPollDO, []*model.PostPollOptionDO, error) {
+	var poll model.PostPollDO
+	if err := r.DB.Where("post_id = ?", postId).First(&poll).Error; err != nil {
+		return nil, nil, err
+	}
+	var options []*model.PostPollOptionDO
+	if err := r.DB.Where("poll_id = ?", poll.Id).Order("position").Find(&options).Error; err != nil {
+		return nil, nil, err
+	}
+	return &poll, options, nil
+}
+
+// GetPollVotes 获取投票的全部选择, 只用于公开投票展示投票人
+func (r *PollRepository) GetPollVotes(pollId int64) ([]*model.PostPollVoteDO, error) {
+	var votes []*model.PostPollVoteDO
+	err := r.DB.Where("poll_id = ?", pollId).Order("id").Find(&votes).Error
+	return votes, err
+}
+
+// GetUserPollChoices 获取用户在投票中选择的选项
+func (r *PollRepository) GetUserPollChoices(pollId, userId int64) ([]int64, error) {
+	optionIds := []int64{}
+	err := r.DB.Model(&model.PostPollVoteDO{}).Where("poll_id = ? AND user_id = ?", pollId, userId).
+		Order("option_id").Pluck("option_id", &optionIds).Error
+	return optionIds, err
+}
+
+// VotePoll 投票: 校验投票未结束、选项属于该投票且数量合法, 选票的唯一索引保证每人只能投一次
+func (r *PollRepository) VotePoll(pollId int64, user *model.UserDTO, optionIds []int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var poll model.PostPollDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&poll, pollId).Error; err != nil {
+			return err
+		}
+		if poll.IsClosed(time.Now()) {
+			return ErrPollClosed
+		}
+		if len(optionIds) == 0 || (!poll.MultipleChoice && len(optionIds) > 1) ||
+			(poll.MaxChoices > 0 && len(optionIds) > poll.MaxChoices) {
+			return ErrPollInvalidChoice
+		}
+		var count int64
+		if err := tx.Model(&model.PostPollOptionDO{}).Where("poll_id = ? AND id IN ?", pollId, optionIds).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(optionIds)) {
+			return ErrPollInvalidChoice
+		}
+
+		ballot := &model.PostPollBallotDO{PollId: pollId, UserId: user.Id, CreatedAt: time.Now()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(ballot)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPollVoted
+		}
+		votes := make([]*model.PostPollVoteDO, len(optionIds))
+		for i, optionId := range optionIds {
+			votes[i] = &model.PostPollVoteDO{PollId: pollId, OptionId: optionId, UserId: user.Id, UserName: user.Name}
+		}
+		if err := tx.Create(votes).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.PostPollOptionDO{}).Where("id IN ?", optionIds).
+			UpdateColumn("vote_count", gorm.Expr("vote_count + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&poll).UpdateColumn("voter_count", gorm.Expr("voter_count + 1")).Error
+	})
+}
+
+// ClosePoll 提前结束投票, 已结束的投票返回ErrPollClosed
+func (r *PollRepository) ClosePoll(pollId int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var poll model.PostPollDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&poll, pollId).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if poll.IsClosed(now) {
+			return ErrPollClosed
+		}
+		return tx.Model(&poll).Update("closed_at", now).Error
+	})
+}
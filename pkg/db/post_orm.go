@@ -1,11 +1,22 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"sort"
 	"time"
+	"yujian-backend/pkg/log"
 	"yujian-backend/pkg/model"
+	"yujian-backend/pkg/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPostDeleted    = errors.New("post has been deleted")
+	ErrCommentDeleted = errors.New("comment has been deleted")
 )
 
 var postRepository PostRepository
@@ -40,22 +51,62 @@ func (r *PostRepository) GetPostById(ids []int64) ([]*model.PostDTO, error) {
 		if err != nil {
 			return nil, err
 		}
+		postDTOs[i] = post.TransformToDTO(userDTO)
+	}
 
-		comments, err := r.GetPostCommentsByPostId(post.Id)
-		if err != nil {
-			return nil, err
-		}
+	return postDTOs, nil
+}
 
-		postDTOs[i] = post.TransformToDTO(userDTO, comments)
+// GetPostClubId 获取帖子所属的读书会, 公开帖子为0
+func (r *PostRepository) GetPostClubId(postId int64) (int64, error) {
+	var post model.PostDO
+	if err := r.DB.Select("id, club_id").First(&post, postId).Error; err != nil {
+		return 0, err
 	}
+	return post.ClubId, nil
+}
 
-	return postDTOs, nil
+// GetPostDOById 获取帖子的数据库记录, 不加载作者和评论
+func (r *PostRepository) GetPostDOById(postId int64) (*model.PostDO, error) {
+	var post model.PostDO
+	if err := r.DB.First(&post, postId).Error; err != nil {
+		return nil, err
+	}
+	return &post, nil
 }
 
-// UpdatePost 更新帖子
-func (r *PostRepository) UpdatePost(postDTO *model.PostDTO) error {
-	postDO := postDTO.TransformToDO()
-	return r.DB.Save(postDO).Error
+// GetPostDOsByIds 批量获取帖子的数据库记录, 按帖子ID索引
+func (r *PostRepository) GetPostDOsByIds(ids []int64) (map[int64]*model.PostDO, error) {
+	posts := make(map[int64]*model.PostDO, len(ids))
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	var postDOs []*model.PostDO
+	if err := r.DB.Where("id IN ?", ids).Find(&postDOs).Error; err != nil {
+		return nil, err
+	}
+	for _, post := range postDOs {
+		posts[post.Id] = post
+	}
+	return posts, nil
+}
+
+// ListLivePostsAfter 按ID顺序分批获取未删除的帖子, 用于重建索引
+func (r *PostRepository) ListLivePostsAfter(afterId int64, limit int) ([]*model.PostDO, error) {
+	var posts []*model.PostDO
+	if err := r.DB.Where("id > ? AND deleted = ?", afterId, false).Order("id").Limit(limit).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// ListLiveCommentsAfter 按ID顺序分批获取未删除的评论, 用于重建索引
+func (r *PostRepository) ListLiveCommentsAfter(afterId int64, limit int) ([]*model.PostCommentDO, error) {
+	var comments []*model.PostCommentDO
+	if err := r.DB.Where("id > ? AND deleted = ?", afterId, false).Order("id").Limit(limit).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
 }
 
 // DeletePost 删除帖子
@@ -63,10 +114,107 @@ func (r *PostRepository) DeletePost(id int64) error {
 	return r.DB.Delete(&model.PostDO{}, id).Error
 }
 
+// EditPost 编辑帖子, 旧的标题和正文先写入历史版本
+// syncContent在同一事务中最后执行, 用于同步ES中的正文, 失败时整体回滚
+func (r *PostRepository) EditPost(edit *model.PostDTO, revision *model.PostRevisionDO, syncContent func() error) (*model.PostDO, error) {
+	var post model.PostDO
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockLivePost(tx, edit.Id, &post); err != nil {
+			return err
+		}
+		revision.Action = model.PostRevisionEdit
+		if err := addPostRevision(tx, &post, revision); err != nil {
+			return err
+		}
+		now := time.Now()
+		post.Title = edit.Title
+		post.Spoiler = edit.Spoiler
+		post.SpoilerSpans = edit.TransformToDO().SpoilerSpans
+		post.Preview = edit.Preview
+		post.EditedAt = &now
+		if err := tx.Model(&post).Updates(map[string]interface{}{
+			"title":         post.Title,
+			"spoiler":       post.Spoiler,
+			"spoiler_spans": post.SpoilerSpans,
+			"preview":       post.Preview,
+			"edited_at":     post.EditedAt,
+		}).Error; err != nil {
+			return err
+		}
+		return syncContent()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// SoftDeletePost 软删除帖子, 保留占位和评论, 删除前的内容写入历史版本
+// syncContent在同一事务中最后执行, 用于删除ES中的正文, 失败时整体回滚
+func (r *PostRepository) SoftDeletePost(postId int64, revision *model.PostRevisionDO, syncContent func() error) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var post model.PostDO
+		if err := lockLivePost(tx, postId, &post); err != nil {
+			return err
+		}
+		revision.Action = model.PostRevisionDelete
+		if err := addPostRevision(tx, &post, revision); err != nil {
+			return err
+		}
+		if err := tx.Model(&post).Updates(map[string]interface{}{
+			"deleted":    true,
+			"deleted_at": time.Now(),
+			"deleted_by": revision.EditorId,
+		}).Error; err != nil {
+			return err
+		}
+		return syncContent()
+	})
+}
+
+// ListPostRevisions 获取帖子的历史版本, 新的在前
+func (r *PostRepository) ListPostRevisions(postId int64) ([]*model.PostRevisionDTO, error) {
+	var revisions []model.PostRevisionDO
+	if err := r.DB.Where("post_id = ?", postId).Order("version DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	revisionDTOs := make([]*model.PostRevisionDTO, len(revisions))
+	for i := range revisions {
+		revisionDTOs[i] = revisions[i].Transfer()
+	}
+	return revisionDTOs, nil
+}
+
+// lockLivePost 锁定未删除的帖子
+func lockLivePost(tx *gorm.DB, postId int64, post *model.PostDO) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(post, postId).Error; err != nil {
+		return err
+	}
+	if post.Deleted {
+		return ErrPostDeleted
+	}
+	return nil
+}
+
+// addPostRevision 以帖子当前的标题和剧透信息写入一条历史版本, 正文由调用方从ES读取后填入
+func addPostRevision(tx *gorm.DB, post *model.PostDO, revision *model.PostRevisionDO) error {
+	var count int64
+	if err := tx.Model(&model.PostRevisionDO{}).Where("post_id = ?", post.Id).Count(&count).Error; err != nil {
+		return err
+	}
+	revision.PostId = post.Id
+	revision.Version = int(count) + 1
+	revision.Title = post.Title
+	revision.Spoiler = post.Spoiler
+	revision.SpoilerSpans = post.SpoilerSpans
+	revision.CreatedAt = time.Now()
+	return tx.Create(revision).Error
+}
+
 // ListPosts 获取帖子列表
 func (r *PostRepository) ListPosts(offset, limit int) ([]*model.PostDTO, error) {
 	var posts []model.PostDO
-	if err := r.DB.Offset(offset).Limit(limit).Find(&posts).Error; err != nil {
+	if err := r.DB.Where("deleted = ?", false).Offset(offset).Limit(limit).Find(&posts).Error; err != nil {
 		return nil, err
 	}
 
@@ -76,30 +224,64 @@ func (r *PostRepository) ListPosts(offset, limit int) ([]*model.PostDTO, error)
 		if err != nil {
 			return nil, err
 		}
-
-		comments, err := r.GetPostCommentsByPostId(post.Id)
-		if err != nil {
-			return nil, err
-		}
-
-		postDTOs[i] = post.TransformToDTO(userDTO, comments)
+		postDTOs[i] = post.TransformToDTO(userDTO)
 	}
 	return postDTOs, nil
 }
 
-// GetPostCommentsByPostId 根据帖子id获取帖子评论
-func (r *PostRepository) GetPostCommentsByPostId(postId int64) ([]*model.PostCommentDTO, error) {
-	var comments []model.PostCommentDO
-	if err := r.DB.Where("post_id = ?", postId).Find(&comments).Error; err != nil {
+// GetPostCommentById 根据ID获取帖子评论
+func (r *PostRepository) GetPostCommentById(id int64) (*model.PostCommentDO, error) {
+	var comment model.PostCommentDO
+	if err := r.DB.First(&comment, id).Error; err != nil {
 		return nil, err
 	}
+	return &comment, nil
+}
 
-	// 将PostCommentDO转换为PostCommentDTO
-	postCommentDTOs := make([]*model.PostCommentDTO, len(comments))
-	for i, comment := range comments {
-		postCommentDTOs[i] = comment.TransformToDTO()
+// ListPostComments 按顶层评论分页获取帖子的评论, 每条顶层评论带上全部子回复; 返回顶层评论总数
+func (r *PostRepository) ListPostComments(postId int64, sortBy string, page, pageSize int) ([]*model.PostCommentDTO, int64, error) {
+	var roots []*model.PostCommentDO
+	var total int64
+	order := "id"
+	switch sortBy {
+	case model.PostCommentSortNewest:
+		order = "id DESC"
+	case model.PostCommentSortTop:
+		order = "like_count DESC, id"
 	}
-	return postCommentDTOs, nil
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.PostCommentDO{}).Where("post_id = ? AND root_id = 0", postId).
+		Count(&total).Order(order).Offset(offset).Limit(pageSize).Find(&roots).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(roots) == 0 {
+		return []*model.PostCommentDTO{}, total, nil
+	}
+
+	rootIds := make([]int64, len(roots))
+	for i, root := range roots {
+		rootIds[i] = root.Id
+	}
+	var children []*model.PostCommentDO
+	if err := r.DB.Where("post_id = ? AND root_id IN ?", postId, rootIds).Order("id").Find(&children).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 顶层评论保持查询的顺序, 子回复按ID升序, 父评论一定先于子回复出现
+	nodes := make(map[int64]*model.PostCommentDTO, len(roots)+len(children))
+	comments := make([]*model.PostCommentDTO, len(roots))
+	for i, root := range roots {
+		comments[i] = root.TransformToDTO()
+		nodes[root.Id] = comments[i]
+	}
+	for _, child := range children {
+		node := child.TransformToDTO()
+		nodes[child.Id] = node
+		if parent, ok := nodes[child.ParentId]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+	return comments, total, nil
 }
 
 // BatchGetPostCommentById 批量获取帖子评论
@@ -118,13 +300,20 @@ func (r *PostRepository) BatchGetPostCommentById(ids []int64) ([]*model.PostComm
 }
 
 // GetPostByTimeLine 根据时间范围获取帖子
-func (r *PostRepository) GetPostByTimeLine(startTime time.Time, endTime time.Time, category string, page int, pageSize int) ([]*model.PostDTO, int64, error) {
+// clubId大于0时只查该读书会的帖子, 否则查公开帖子和visibleClubIds中读书会的帖子
+func (r *PostRepository) GetPostByTimeLine(startTime time.Time, endTime time.Time, category string, clubId int64, visibleClubIds []int64, page int, pageSize int) ([]*model.PostDTO, int64, error) {
 	var posts []*model.PostDO
 	var total int64
 
+	query := r.DB.Model(&model.PostDO{}).Where("deleted = ? AND edit_time BETWEEN ? AND ?", false, startTime, endTime)
+	if clubId > 0 {
+		query = query.Where("club_id = ?", clubId)
+	} else {
+		query = visibleClubScope(query, visibleClubIds)
+	}
+
 	offset := (page - 1) * pageSize
-	if err := r.DB.Model(&model.PostDO{}).Where("edit_time BETWEEN ? AND ?", startTime, endTime).
-		Count(&total).Order("edit_time DESC").Offset(offset).Limit(pageSize).Find(&posts).Error; err != nil {
+	if err := query.Count(&total).Order("edit_time DESC").Offset(offset).Limit(pageSize).Find(&posts).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -135,12 +324,7 @@ func (r *PostRepository) GetPostByTimeLine(startTime time.Time, endTime time.Tim
 		if err != nil {
 			return nil, 0, err
 		}
-
-		comments, err := r.GetPostCommentsByPostId(post.Id)
-		if err != nil {
-			return nil, 0, err
-		}
-		postDTOs[i] = post.TransformToDTO(userDTO, comments)
+		postDTOs[i] = post.TransformToDTO(userDTO)
 	}
 
 	// go代码里再排序一次
@@ -151,14 +335,15 @@ func (r *PostRepository) GetPostByTimeLine(startTime time.Time, endTime time.Tim
 	return postDTOs, total, nil
 }
 
-// GetPostByUserId 根据用户ID获取帖子
-func (r *PostRepository) GetPostByUserId(userId int64, page int, pageSize int) ([]*model.PostDTO, int64, error) {
+// GetPostByUserId 根据用户ID获取帖子, 只包含公开帖子和visibleClubIds中读书会的帖子
+func (r *PostRepository) GetPostByUserId(userId int64, visibleClubIds []int64, page int, pageSize int) ([]*model.PostDTO, int64, error) {
 	var posts []*model.PostDO
 	var total int64
 
+	query := visibleClubScope(r.DB.Model(&model.PostDO{}).Where("author_id = ? AND deleted = ?", userId, false), visibleClubIds)
+
 	offset := (page - 1) * pageSize
-	if err := r.DB.Model(&model.PostDO{}).Where("author_id = ?", userId).
-		Count(&total).Order("edit_time DESC").Offset(offset).Limit(pageSize).Find(&posts).Error; err != nil {
+	if err := query.Count(&total).Order("edit_time DESC").Offset(offset).Limit(pageSize).Find(&posts).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -168,32 +353,445 @@ func (r *PostRepository) GetPostByUserId(userId int64, page int, pageSize int) (
 		if err != nil {
 			return nil, 0, err
 		}
+		postDTOs[i] = post.TransformToDTO(userDTO)
+	}
 
-		comments, err := r.GetPostCommentsByPostId(post.Id)
-		if err != nil {
-			return nil, 0, err
-		}
-		postDTOs[i] = post.TransformToDTO(userDTO, comments)
+	return postDTOs, total, nil
+}
+
+// visibleClubScope 限定为公开帖子或指定读书会内的帖子
+func visibleClubScope(query *gorm.DB, clubIds []int64) *gorm.DB {
+	if len(clubIds) == 0 {
+		return query.Where("club_id = 0")
 	}
+	return query.Where("(club_id = 0 OR club_id IN ?)", clubIds)
+}
 
+// ListPostsByHashtag 话题页: 按最近一次在正文或评论中使用该话题的时间倒序分页获取帖子, 只包含visibleClubIds中读书会的帖子
+func (r *PostRepository) ListPostsByHashtag(tagId int64, visibleClubIds []int64, page, pageSize int) ([]*model.PostDTO, int64, error) {
+	tagged := r.DB.Model(&model.PostHashtagDO{}).Select("post_id, MAX(created_at) AS tagged_at").
+		Where("tag_id = ?", tagId).Group("post_id")
+	query := visibleClubScope(r.DB.Model(&model.PostDO{}).Joins("JOIN (?) AS tagged ON tagged.post_id = post.id", tagged).
+		Where("deleted = ?", false), visibleClubIds)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var posts []*model.PostDO
+	if err := query.Select("post.*").Order("tagged.tagged_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+	postDTOs := make([]*model.PostDTO, len(posts))
+	for i, post := range posts {
+		postDTOs[i] = post.TransformToDTO(&model.UserDTO{Id: post.AuthorId, Name: post.AuthorName})
+	}
 	return postDTOs, total, nil
 }
 
-// CreatePostComment 创建帖子评论
-func (r *PostRepository) CreatePostComment(user *model.UserDTO, postId int64, content string) error {
-	comment := model.PostCommentDO{
-		PostId:         postId,
-		AuthorId:       user.Id,
-		AuthorName:     user.Name,
-		EditTime:       time.Now(),
-		Content:        content,
-		LikeUserIds:    "",
-		DislikeUserIds: "",
+// CreatePostComment 创建帖子评论, 同时累加帖子的评论数
+func (r *PostRepository) CreatePostComment(comment *model.PostCommentDO) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.PostDO{}).Where("id = ?", comment.PostId).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error
+	})
+}
+
+// EditPostComment 修改评论内容并记录修改时间, 已删除的评论不能修改
+func (r *PostRepository) EditPostComment(comment *model.PostCommentDO, content, contentHTML string) error {
+	now := time.Now()
+	result := r.DB.Model(comment).Where("deleted = ?", false).Updates(map[string]interface{}{
+		"content":      content,
+		"content_html": contentHTML,
+		"edited_at":    now,
+	})
+	if result.Error != nil {
+		return result.Error
 	}
-	return r.DB.Create(&comment).Error
+	if result.RowsAffected == 0 {
+		return ErrCommentDeleted
+	}
+	comment.Content = content
+	comment.ContentHTML = contentHTML
+	comment.EditedAt = &now
+	return nil
+}
+
+// DeletePostComment 删除评论: 有回复时清空内容保留占位, 否则直接删除并清理只剩占位的父评论
+func (r *PostRepository) DeletePostComment(commentId, operatorId int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var comment model.PostCommentDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&comment, commentId).Error; err != nil {
+			return err
+		}
+		if comment.Deleted {
+			return ErrCommentDeleted
+		}
+		var replies int64
+		if err := tx.Model(&model.PostCommentDO{}).Where("parent_id = ?", comment.Id).Count(&replies).Error; err != nil {
+			return err
+		}
+		if replies > 0 {
+			return tx.Model(&comment).Updates(map[string]interface{}{
+				"content":      "",
+				"content_html": "",
+				"deleted":      true,
+				"deleted_at":   time.Now(),
+				"deleted_by":   operatorId,
+			}).Error
+		}
+		return removePostComments(tx, &comment, []int64{comment.Id})
+	})
 }
 
-func (r *PostRepository) UpdateComment(comment *model.PostCommentDTO) error {
-	do := comment.TransformToDO()
-	return r.DB.Save(do).Error
+// HardDeletePostComment 物理删除评论及其全部回复, 用于法律要求的下架; 返回删除的评论ID
+func (r *PostRepository) HardDeletePostComment(commentId int64) ([]int64, error) {
+	var removed []int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var comment model.PostCommentDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&comment, commentId).Error; err != nil {
+			return err
+		}
+
+		// 从所在的顶层评论下找出整棵子树
+		rootId := comment.RootId
+		if rootId == 0 {
+			rootId = comment.Id
+		}
+		var thread []*model.PostCommentDO
+		if err := tx.Select("id, parent_id").Where("post_id = ? AND root_id = ?", comment.PostId, rootId).
+			Order("id").Find(&thread).Error; err != nil {
+			return err
+		}
+		subtree := map[int64]bool{comment.Id: true}
+		ids := []int64{comment.Id}
+		for _, reply := range thread {
+			if subtree[reply.ParentId] {
+				subtree[reply.Id] = true
+				ids = append(ids, reply.Id)
+			}
+		}
+		removed = ids
+		return removePostComments(tx, &comment, ids)
+	})
+	return removed, err
+}
+
+// removePostComments 删除comment所在子树中的ids并扣减帖子评论数, 再向上清理没有回复的占位评论
+func removePostComments(tx *gorm.DB, comment *model.PostCommentDO, ids []int64) error {
+	if err := tx.Where("id IN ?", ids).Delete(&model.PostCommentDO{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("comment_id IN ?", ids).Delete(&model.PostCommentVoteDO{}).Error; err != nil {
+		return err
+	}
+	removed := len(ids)
+	for parentId := comment.ParentId; parentId > 0; {
+		var parent model.PostCommentDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&parent, parentId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return err
+		}
+		if !parent.Deleted {
+			break
+		}
+		var replies int64
+		if err := tx.Model(&model.PostCommentDO{}).Where("parent_id = ?", parent.Id).Count(&replies).Error; err != nil {
+			return err
+		}
+		if replies > 0 {
+			break
+		}
+		if err := tx.Delete(&parent).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", parent.Id).Delete(&model.PostCommentVoteDO{}).Error; err != nil {
+			return err
+		}
+		removed++
+		parentId = parent.ParentId
+	}
+	return tx.Model(&model.PostDO{}).Where("id = ?", comment.PostId).
+		UpdateColumn("comment_count", gorm.Expr("GREATEST(comment_count - ?, 0)", removed)).Error
+}
+
+// BackfillPostCommentCounts 补算历史帖子的评论数
+func (r *PostRepository) BackfillPostCommentCounts() error {
+	return r.DB.Exec("UPDATE post SET comment_count = " +
+		"(SELECT COUNT(*) FROM post_comment WHERE post_comment.post_id = post.id) WHERE comment_count = 0").Error
+}
+
+// BackfillPostCommentHTML 为历史评论渲染HTML, 可重复执行
+func (r *PostRepository) BackfillPostCommentHTML() error {
+	for afterId := int64(0); ; {
+		var comments []*model.PostCommentDO
+		if err := r.DB.Select("id", "content").Where("id > ? AND (content_html = '' OR content_html IS NULL)", afterId).
+			Where("deleted = ? AND content <> ''", false).Order("id").Limit(500).Find(&comments).Error; err != nil {
+			return err
+		}
+		if len(comments) == 0 {
+			return nil
+		}
+		for _, comment := range comments {
+			afterId = comment.Id
+			if err := r.DB.Model(comment).UpdateColumn("content_html", utils.RenderMarkdown(comment.Content)).Error; err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// UpdatePostPreview 更新帖子的摘要, 用于补齐历史帖子
+func (r *PostRepository) UpdatePostPreview(postId int64, preview string) error {
+	return r.DB.Model(&model.PostDO{}).Where("id = ?", postId).UpdateColumn("preview", preview).Error
+}
+
+// VotePost 赞/踩帖子: 没投过则新增, 再次投同一票则取消, 投另一票则改票; 返回更新后的帖子和操作后的投票
+func (r *PostRepository) VotePost(postId, userId int64, value int) (*model.PostDO, int, error) {
+	var post model.PostDO
+	myVote := 0
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockLivePost(tx, postId, &post); err != nil {
+			return err
+		}
+		var votes []*model.PostVoteDO
+		if err := tx.Where("post_id = ? AND user_id = ?", postId, userId).Limit(1).Find(&votes).Error; err != nil {
+			return err
+		}
+
+		current := 0
+		if len(votes) > 0 {
+			current = votes[0].Value
+		}
+		var likeDelta, dislikeDelta int64
+		myVote, likeDelta, dislikeDelta = toggleVote(current, value)
+		switch {
+		case current == 0:
+			vote := &model.PostVoteDO{PostId: postId, UserId: userId, Value: value, CreatedAt: time.Now()}
+			if err := tx.Create(vote).Error; err != nil {
+				return err
+			}
+		case myVote == 0:
+			if err := tx.Delete(votes[0]).Error; err != nil {
+				return err
+			}
+		default:
+			if err := tx.Model(votes[0]).Update("value", myVote).Error; err != nil {
+				return err
+			}
+		}
+		if err := addVoteCounts(tx.Model(&post), likeDelta, dislikeDelta); err != nil {
+			return err
+		}
+		return tx.First(&post, postId).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return &post, myVote, nil
+}
+
+// VotePostComment 赞/踩帖子评论, 语义同VotePost; 已删除的评论不能投票
+func (r *PostRepository) VotePostComment(commentId, userId int64, value int) (*model.PostCommentDO, int, error) {
+	var comment model.PostCommentDO
+	myVote := 0
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&comment, commentId).Error; err != nil {
+			return err
+		}
+		if comment.Deleted {
+			return ErrCommentDeleted
+		}
+		var votes []*model.PostCommentVoteDO
+		if err := tx.Where("comment_id = ? AND user_id = ?", commentId, userId).Limit(1).Find(&votes).Error; err != nil {
+			return err
+		}
+
+		current := 0
+		if len(votes) > 0 {
+			current = votes[0].Value
+		}
+		var likeDelta, dislikeDelta int64
+		myVote, likeDelta, dislikeDelta = toggleVote(current, value)
+		switch {
+		case current == 0:
+			vote := &model.PostCommentVoteDO{CommentId: commentId, UserId: userId, Value: value, CreatedAt: time.Now()}
+			if err := tx.Create(vote).Error; err != nil {
+				return err
+			}
+		case myVote == 0:
+			if err := tx.Delete(votes[0]).Error; err != nil {
+				return err
+			}
+		default:
+			if err := tx.Model(votes[0]).Update("value", myVote).Error; err != nil {
+				return err
+			}
+		}
+		if err := addVoteCounts(tx.Model(&comment), likeDelta, dislikeDelta); err != nil {
+			return err
+		}
+		return tx.First(&comment, commentId).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return &comment, myVote, nil
+}
+
+// GetPostVotes 获取用户对一批帖子的投票, 按帖子ID索引, 未投票的不在结果中
+func (r *PostRepository) GetPostVotes(userId int64, postIds []int64) (map[int64]int, error) {
+	votes := make(map[int64]int, len(postIds))
+	if len(postIds) == 0 {
+		return votes, nil
+	}
+	var voteDOs []*model.PostVoteDO
+	if err := r.DB.Where("user_id = ? AND post_id IN ?", userId, postIds).Find(&voteDOs).Error; err != nil {
+		return nil, err
+	}
+	for _, vote := range voteDOs {
+		votes[vote.PostId] = vote.Value
+	}
+	return votes, nil
+}
+
+// GetPostCommentVotes 获取用户对一批评论的投票, 按评论ID索引, 未投票的不在结果中
+func (r *PostRepository) GetPostCommentVotes(userId int64, commentIds []int64) (map[int64]int, error) {
+	votes := make(map[int64]int, len(commentIds))
+	if len(commentIds) == 0 {
+		return votes, nil
+	}
+	var voteDOs []*model.PostCommentVoteDO
+	if err := r.DB.Where("user_id = ? AND comment_id IN ?", userId, commentIds).Find(&voteDOs).Error; err != nil {
+		return nil, err
+	}
+	for _, vote := range voteDOs {
+		votes[vote.CommentId] = vote.Value
+	}
+	return votes, nil
+}
+
+// toggleVote 计算投票后的结果: 再次投同一票为取消, 投另一票为改票; 返回操作后的投票和赞/踩计数的变化
+func toggleVote(current, value int) (int, int64, int64) {
+	var likeDelta, dislikeDelta int64
+	count := func(v int, delta int64) {
+		if v == model.PostVoteLike {
+			likeDelta += delta
+		} else {
+			dislikeDelta += delta
+		}
+	}
+	if current != 0 {
+		count(current, -1)
+	}
+	if current == value {
+		return 0, likeDelta, dislikeDelta
+	}
+	count(value, 1)
+	return value, likeDelta, dislikeDelta
+}
+
+// addVoteCounts 原子地累加赞/踩计数
+func addVoteCounts(query *gorm.DB, likeDelta, dislikeDelta int64) error {
+	return query.Updates(map[string]interface{}{
+		"like_count":    gorm.Expr("like_count + ?", likeDelta),
+		"dislike_count": gorm.Expr("dislike_count + ?", dislikeDelta),
+	}).Error
+}
+
+// MigrateLegacyVotes 将帖子和评论中JSON数组形式的赞踩用户迁移到投票表并重算计数, 完成后删除旧列
+// 同一用户重复出现只算一票, 同时赞和踩的按赞处理; 中途失败可重复执行
+func (r *PostRepository) MigrateLegacyVotes() error {
+	migrator := r.DB.Migrator()
+	if migrator.HasColumn(&model.PostDO{}, "like_user_ids") {
+		var rows []legacyVoteRow
+		if err := r.DB.Table("post").Select("id, like_user_ids AS likes, unlike_user_ids AS dislikes").Scan(&rows).Error; err != nil {
+			return err
+		}
+		votes := make([]*model.PostVoteDO, 0)
+		for _, row := range rows {
+			for userId, value := range row.votes() {
+				votes = append(votes, &model.PostVoteDO{PostId: row.Id, UserId: userId, Value: value, CreatedAt: time.Now()})
+			}
+		}
+		if len(votes) > 0 {
+			if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(votes, 500).Error; err != nil {
+				return err
+			}
+		}
+		if err := r.DB.Exec("UPDATE post SET " +
+			"like_count = (SELECT COUNT(*) FROM post_vote WHERE post_vote.post_id = post.id AND value = 1), " +
+			"dislike_count = (SELECT COUNT(*) FROM post_vote WHERE post_vote.post_id = post.id AND value = -1)").Error; err != nil {
+			return err
+		}
+		if err := migrator.DropColumn(&model.PostDO{}, "like_user_ids"); err != nil {
+			return err
+		}
+		if err := migrator.DropColumn(&model.PostDO{}, "unlike_user_ids"); err != nil {
+			return err
+		}
+		log.GetLogger().Infof("migrated %d legacy post votes", len(votes))
+	}
+
+	if migrator.HasColumn(&model.PostCommentDO{}, "like_user_ids") {
+		var rows []legacyVoteRow
+		if err := r.DB.Table("post_comment").Select("id, like_user_ids AS likes, dislike_user_ids AS dislikes").Scan(&rows).Error; err != nil {
+			return err
+		}
+		votes := make([]*model.PostCommentVoteDO, 0)
+		for _, row := range rows {
+			for userId, value := range row.votes() {
+				votes = append(votes, &model.PostCommentVoteDO{CommentId: row.Id, UserId: userId, Value: value, CreatedAt: time.Now()})
+			}
+		}
+		if len(votes) > 0 {
+			if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(votes, 500).Error; err != nil {
+				return err
+			}
+		}
+		if err := r.DB.Exec("UPDATE post_comment SET " +
+			"like_count = (SELECT COUNT(*) FROM post_comment_vote WHERE post_comment_vote.comment_id = post_comment.id AND value = 1), " +
+			"dislike_count = (SELECT COUNT(*) FROM post_comment_vote WHERE post_comment_vote.comment_id = post_comment.id AND value = -1)").Error; err != nil {
+			return err
+		}
+		if err := migrator.DropColumn(&model.PostCommentDO{}, "like_user_ids"); err != nil {
+			return err
+		}
+		if err := migrator.DropColumn(&model.PostCommentDO{}, "dislike_user_ids"); err != nil {
+			return err
+		}
+		log.GetLogger().Infof("migrated %d legacy comment votes", len(votes))
+	}
+	return nil
+}
+
+// legacyVoteRow 旧数据中JSON数组形式的赞踩用户
+type legacyVoteRow struct {
+	Id       int64
+	Likes    sql.NullString
+	Dislikes sql.NullString
+}
+
+// votes 解析出每个用户的投票
+func (row legacyVoteRow) votes() map[int64]int {
+	votes := make(map[int64]int)
+	var ids []int64
+	if row.Dislikes.Valid && json.Unmarshal([]byte(row.Dislikes.String), &ids) == nil {
+		for _, id := range ids {
+			votes[id] = model.PostVoteDislike
+		}
+	}
+	ids = nil
+	if row.Likes.Valid && json.Unmarshal([]byte(row.Likes.String), &ids) == nil {
+		for _, id := range ids {
+			votes[id] = model.PostVoteLike
+		}
+	}
+	return votes
 }
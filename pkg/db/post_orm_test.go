@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+
+	"yujian-backend/pkg/model"
+)
+
+func TestToggleVote(t *testing.T) {
+	like, dislike := model.PostVoteLike, model.PostVoteDislike
+	tests := []struct {
+		name                    string
+		current, value          int
+		want                    int
+		likeDelta, dislikeDelta int64
+	}{
+		{"like", 0, like, like, 1, 0},
+		{"dislike", 0, dislike, dislike, 0, 1},
+		{"cancel like", like, like, 0, -1, 0},
+		{"cancel dislike", dislike, dislike, 0, 0, -1},
+		{"like to dislike", like, dislike, dislike, -1, 1},
+		{"dislike to like", dislike, like, like, 1, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, likeDelta, dislikeDelta := toggleVote(tt.current, tt.value)
+			if got != tt.want || likeDelta != tt.likeDelta || dislikeDelta != tt.dislikeDelta {
+				t.Errorf("toggleVote(%d, %d) = %d, %d, %d, want %d, %d, %d", tt.current, tt.value,
+					got, likeDelta, dislikeDelta, tt.want, tt.likeDelta, tt.dislikeDelta)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var progressRepository ProgressRepository
+
+type ProgressRepository struct {
+	DB *gorm.DB
+}
+
+func GetProgressRepository() *ProgressRepository {
+	return &progressRepository
+}
+
+// LogProgress 追加一条阅读进度, 根据上一条记录计算新读页数, 并把图书放入在读/读过书架
+func (r *ProgressRepository) LogProgress(userId int64, req *model.LogProgressRequest) (*model.ReadingProgressDO, error) {
+	if err := shelfRepository.EnsureBuiltinShelves(userId); err != nil {
+		return nil, err
+	}
+	var progress *model.ReadingProgressDO
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁住用户, 保证同一用户的进度记录按顺序计算增量
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model.UserDO{}, userId).Error; err != nil {
+			return err
+		}
+		var last model.ReadingProgressDO
+		err := tx.Where("user_id = ? AND book_id = ?", userId, req.BookId).Order("id DESC").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		progress = &model.ReadingProgressDO{
+			UserId:     userId,
+			BookId:     req.BookId,
+			Page:       req.Page,
+			TotalPages: req.TotalPages,
+			Percent:    req.Percent,
+			Finished:   req.Finished,
+			Note:       req.Note,
+			CreatedAt:  time.Now(),
+		}
+		if progress.TotalPages == 0 {
+			progress.TotalPages = last.TotalPages
+		}
+		if progress.TotalPages > 0 {
+			if progress.Page == 0 && progress.Percent > 0 {
+				progress.Page = int(math.Round(progress.Percent * float64(progress.TotalPages) / 100))
+			} else if progress.Percent == 0 {
+				progress.Percent = math.Min(100, float64(progress.Page)*100/float64(progress.TotalPages))
+			}
+		}
+		if progress.Percent >= 100 {
+			progress.Finished = true
+		}
+		if progress.Finished {
+			progress.Percent = 100
+			if progress.TotalPages > 0 {
+				progress.Page = progress.TotalPages
+			}
+		}
+		// 重读时页码会变小, 不计为负数
+		if last.Finished {
+			last.Page = 0
+		}
+		if progress.Page > last.Page {
+			progress.PagesRead = progress.Page - last.Page
+		}
+		if err = tx.Create(progress).Error; err != nil {
+			return err
+		}
+
+		kind := model.ShelfReading
+		if progress.Finished {
+			kind = model.ShelfRead
+		}
+		var shelf model.ShelfDO
+		if err = tx.Where("user_id = ? AND kind = ?", userId, kind).First(&shelf).Error; err != nil {
+			return err
+		}
+		return addToShelf(tx, &shelf, req.BookId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// ListBookProgress 获取用户某本书的全部进度记录, 按时间顺序
+func (r *ProgressRepository) ListBookProgress(userId, bookId int64) ([]*model.ReadingProgressDTO, error) {
+	var progress []*model.ReadingProgressDO
+	if err := r.DB.Where("user_id = ? AND book_id = ?", userId, bookId).Order("id").Find(&progress).Error; err != nil {
+		return nil, err
+	}
+	progressDTOs := make([]*model.ReadingProgressDTO, len(progress))
+	for i, p := range progress {
+		progressDTOs[i] = p.Transfer()
+	}
+	return progressDTOs, nil
+}
+
+// ListProgressBetween 获取用户在[from, to)内的进度记录, 按时间顺序
+func (r *ProgressRepository) ListProgressBetween(userId int64, from, to time.Time) ([]*model.ReadingProgressDO, error) {
+	var progress []*model.ReadingProgressDO
+	if err := r.DB.Where("user_id = ? AND created_at >= ? AND created_at < ?", userId, from, to).
+		Order("created_at, id").Find(&progress).Error; err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// ListUserScores 获取用户在[from, to)内所写书评的评分, 按图书ID索引
+func (r *ProgressRepository) ListUserScores(userId int64, from, to time.Time) (map[int64]float64, error) {
+	var comments []*model.BookCommentDO
+	if err := r.DB.Select("book_id, score").
+		Where("publisher_id = ? AND post_time >= ? AND post_time < ?", userId, from, to).
+		Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	scores := make(map[int64]float64, len(comments))
+	for _, comment := range comments {
+		scores[comment.BookId] = comment.Score
+	}
+	return scores, nil
+}
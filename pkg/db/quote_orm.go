@@ -0,0 +1,171 @@
+package db
+
+import (
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var quoteRepository QuoteRepository
+
+type QuoteRepository struct {
+	DB *gorm.DB
+}
+
+func GetQuoteRepository() *QuoteRepository {
+	return &quoteRepository
+}
+
+// CreateQuote 创建书摘
+func (r *QuoteRepository) CreateQuote(quote *model.QuoteDO) (int64, error) {
+	if err := r.DB.Create(quote).Error; err != nil {
+		return 0, err
+	}
+	return quote.Id, nil
+}
+
+// GetQuoteById 根据ID获取书摘
+func (r *QuoteRepository) GetQuoteById(id int64) (*model.QuoteDO, error) {
+	var quote model.QuoteDO
+	if err := r.DB.First(&quote, id).Error; err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// GetQuotesByIds 批量获取书摘, 按ids的顺序返回, 不存在的会被忽略
+func (r *QuoteRepository) GetQuotesByIds(ids []int64) ([]*model.QuoteDO, error) {
+	if len(ids) == 0 {
+		return []*model.QuoteDO{}, nil
+	}
+	var quotes []*model.QuoteDO
+	if err := r.DB.Where("id IN ?", ids).Find(&quotes).Error; err != nil {
+		return nil, err
+	}
+	quoteMap := make(map[int64]*model.QuoteDO, len(quotes))
+	for _, quote := range quotes {
+		quoteMap[quote.Id] = quote
+	}
+	result := make([]*model.QuoteDO, 0, len(quotes))
+	for _, id := range ids {
+		if quote, ok := quoteMap[id]; ok {
+			result = append(result, quote)
+		}
+	}
+	return result, nil
+}
+
+// DeleteQuote 删除书摘及其点赞和收藏
+func (r *QuoteRepository) DeleteQuote(id int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("quote_id = ?", id).Delete(&model.QuoteLikeDO{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("quote_id = ?", id).Delete(&model.QuoteBookmarkDO{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.QuoteDO{}, id).Error
+	})
+}
+
+// ListBookQuotes 分页获取图书的书摘, sort为latest时按时间倒序, 否则按热度
+func (r *QuoteRepository) ListBookQuotes(bookId int64, sort string, page, pageSize int) ([]*model.QuoteDO, int64, error) {
+	var quotes []*model.QuoteDO
+	var total int64
+	order := "like_count DESC, bookmark_count DESC, id DESC"
+	if sort == "latest" {
+		order = "id DESC"
+	}
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.QuoteDO{}).Where("book_id = ?", bookId).
+		Count(&total).Order(order).Offset(offset).Limit(pageSize).Find(&quotes).Error; err != nil {
+		return nil, 0, err
+	}
+	return quotes, total, nil
+}
+
+// ListBookmarkedQuotes 分页获取用户收藏的书摘, 按收藏时间倒序
+func (r *QuoteRepository) ListBookmarkedQuotes(userId int64, page, pageSize int) ([]*model.QuoteDO, int64, error) {
+	var quoteIds []int64
+	var total int64
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.QuoteBookmarkDO{}).Where("user_id = ?", userId).
+		Count(&total).Order("id DESC").Offset(offset).Limit(pageSize).Pluck("quote_id", &quoteIds).Error; err != nil {
+		return nil, 0, err
+	}
+	quotes, err := r.GetQuotesByIds(quoteIds)
+	if err != nil {
+		return nil, 0, err
+	}
+	return quotes, total, nil
+}
+
+// GetUserQuoteStates 获取用户在给定书摘中已点赞和已收藏的书摘ID集合
+func (r *QuoteRepository) GetUserQuoteStates(userId int64, quoteIds []int64) (liked, bookmarked map[int64]bool, err error) {
+	liked = make(map[int64]bool)
+	bookmarked = make(map[int64]bool)
+	if len(quoteIds) == 0 {
+		return liked, bookmarked, nil
+	}
+	var ids []int64
+	if err = r.DB.Model(&model.QuoteLikeDO{}).Where("user_id = ? AND quote_id IN ?", userId, quoteIds).
+		Pluck("quote_id", &ids).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, id := range ids {
+		liked[id] = true
+	}
+	ids = nil
+	if err = r.DB.Model(&model.QuoteBookmarkDO{}).Where("user_id = ? AND quote_id IN ?", userId, quoteIds).
+		Pluck("quote_id", &ids).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, id := range ids {
+		bookmarked[id] = true
+	}
+	return liked, bookmarked, nil
+}
+
+// Like 点赞书摘, 重复点赞不会重复计数
+func (r *QuoteRepository) Like(quoteId, userId int64) error {
+	return r.addMark(&model.QuoteLikeDO{QuoteId: quoteId, UserId: userId, CreatedAt: time.Now()}, quoteId, "like_count")
+}
+
+// Unlike 取消点赞
+func (r *QuoteRepository) Unlike(quoteId, userId int64) error {
+	return r.removeMark(&model.QuoteLikeDO{}, quoteId, userId, "like_count")
+}
+
+// Bookmark 收藏书摘, 重复收藏不会重复计数
+func (r *QuoteRepository) Bookmark(quoteId, userId int64) error {
+	return r.addMark(&model.QuoteBookmarkDO{QuoteId: quoteId, UserId: userId, CreatedAt: time.Now()}, quoteId, "bookmark_count")
+}
+
+// Unbookmark 取消收藏
+func (r *QuoteRepository) Unbookmark(quoteId, userId int64) error {
+	return r.removeMark(&model.QuoteBookmarkDO{}, quoteId, userId, "bookmark_count")
+}
+
+func (r *QuoteRepository) addMark(mark interface{}, quoteId int64, counter string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(mark)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&model.QuoteDO{}).Where("id = ?", quoteId).
+			UpdateColumn(counter, gorm.Expr(counter+" + 1")).Error
+	})
+}
+
+func (r *QuoteRepository) removeMark(mark interface{}, quoteId, userId int64, counter string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("quote_id = ? AND user_id = ?", quoteId, userId).Delete(mark)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&model.QuoteDO{}).Where("id = ? AND "+counter+" > 0", quoteId).
+			UpdateColumn(counter, gorm.Expr(counter+" - 1")).Error
+	})
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"errors"
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSeatConflict       = errors.New("seat is already booked in this period")
+	ErrUserBookingOverlap = errors.New("user already has a booking in this period")
+	ErrDailyLimitExceeded = errors.New("daily booking limit exceeded")
+)
+
+// 占用座位的预约状态
+var activeSeatBookingStatus = []string{model.SeatBookingBooked, model.SeatBookingCheckedIn}
+
+var seatRepository SeatRepository
+
+type SeatRepository struct {
+	DB *gorm.DB
+}
+
+func GetSeatRepository() *SeatRepository {
+	return &seatRepository
+}
+
+// 阅览室
+
+// CreateRoom 创建阅览室
+func (r *SeatRepository) CreateRoom(roomDTO *model.ReadingRoomDTO) (int64, error) {
+	roomDO := roomDTO.Transfer()
+	if err := r.DB.Create(roomDO).Error; err != nil {
+		return 0, err
+	}
+	return roomDO.Id, nil
+}
+
+// GetRoomById 根据ID获取阅览室
+func (r *SeatRepository) GetRoomById(id int64) (*model.ReadingRoomDTO, error) {
+	var room model.ReadingRoomDO
+	if err := r.DB.First(&room, id).Error; err != nil {
+		return nil, err
+	}
+	return room.Transfer(), nil
+}
+
+// ListRooms 获取全部阅览室
+func (r *SeatRepository) ListRooms() ([]*model.ReadingRoomDTO, error) {
+	var rooms []*model.ReadingRoomDO
+	if err := r.DB.Order("id").Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+	roomDTOs := make([]*model.ReadingRoomDTO, len(rooms))
+	for i, room := range rooms {
+		roomDTOs[i] = room.Transfer()
+	}
+	return roomDTOs, nil
+}
+
+// 座位
+
+// CreateSeat 创建座位
+func (r *SeatRepository) CreateSeat(seat *model.SeatDO) (int64, error) {
+	if err := r.DB.Create(seat).Error; err != nil {
+		return 0, err
+	}
+	return seat.Id, nil
+}
+
+// GetSeatById 根据ID获取座位(包含签到码)
+func (r *SeatRepository) GetSeatById(id int64) (*model.SeatDO, error) {
+	var seat model.SeatDO
+	if err := r.DB.First(&seat, id).Error; err != nil {
+		return nil, err
+	}
+	return &seat, nil
+}
+
+// ListSeatsByRoomId 获取阅览室下的全部座位
+func (r *SeatRepository) ListSeatsByRoomId(roomId int64) ([]*model.SeatDTO, error) {
+	var seats []*model.SeatDO
+	if err := r.DB.Where("room_id = ?", roomId).Order("code").Find(&seats).Error; err != nil {
+		return nil, err
+	}
+	seatDTOs := make([]*model.SeatDTO, len(seats))
+	for i, seat := range seats {
+		seatDTOs[i] = seat.Transfer()
+	}
+	return seatDTOs, nil
+}
+
+// 预约
+
+// CreateSeatBooking 创建座位预约
+// 在事务中锁住座位和用户,检查时间冲突以及用户当天的预约次数和时长限制
+func (r *SeatRepository) CreateSeatBooking(booking *model.SeatBookingDO, dayStart, dayEnd time.Time, maxBookings int, maxDuration time.Duration) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		locking := clause.Locking{Strength: "UPDATE"}
+		if err := tx.Clauses(locking).First(&model.SeatDO{}, booking.SeatId).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(locking).First(&model.UserDO{}, booking.UserId).Error; err != nil {
+			return err
+		}
+
+		// 座位时间冲突
+		var count int64
+		if err := tx.Model(&model.SeatBookingDO{}).
+			Where("seat_id = ? AND status IN ?", booking.SeatId, activeSeatBookingStatus).
+			Where("start_time < ? AND end_time > ?", booking.EndTime, booking.StartTime).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSeatConflict
+		}
+
+		// 同一用户同一时间只能占一个座位
+		if err := tx.Model(&model.SeatBookingDO{}).
+			Where("user_id = ? AND status IN ?", booking.UserId, activeSeatBookingStatus).
+			Where("start_time < ? AND end_time > ?", booking.EndTime, booking.StartTime).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserBookingOverlap
+		}
+
+		// 每日限额
+		var todays []*model.SeatBookingDO
+		if err := tx.Where("user_id = ? AND status IN ?", booking.UserId, activeSeatBookingStatus).
+			Where("start_time >= ? AND start_time < ?", dayStart, dayEnd).
+			Find(&todays).Error; err != nil {
+			return err
+		}
+		total := booking.EndTime.Sub(booking.StartTime)
+		for _, b := range todays {
+			total += b.EndTime.Sub(b.StartTime)
+		}
+		if (maxBookings > 0 && len(todays) >= maxBookings) || (maxDuration > 0 && total > maxDuration) {
+			return ErrDailyLimitExceeded
+		}
+
+		return tx.Create(booking).Error
+	})
+}
+
+// GetSeatBookingById 根据ID获取预约
+func (r *SeatRepository) GetSeatBookingById(id int64) (*model.SeatBookingDO, error) {
+	var booking model.SeatBookingDO
+	if err := r.DB.First(&booking, id).Error; err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// ListSeatBookingsByUserId 获取用户的预约,按开始时间倒序
+func (r *SeatRepository) ListSeatBookingsByUserId(userId int64, page, pageSize int) ([]*model.SeatBookingDTO, error) {
+	var bookings []*model.SeatBookingDO
+	offset := (page - 1) * pageSize
+	if err := r.DB.Where("user_id = ?", userId).Order("start_time DESC").
+		Offset(offset).Limit(pageSize).Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	bookingDTOs := make([]*model.SeatBookingDTO, len(bookings))
+	for i, booking := range bookings {
+		bookingDTOs[i] = booking.Transfer()
+	}
+	return bookingDTOs, nil
+}
+
+// ListActiveSeatBookings 获取若干座位在时间范围内仍占用的预约
+func (r *SeatRepository) ListActiveSeatBookings(seatIds []int64, start, end time.Time) ([]*model.SeatBookingDO, error) {
+	var bookings []*model.SeatBookingDO
+	if len(seatIds) == 0 {
+		return bookings, nil
+	}
+	if err := r.DB.Where("seat_id IN ? AND status IN ?", seatIds, activeSeatBookingStatus).
+		Where("start_time < ? AND end_time > ?", end, start).
+		Order("start_time").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+// TransitSeatBookingStatus 将预约从from状态迁移到to状态,返回是否迁移成功(并发下只有一个请求能成功)
+func (r *SeatRepository) TransitSeatBookingStatus(id int64, from, to string, updates map[string]interface{}) (bool, error) {
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["status"] = to
+	result := r.DB.Model(&model.SeatBookingDO{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseNoShowBookings 释放开始时间早于deadline仍未签到的预约
+func (r *SeatRepository) ReleaseNoShowBookings(deadline time.Time) (int64, error) {
+	result := r.DB.Model(&model.SeatBookingDO{}).
+		Where("status = ? AND start_time < ?", model.SeatBookingBooked, deadline).
+		Update("status", model.SeatBookingReleased)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,272 @@
+package db
+
+import (
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var shelfRepository ShelfRepository
+
+type ShelfRepository struct {
+	DB *gorm.DB
+}
+
+func GetShelfRepository() *ShelfRepository {
+	return &shelfRepository
+}
+
+// 状态书架类型
+var builtinShelfKinds = []string{model.ShelfWantToRead, model.ShelfReading, model.ShelfRead}
+
+// EnsureBuiltinShelves 确保用户拥有三个自带书架, 缺少时创建
+func (r *ShelfRepository) EnsureBuiltinShelves(userId int64) error {
+	var count int64
+	if err := r.DB.Model(&model.ShelfDO{}).Where("user_id = ? AND kind IN ?", userId, builtinShelfKinds).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == int64(len(builtinShelfKinds)) {
+		return nil
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁住用户, 避免并发请求重复创建
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model.UserDO{}, userId).Error; err != nil {
+			return err
+		}
+		var kinds []string
+		if err := tx.Model(&model.ShelfDO{}).Where("user_id = ? AND kind IN ?", userId, builtinShelfKinds).
+			Pluck("kind", &kinds).Error; err != nil {
+			return err
+		}
+		existing := make(map[string]bool)
+		for _, kind := range kinds {
+			existing[kind] = true
+		}
+		for _, builtin := range model.BuiltinShelves {
+			if existing[builtin.Kind] {
+				continue
+			}
+			shelf := &model.ShelfDO{UserId: userId, Name: builtin.Name, Kind: builtin.Kind, CreatedAt: time.Now()}
+			if err := tx.Create(shelf).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListShelves 获取用户的书架及书数, onlyPublic为true时只返回公开书架
+func (r *ShelfRepository) ListShelves(userId int64, onlyPublic bool) ([]*model.ShelfDTO, error) {
+	var shelves []*model.ShelfDO
+	query := r.DB.Where("user_id = ?", userId)
+	if onlyPublic {
+		query = query.Where("is_public = ?", true)
+	}
+	if err := query.Order("id").Find(&shelves).Error; err != nil {
+		return nil, err
+	}
+	if len(shelves) == 0 {
+		return []*model.ShelfDTO{}, nil
+	}
+
+	ids := make([]int64, len(shelves))
+	for i, shelf := range shelves {
+		ids[i] = shelf.Id
+	}
+	var rows []struct {
+		ShelfId int64
+		Count   int64
+	}
+	if err := r.DB.Model(&model.ShelfItemDO{}).Select("shelf_id, COUNT(*) AS count").
+		Where("shelf_id IN ?", ids).Group("shelf_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ShelfId] = row.Count
+	}
+
+	shelfDTOs := make([]*model.ShelfDTO, len(shelves))
+	for i, shelf := range shelves {
+		shelfDTOs[i] = shelf.Transfer()
+		shelfDTOs[i].BookCount = counts[shelf.Id]
+	}
+	return shelfDTOs, nil
+}
+
+// GetShelfById 根据ID获取书架
+func (r *ShelfRepository) GetShelfById(id int64) (*model.ShelfDO, error) {
+	var shelf model.ShelfDO
+	if err := r.DB.First(&shelf, id).Error; err != nil {
+		return nil, err
+	}
+	return &shelf, nil
+}
+
+// GetBuiltinShelf 获取用户某个类型的自带书架
+func (r *ShelfRepository) GetBuiltinShelf(userId int64, kind string) (*model.ShelfDO, error) {
+	if err := r.EnsureBuiltinShelves(userId); err != nil {
+		return nil, err
+	}
+	var shelf model.ShelfDO
+	if err := r.DB.Where("user_id = ? AND kind = ?", userId, kind).First(&shelf).Error; err != nil {
+		return nil, err
+	}
+	return &shelf, nil
+}
+
+// CreateShelf 创建自定义书单
+func (r *ShelfRepository) CreateShelf(shelf *model.ShelfDO) (int64, error) {
+	if err := r.DB.Create(shelf).Error; err != nil {
+		return 0, err
+	}
+	return shelf.Id, nil
+}
+
+// UpdateShelf 修改书架名称和可见性
+func (r *ShelfRepository) UpdateShelf(id int64, name string, isPublic bool) error {
+	return r.DB.Model(&model.ShelfDO{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"name": name, "is_public": isPublic}).Error
+}
+
+// DeleteShelf 删除书架及其中的图书
+func (r *ShelfRepository) DeleteShelf(id int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shelf_id = ?", id).Delete(&model.ShelfItemDO{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.ShelfDO{}, id).Error
+	})
+}
+
+// AddBook 将图书加入书架, 加入状态书架时会从其他状态书架移出
+func (r *ShelfRepository) AddBook(shelf *model.ShelfDO, bookId int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return addToShelf(tx, shelf, bookId)
+	})
+}
+
+// MoveBook 将图书从一个书架移动到另一个书架
+func (r *ShelfRepository) MoveBook(from, to *model.ShelfDO, bookId int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("shelf_id = ? AND book_id = ?", from.Id, bookId).Delete(&model.ShelfItemDO{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return addToShelf(tx, to, bookId)
+	})
+}
+
+func addToShelf(tx *gorm.DB, shelf *model.ShelfDO, bookId int64) error {
+	if model.IsBuiltinShelf(shelf.Kind) {
+		if err := tx.Where("user_id = ? AND book_id = ? AND shelf_id IN (?)", shelf.UserId, bookId,
+			tx.Model(&model.ShelfDO{}).Select("id").Where("user_id = ? AND kind IN ? AND id <> ?", shelf.UserId, builtinShelfKinds, shelf.Id)).
+			Delete(&model.ShelfItemDO{}).Error; err != nil {
+			return err
+		}
+	}
+	var maxPosition int
+	if err := tx.Model(&model.ShelfItemDO{}).Where("shelf_id = ?", shelf.Id).
+		Select("COALESCE(MAX(position), 0)").Scan(&maxPosition).Error; err != nil {
+		return err
+	}
+	item := &model.ShelfItemDO{
+		ShelfId:  shelf.Id,
+		BookId:   bookId,
+		UserId:   shelf.UserId,
+		Position: maxPosition + 1,
+		AddedAt:  time.Now(),
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(item).Error
+}
+
+// RemoveBook 将图书移出书架
+func (r *ShelfRepository) RemoveBook(shelfId, bookId int64) error {
+	return r.DB.Where("shelf_id = ? AND book_id = ?", shelfId, bookId).Delete(&model.ShelfItemDO{}).Error
+}
+
+// ListShelfBooks 分页获取书架中的图书, 按排序位置
+func (r *ShelfRepository) ListShelfBooks(shelfId int64, page, pageSize int) ([]*model.ShelfBookDTO, int64, error) {
+	var items []*model.ShelfItemDO
+	var total int64
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.ShelfItemDO{}).Where("shelf_id = ?", shelfId).
+		Count(&total).Order("position, id").Offset(offset).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(items) == 0 {
+		return []*model.ShelfBookDTO{}, total, nil
+	}
+
+	bookIds := make([]int64, len(items))
+	for i, item := range items {
+		bookIds[i] = item.BookId
+	}
+	var books []*model.BookInfoDO
+	if err := r.DB.Where("id IN ?", bookIds).Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	bookMap := make(map[int64]*model.BookInfoDO, len(books))
+	for _, book := range books {
+		bookMap[book.Id] = book
+	}
+
+	result := make([]*model.ShelfBookDTO, 0, len(items))
+	for _, item := range items {
+		if book, ok := bookMap[item.BookId]; ok {
+			result = append(result, &model.ShelfBookDTO{Book: book.Transfer(), Position: item.Position, AddedAt: item.AddedAt})
+		}
+	}
+	return result, total, nil
+}
+
+// Reorder 按bookIds的顺序重排书架, 未列出的图书排在后面并保持原有相对顺序
+func (r *ShelfRepository) Reorder(shelfId int64, bookIds []int64) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var items []*model.ShelfItemDO
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("shelf_id = ?", shelfId).
+			Order("position, id").Find(&items).Error; err != nil {
+			return err
+		}
+		order := make(map[int64]int, len(bookIds))
+		for i, bookId := range bookIds {
+			order[bookId] = i + 1
+		}
+		next := len(bookIds) + 1
+		for _, item := range items {
+			position, ok := order[item.BookId]
+			if !ok {
+				position = next
+				next++
+			}
+			if position == item.Position {
+				continue
+			}
+			if err := tx.Model(item).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetShelfKindOfBook 获取图书在用户状态书架中的状态, 不在任何状态书架时返回空串
+func (r *ShelfRepository) GetShelfKindOfBook(userId, bookId int64) (string, error) {
+	var kinds []string
+	if err := r.DB.Model(&model.ShelfItemDO{}).
+		Joins("JOIN shelf ON shelf.id = shelf_item.shelf_id").
+		Where("shelf_item.user_id = ? AND shelf_item.book_id = ? AND shelf.kind IN ?", userId, bookId, builtinShelfKinds).
+		Limit(1).Pluck("shelf.kind", &kinds).Error; err != nil {
+		return "", err
+	}
+	if len(kinds) == 0 {
+		return "", nil
+	}
+	return kinds[0], nil
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"errors"
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSuggestionClosed 荐购已审核, 不能再附议或取消附议
+var ErrSuggestionClosed = errors.New("suggestion is no longer pending")
+
+var suggestionRepository SuggestionRepository
+
+type SuggestionRepository struct {
+	DB *gorm.DB
+}
+
+func GetSuggestionRepository() *SuggestionRepository {
+	return &suggestionRepository
+}
+
+// 尚未结束的荐购状态
+var openSuggestionStatus = []string{model.SuggestionPending, model.SuggestionApproved, model.SuggestionOrdered}
+
+// CreateSuggestion 创建荐购
+func (r *SuggestionRepository) CreateSuggestion(suggestion *model.PurchaseSuggestionDO) (int64, error) {
+	if err := r.DB.Create(suggestion).Error; err != nil {
+		return 0, err
+	}
+	return suggestion.Id, nil
+}
+
+// GetSuggestionById 根据ID获取荐购
+func (r *SuggestionRepository) GetSuggestionById(id int64) (*model.PurchaseSuggestionDO, error) {
+	var suggestion model.PurchaseSuggestionDO
+	if err := r.DB.First(&suggestion, id).Error; err != nil {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// FindOpenSuggestionByISBN 查找同一ISBN尚未结束的荐购,不存在时返回nil
+func (r *SuggestionRepository) FindOpenSuggestionByISBN(isbn string) (*model.PurchaseSuggestionDO, error) {
+	var suggestions []*model.PurchaseSuggestionDO
+	if err := r.DB.Where("isbn = ? AND status IN ?", isbn, openSuggestionStatus).Limit(1).Find(&suggestions).Error; err != nil {
+		return nil, err
+	}
+	if len(suggestions) == 0 {
+		return nil, nil
+	}
+	return suggestions[0], nil
+}
+
+// ListSuggestions 分页获取荐购列表
+func (r *SuggestionRepository) ListSuggestions(status, sort string, page, pageSize int) ([]*model.PurchaseSuggestionDO, int64, error) {
+	var suggestions []*model.PurchaseSuggestionDO
+	var total int64
+
+	query := r.DB.Model(&model.PurchaseSuggestionDO{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	order := "id DESC"
+	if sort == "votes" {
+		order = "vote_count DESC, id DESC"
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Count(&total).Order(order).Offset(offset).Limit(pageSize).Find(&suggestions).Error; err != nil {
+		return nil, 0, err
+	}
+	return suggestions, total, nil
+}
+
+// GetVotedSuggestionIds 获取用户在给定荐购中已附议的荐购ID集合
+func (r *SuggestionRepository) GetVotedSuggestionIds(userId int64, suggestionIds []int64) (map[int64]bool, error) {
+	voted := make(map[int64]bool)
+	if len(suggestionIds) == 0 {
+		return voted, nil
+	}
+	var ids []int64
+	if err := r.DB.Model(&model.PurchaseSuggestionVoteDO{}).
+		Where("user_id = ? AND suggestion_id IN ?", userId, suggestionIds).
+		Pluck("suggestion_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		voted[id] = true
+	}
+	return voted, nil
+}
+
+// Vote 附议荐购,只能附议待审核的荐购,重复附议不会重复计数,返回本次是否新增
+func (r *SuggestionRepository) Vote(suggestionId, userId int64) (bool, error) {
+	added := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingSuggestion(tx, suggestionId); err != nil {
+			return err
+		}
+		vote := &model.PurchaseSuggestionVoteDO{SuggestionId: suggestionId, UserId: userId, CreatedAt: time.Now()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(vote)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		added = true
+		return tx.Model(&model.PurchaseSuggestionDO{}).Where("id = ?", suggestionId).
+			UpdateColumn("vote_count", gorm.Expr("vote_count + 1")).Error
+	})
+	return added, err
+}
+
+// Unvote 取消附议,只能对待审核的荐购操作,返回本次是否删除了附议记录
+func (r *SuggestionRepository) Unvote(suggestionId, userId int64) (bool, error) {
+	removed := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingSuggestion(tx, suggestionId); err != nil {
+			return err
+		}
+		result := tx.Where("suggestion_id = ? AND user_id = ?", suggestionId, userId).Delete(&model.PurchaseSuggestionVoteDO{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		removed = true
+		return tx.Model(&model.PurchaseSuggestionDO{}).Where("id = ? AND vote_count > 0", suggestionId).
+			UpdateColumn("vote_count", gorm.Expr("vote_count - 1")).Error
+	})
+	return removed, err
+}
+
+// lockPendingSuggestion 锁住荐购记录, 防止与审核并发; 不在待审核状态时返回ErrSuggestionClosed
+func lockPendingSuggestion(tx *gorm.DB, id int64) error {
+	var suggestion model.PurchaseSuggestionDO
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&suggestion, id).Error; err != nil {
+		return err
+	}
+	if suggestion.Status != model.SuggestionPending {
+		return ErrSuggestionClosed
+	}
+	return nil
+}
+
+// ReviewSuggestion 馆员审核,仅当状态仍为from时更新,返回是否更新成功
+func (r *SuggestionRepository) ReviewSuggestion(id int64, from, to string, reviewerId int64, comment string) (bool, error) {
+	result := r.DB.Model(&model.PurchaseSuggestionDO{}).Where("id = ? AND status = ?", id, from).Updates(map[string]interface{}{
+		"status":         to,
+		"reviewer_id":    reviewerId,
+		"review_comment": comment,
+		"updated_at":     time.Now(),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FulfillSuggestions 图书入库后,将同ISBN(或同书名作者)尚未结束的荐购标记为已到馆,返回被更新的荐购
+func (r *SuggestionRepository) FulfillSuggestions(book *model.BookInfoDTO) ([]*model.PurchaseSuggestionDO, error) {
+	var suggestions []*model.PurchaseSuggestionDO
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("status IN ?", openSuggestionStatus)
+		if book.ISBN != "" {
+			query = query.Where("isbn = ? OR (isbn = '' AND title = ? AND author = ?)", book.ISBN, book.Name, book.Author)
+		} else {
+			query = query.Where("title = ? AND author = ?", book.Name, book.Author)
+		}
+		if err := query.Find(&suggestions).Error; err != nil {
+			return err
+		}
+		if len(suggestions) == 0 {
+			return nil
+		}
+		ids := make([]int64, len(suggestions))
+		for i, suggestion := range suggestions {
+			ids[i] = suggestion.Id
+			suggestion.Status = model.SuggestionFulfilled
+			suggestion.BookId = book.Id
+		}
+		return tx.Model(&model.PurchaseSuggestionDO{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":     model.SuggestionFulfilled,
+			"book_id":    book.Id,
+			"updated_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
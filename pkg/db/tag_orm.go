@@ -0,0 +1,187 @@
+package db
+
+import (
+	"errors"
+	"time"
+	"yujian-backend/pkg/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTagBanned = errors.New("tag is banned")
+
+var tagRepository TagRepository
+
+type TagRepository struct {
+	DB *gorm.DB
+}
+
+func GetTagRepository() *TagRepository {
+	return &tagRepository
+}
+
+// GetTagById 根据ID获取标签
+func (r *TagRepository) GetTagById(id int64) (*model.TagDO, error) {
+	var tag model.TagDO
+	if err := r.DB.First(&tag, id).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// AddBookTag 用户给图书打标签, 标签不存在时创建, 重复打同一标签不报错
+func (r *TagRepository) AddBookTag(bookId, userId int64, name string) (*model.TagDO, error) {
+	tag := &model.TagDO{Name: name}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(tag).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("name = ?", name).First(tag).Error; err != nil {
+			return err
+		}
+		if tag.Banned {
+			return ErrTagBanned
+		}
+		bookTag := &model.BookTagDO{
+			BookId:    bookId,
+			TagId:     tag.Id,
+			UserId:    userId,
+			Status:    model.BookTagVisible,
+			CreatedAt: time.Now(),
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(bookTag).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// RemoveBookTag 用户撤销自己打的标签
+func (r *TagRepository) RemoveBookTag(bookId, tagId, userId int64) error {
+	return r.DB.Where("book_id = ? AND tag_id = ? AND user_id = ?", bookId, tagId, userId).
+		Delete(&model.BookTagDO{}).Error
+}
+
+// ListBookTags 获取图书上可见的标签及打标人数, 按人数倒序
+func (r *TagRepository) ListBookTags(bookId int64, limit int) ([]*model.TagDTO, error) {
+	var tags []*model.TagDTO
+	if err := r.DB.Model(&model.BookTagDO{}).
+		Select("tag.id AS id, tag.name AS name, COUNT(*) AS count").
+		Joins("JOIN tag ON tag.id = book_tag.tag_id").
+		Where("book_tag.book_id = ? AND book_tag.status = ? AND tag.banned = ?", bookId, model.BookTagVisible, false).
+		Group("tag.id, tag.name").Order("count DESC").Limit(limit).
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ListBooksByTag 分页获取带有某标签的图书, 按打标人数倒序
+func (r *TagRepository) ListBooksByTag(tagId int64, page, pageSize int) ([]*model.BookInfoDTO, int64, error) {
+	var total int64
+	if err := r.DB.Model(&model.BookTagDO{}).Where("tag_id = ? AND status = ?", tagId, model.BookTagVisible).
+		Distinct("book_id").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var bookIds []int64
+	offset := (page - 1) * pageSize
+	if err := r.DB.Model(&model.BookTagDO{}).Where("tag_id = ? AND status = ?", tagId, model.BookTagVisible).
+		Group("book_id").Order("COUNT(*) DESC").Offset(offset).Limit(pageSize).
+		Pluck("book_id", &bookIds).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(bookIds) == 0 {
+		return []*model.BookInfoDTO{}, total, nil
+	}
+
+	var books []*model.BookInfoDO
+	if err := r.DB.Where("id IN ?", bookIds).Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	// 保持按打标人数的顺序
+	byId := make(map[int64]*model.BookInfoDO, len(books))
+	for _, book := range books {
+		byId[book.Id] = book
+	}
+	bookDTOs := make([]*model.BookInfoDTO, 0, len(books))
+	for _, id := range bookIds {
+		if book, ok := byId[id]; ok {
+			bookDTOs = append(bookDTOs, book.Transfer())
+		}
+	}
+	return bookDTOs, total, nil
+}
+
+// CountTagUsers 统计标签的打标人数
+func (r *TagRepository) CountTagUsers(tagId int64) (int64, error) {
+	var count int64
+	err := r.DB.Model(&model.BookTagDO{}).Where("tag_id = ? AND status = ?", tagId, model.BookTagVisible).Count(&count).Error
+	return count, err
+}
+
+// SetTagBanned 版主全局禁用/解禁标签
+func (r *TagRepository) SetTagBanned(tagId int64, banned bool) error {
+	return r.DB.Model(&model.TagDO{}).Where("id = ?", tagId).Update("banned", banned).Error
+}
+
+// SetBookTagStatus 版主隐藏/恢复某本书上的某个标签
+func (r *TagRepository) SetBookTagStatus(bookId, tagId int64, status string) error {
+	return r.DB.Model(&model.BookTagDO{}).Where("book_id = ? AND tag_id = ?", bookId, tagId).
+		Update("status", status).Error
+}
+
+// SetPostHashtags 用正文中的话题替换帖子正文(commentId为0)或评论的话题索引, 标签不存在时创建, 被禁用的标签忽略
+// 编辑后仍保留的话题不更新使用时间, 避免编辑把帖子顶到话题页前面
+func (r *TagRepository) SetPostHashtags(postId, commentId int64, names []string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		tagIds := []int64{}
+		for _, name := range names {
+			tag := &model.TagDO{Name: name}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(tag).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", name).First(tag).Error; err != nil {
+				return err
+			}
+			if tag.Banned {
+				continue
+			}
+			tagIds = append(tagIds, tag.Id)
+			hashtag := &model.PostHashtagDO{TagId: tag.Id, PostId: postId, CommentId: commentId, CreatedAt: now}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(hashtag).Error; err != nil {
+				return err
+			}
+		}
+		query := tx.Where("post_id = ? AND comment_id = ?", postId, commentId)
+		if len(tagIds) > 0 {
+			query = query.Where("tag_id NOT IN ?", tagIds)
+		}
+		return query.Delete(&model.PostHashtagDO{}).Error
+	})
+}
+
+// RemovePostHashtags 删除帖子正文和全部评论的话题索引
+func (r *TagRepository) RemovePostHashtags(postId int64) error {
+	return r.DB.Where("post_id = ?", postId).Delete(&model.PostHashtagDO{}).Error
+}
+
+// RemoveCommentHashtags 删除评论的话题索引
+func (r *TagRepository) RemoveCommentHashtags(commentIds []int64) error {
+	if len(commentIds) == 0 {
+		return nil
+	}
+	return r.DB.Where("comment_id IN ?", commentIds).Delete(&model.PostHashtagDO{}).Error
+}
+
+// GetTagByName 根据名称获取标签
+func (r *TagRepository) GetTagByName(name string) (*model.TagDO, error) {
+	var tag model.TagDO
+	if err := r.DB.Where("name = ?", name).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
@@ -45,9 +45,35 @@ func (r *UserRepository) GetUserByName(name string) (*model.UserDTO, error) {
 	}
 }
 
-// UpdateUser 更新用户信息
+// UpdateUser 更新用户信息(角色不允许通过此接口修改)
 func (r *UserRepository) UpdateUser(user *model.UserDO) error {
-	return r.DB.Save(user).Error
+	return r.DB.Omit("role").Save(user).Error
+}
+
+// SetUserRole 修改用户角色,用户不存在时返回gorm.ErrRecordNotFound
+func (r *UserRepository) SetUserRole(id int64, role string) error {
+	result := r.DB.Model(&model.UserDO{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.DB.Model(&model.UserDO{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+// GrantAdmins 把配置中列出的用户名设为管理员,用于初始化第一个管理员
+func (r *UserRepository) GrantAdmins(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return r.DB.Model(&model.UserDO{}).Where("name IN ?", names).Update("role", model.RoleAdmin).Error
 }
 
 // DeleteUser 删除用户
@@ -10,10 +10,19 @@ const (
 	book_index = "book_index"
 )
 
-// SearchBooks 搜索图书
-func SearchBooks(ctx context.Context, keyword, category string, page, pageSize int) ([]int64, error) {
+// SearchBooks 搜索图书, sort为rating时按贝叶斯加权分排序, 否则按相关度
+func SearchBooks(ctx context.Context, keyword, category, sort string, page, pageSize int) ([]int64, error) {
+	var sorts []model.SortField
+	if sort == "rating" {
+		// 还没有图书写入评分字段时索引映射中没有这两个字段
+		sorts = []model.SortField{
+			{Field: "weighted_score", Desc: true, UnmappedType: "float"},
+			{Field: "rating_count", Desc: true, UnmappedType: "long"},
+		}
+	}
 	//调用Search函数
 	esResult, err := Search[model.BookInfoES](ctx, book_index, model.EsQueryCondition{
+		Sorts:              sorts,
 		From:               page,
 		Size:               pageSize,
 		MinimumShouldMatch: 1,
@@ -110,6 +110,12 @@ func PutSimilarityMapping(ctx context.Context, indexName string, fieldName strin
 
 // Search 搜索内容
 func Search[T model.EsModel](ctx context.Context, indexName string, condition model.EsQueryCondition) ([]T, error) {
+	items, _, err := SearchWithTotal[T](ctx, indexName, condition)
+	return items, err
+}
+
+// SearchWithTotal 搜索内容, 同时返回命中的总数用于分页
+func SearchWithTotal[T model.EsModel](ctx context.Context, indexName string, condition model.EsQueryCondition) ([]T, int64, error) {
 	var shouldFields []interface{}
 	for _, condition := range condition.Conditions {
 		shouldCondition := map[string]interface{}{
@@ -128,14 +134,30 @@ func Search[T model.EsModel](ctx context.Context, indexName string, condition mo
 				"minimum_should_match": condition.MinimumShouldMatch, // 至少匹配一个 should 子句
 			},
 		},
-		"from": condition.From,
-		"size": condition.Size,
+		"from":             condition.From,
+		"size":             condition.Size,
+		"track_total_hits": true,
+	}
+	if len(condition.Sorts) > 0 {
+		var sorts []interface{}
+		for _, sort := range condition.Sorts {
+			order := "asc"
+			if sort.Desc {
+				order = "desc"
+			}
+			options := map[string]interface{}{"order": order}
+			if sort.UnmappedType != "" {
+				options["unmapped_type"] = sort.UnmappedType
+			}
+			sorts = append(sorts, map[string]interface{}{sort.Field: options})
+		}
+		searchQuery["sort"] = append(sorts, "_score")
 	}
 
 	body, err := json.Marshal(searchQuery)
 	if err != nil {
 		log.GetLogger().Warnf("Error marshaling es-search query: %v", err)
-		return nil, err
+		return nil, 0, err
 	}
 
 	res, err := es.Search(
@@ -145,21 +167,27 @@ func Search[T model.EsModel](ctx context.Context, indexName string, condition mo
 	)
 	if err != nil {
 		log.GetLogger().Warnf("Error searching documents: %v", err)
-		return nil, err
+		return nil, 0, err
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return nil, errors.New("error searching documents")
+		return nil, 0, errors.New("error searching documents")
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	var items []T
 	hits := result["hits"].(map[string]interface{})["hits"].([]interface{})
+	var total int64
+	if totalHits, ok := result["hits"].(map[string]interface{})["total"].(map[string]interface{}); ok {
+		if value, ok := totalHits["value"].(float64); ok {
+			total = int64(value)
+		}
+	}
 
 	for _, hit := range hits {
 		source := hit.(map[string]interface{})["_source"]
@@ -177,7 +205,7 @@ func Search[T model.EsModel](ctx context.Context, indexName string, condition mo
 		items = append(items, item)
 	}
 
-	return items, nil
+	return items, total, nil
 }
 
 // UpdateArticle 更新文章索引
@@ -439,11 +467,26 @@ func SearchArticlesWithScores[T model.EsModel](ctx context.Context, indexName st
 }
 
 func GetContentById(ctx context.Context, indexName, id string) (string, error) {
+	source, err := GetSourceById(ctx, indexName, id)
+	if err != nil {
+		return "", err
+	}
+	content, ok := source["content"].(string)
+	if !ok {
+		log.GetLogger().Error("获取content字段失败")
+		return "", errors.New("获取content字段失败")
+	}
+
+	return content, nil
+}
+
+// GetSourceById 根据documentId获取文档的全部字段
+func GetSourceById(ctx context.Context, indexName, id string) (map[string]interface{}, error) {
 	// 根据documentId和indexName查询文章
-	res, err := esClient.Get(indexName, id)
+	res, err := esClient.Get(indexName, id, esClient.Get.WithContext(ctx))
 	if err != nil {
 		log.GetLogger().Error("查询文章失败: %v", err)
-		return "", err
+		return nil, err
 	}
 	defer res.Body.Close()
 
@@ -451,24 +494,47 @@ func GetContentById(ctx context.Context, indexName, id string) (string, error) {
 		var e map[string]interface{}
 		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
 			log.GetLogger().Error("解析错误响应失败: %v", err)
-			return "", err
+			return nil, err
 		}
 		log.GetLogger().Error("Elasticsearch错误: %v", e)
-		return "", err
+		return nil, fmt.Errorf("elasticsearch error: %v", e)
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
 		log.GetLogger().Error("解析响应失败: %v", err)
-		return "", err
+		return nil, err
 	}
 
-	source := result["_source"]
-	content, ok := source.(map[string]interface{})["content"].(string)
+	source, ok := result["_source"].(map[string]interface{})
 	if !ok {
-		log.GetLogger().Error("获取content字段失败")
-		return "", errors.New("获取content字段失败")
+		return nil, errors.New("获取_source失败")
 	}
+	return source, nil
+}
 
-	return content, nil
+// DeleteByQuery 删除索引中符合查询条件的文档
+func DeleteByQuery(ctx context.Context, indexName string, query map[string]interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query})
+	if err != nil {
+		return err
+	}
+
+	res, err := es.DeleteByQuery(
+		[]string{indexName},
+		bytes.NewReader(body),
+		es.DeleteByQuery.WithContext(ctx),
+		es.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		log.GetLogger().Error("Error deleting by query: %v", err)
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.GetLogger().Error("Error response from Elasticsearch: %s", res.String())
+		return errors.New("error deleting by query")
+	}
+	return nil
 }
@@ -24,5 +24,6 @@ func InitESClient() {
 	} else {
 		log.GetLogger().Info("创建ES客户端成功")
 		esClient = client
+		es = client
 	}
 }
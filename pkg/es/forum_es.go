@@ -0,0 +1,189 @@
+package es
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"time"
+
+	"yujian-backend/pkg/model"
+)
+
+const (
+	post_index         = "post"
+	post_comment_index = "post_comment"
+)
+
+// forumHighlightTag 高亮片段中包住关键词的标签
+const forumHighlightTag = "em"
+
+// SearchForum 在帖子和评论中全文搜索, 只匹配标题和去掉剧透后的正文; 返回带高亮片段的结果和命中总数
+func SearchForum(ctx context.Context, query *model.ForumSearchQuery) ([]*model.ForumSearchHit, int64, error) {
+	indices := []string{post_index, post_comment_index}
+	switch query.Type {
+	case model.ForumSearchPost:
+		indices = []string{post_index}
+	case model.ForumSearchComment:
+		indices = []string{post_comment_index}
+	}
+
+	// 公开帖子总是可见, 读书会帖子只对成员可见; 读书会功能上线前索引的文档没有club_id字段, 同样视为公开
+	filters := []interface{}{
+		map[string]interface{}{"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{"terms": map[string]interface{}{"club_id": append([]int64{0}, query.ClubIds...)}},
+				map[string]interface{}{"bool": map[string]interface{}{
+					"must_not": map[string]interface{}{"exists": map[string]interface{}{"field": "club_id"}},
+				}},
+			},
+			"minimum_should_match": 1,
+		}},
+	}
+	if query.AuthorId > 0 {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"author_id": query.AuthorId}})
+	}
+	if query.CategoryId > 0 {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"category_id": query.CategoryId}})
+	} else if query.Category != "" {
+		filters = append(filters, map[string]interface{}{"match_phrase": map[string]interface{}{"category": query.Category}})
+	}
+	if !query.StartTime.IsZero() || !query.EndTime.IsZero() {
+		timeRange := map[string]interface{}{}
+		if !query.StartTime.IsZero() {
+			timeRange["gte"] = query.StartTime.Format(time.RFC3339)
+		}
+		if !query.EndTime.IsZero() {
+			timeRange["lte"] = query.EndTime.Format(time.RFC3339)
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"created_at": timeRange}})
+	}
+
+	searchQuery := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":  query.Keyword,
+						"fields": []string{"title^2", "content"},
+					},
+				},
+				"filter": filters,
+			},
+		},
+		// 片段按HTML返回, 由ES转义正文中的用户输入, 只保留高亮标签
+		"highlight": map[string]interface{}{
+			"encoder":   "html",
+			"pre_tags":  []string{"<" + forumHighlightTag + ">"},
+			"post_tags": []string{"</" + forumHighlightTag + ">"},
+			"fields": map[string]interface{}{
+				"title":   map[string]interface{}{"number_of_fragments": 0},
+				"content": map[string]interface{}{"fragment_size": 100, "number_of_fragments": 3},
+			},
+		},
+		"from":             query.From,
+		"size":             query.Size,
+		"track_total_hits": true,
+	}
+	if query.Sort == model.ForumSortRecent {
+		searchQuery["sort"] = []interface{}{
+			map[string]interface{}{"created_at": map[string]interface{}{"order": "desc", "unmapped_type": "date"}},
+			"_score",
+		}
+	}
+
+	body, err := json.Marshal(searchQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(indices...),
+		es.Search.WithBody(bytes.NewReader(body)),
+		es.Search.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search forum in ES: %v", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, 0, errors.New("error searching forum")
+	}
+
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Index     string              `json:"_index"`
+				Id        string              `json:"_id"`
+				Score     float64             `json:"_score"`
+				Source    forumSearchSource   `json:"_source"`
+				Highlight map[string][]string `json:"highlight"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]*model.ForumSearchHit, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		id, err := strconv.ParseInt(hit.Id, 10, 64)
+		if err != nil {
+			continue
+		}
+		item := &model.ForumSearchHit{
+			Type:       model.ForumSearchPost,
+			PostId:     id,
+			Title:      html.EscapeString(hit.Source.Title),
+			Highlights: hit.Highlight["content"],
+			AuthorId:   hit.Source.AuthorId,
+			CreatedAt:  hit.Source.CreatedAt,
+			Score:      hit.Score,
+		}
+		if hit.Index == post_comment_index {
+			item.Type = model.ForumSearchComment
+			item.PostId = hit.Source.PostId
+			item.CommentId = id
+		}
+		if titles := hit.Highlight["title"]; len(titles) > 0 {
+			item.Title = titles[0]
+		}
+		if item.Highlights == nil {
+			item.Highlights = []string{}
+		}
+		hits = append(hits, item)
+	}
+	return hits, result.Hits.Total.Value, nil
+}
+
+// forumSearchSource 帖子和评论文档中搜索结果用到的字段
+type forumSearchSource struct {
+	PostId    int64     `json:"post_id"`
+	Title     string    `json:"title"`
+	AuthorId  int64     `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeletePostComments 删除帖子下全部评论的文档
+func DeletePostComments(ctx context.Context, postId int64) error {
+	return DeleteByQuery(ctx, post_comment_index, map[string]interface{}{
+		"term": map[string]interface{}{"post_id": postId},
+	})
+}
+
+// DeleteCommentDocs 按评论ID删除评论的文档
+func DeleteCommentDocs(ctx context.Context, commentIds []int64) error {
+	ids := make([]string, len(commentIds))
+	for i, id := range commentIds {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	return DeleteByQuery(ctx, post_comment_index, map[string]interface{}{
+		"ids": map[string]interface{}{"values": ids},
+	})
+}
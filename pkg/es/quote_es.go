@@ -0,0 +1,46 @@
+package es
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"yujian-backend/pkg/model"
+)
+
+const (
+	quote_index = "quote"
+)
+
+// SearchQuotes 全文搜索书摘原文和感想, bookId不为0时只在该书内搜索; 返回当前页的书摘ID和命中总数
+func SearchQuotes(ctx context.Context, keyword string, bookId int64, page, pageSize int) ([]int64, int64, error) {
+	condition := model.EsQueryCondition{
+		From:               (page - 1) * pageSize,
+		Size:               pageSize,
+		MinimumShouldMatch: 1,
+		Conditions: []model.Condition{
+			{
+				Fields: []string{"content", "comment"},
+				Value:  keyword,
+			},
+		},
+	}
+	if bookId != 0 {
+		condition.Conditions = append(condition.Conditions, model.Condition{
+			Fields: []string{"book_id"},
+			Value:  strconv.FormatInt(bookId, 10),
+		})
+		condition.MinimumShouldMatch = 2
+	}
+	esResult, total, err := SearchWithTotal[*model.QuoteEsModel](ctx, quote_index, condition)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search quotes in ES: %v", err)
+	}
+
+	var quoteIds []int64
+	for _, quote := range esResult {
+		if id, err := strconv.ParseInt(quote.Id, 10, 64); err == nil {
+			quoteIds = append(quoteIds, id)
+		}
+	}
+	return quoteIds, total, nil
+}
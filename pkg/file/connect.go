@@ -3,7 +3,9 @@ package file
 import (
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"strings"
 	"sync"
+	"yujian-backend/pkg/config"
 	"yujian-backend/pkg/log"
 )
 
@@ -18,7 +20,8 @@ type MinioClient struct {
 
 func InitMinio() {
 	once.Do(func() {
-		if client, err := newMinIOClient("http://127.0.0.1:9000", "minioadmin", "minioadmin"); err != nil {
+		minioConfig := config.Config.Minio
+		if client, err := newMinIOClient(minioConfig.Endpoint, minioConfig.AccessKeyID, minioConfig.SecretAccessKey); err != nil {
 			log.GetLogger().Fatalf("Failed to initialize MinIO client: %v", err)
 		} else {
 			minioClient = &MinioClient{inner: client}
@@ -32,9 +35,12 @@ func GetMinioClient() *MinioClient {
 }
 
 func newMinIOClient(endpoint, accessKeyID, secretAccessKey string) (*minio.Client, error) {
+	// minio客户端只接受host:port, 协议由Secure决定
+	secure := strings.HasPrefix(endpoint, "https://")
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
 	client, err := minio.New(endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
-		Secure: false, // 如果使用 HTTPS 则设置为 true
+		Secure: secure,
 	})
 	if err != nil {
 		return nil, err
@@ -36,6 +36,22 @@ func (client *MinioClient) UploadFile(ctx context.Context, bucketName, objectNam
 	return nil
 }
 
+// PutObject 将数据流上传到 MinIO
+func (client *MinioClient) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, size int64, contentType string) error {
+	_, err := client.inner.PutObject(ctx, bucketName, objectName, reader, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// OpenObject 打开 MinIO 中的对象用于流式读取, 调用方负责关闭
+func (client *MinioClient) OpenObject(ctx context.Context, bucketName, objectName string) (*minio.Object, error) {
+	return client.inner.GetObject(ctx, bucketName, objectName, minio.GetObjectOptions{})
+}
+
+// RemoveObject 删除 MinIO 中的对象, 对象不存在时不报错
+func (client *MinioClient) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	return client.inner.RemoveObject(ctx, bucketName, objectName, minio.RemoveObjectOptions{})
+}
+
 // DownloadFile 从 MinIO 下载文件
 func (client *MinioClient) DownloadFile(ctx context.Context, bucketName, objectName, filePath string) (string, error) {
 	// 下载文件
@@ -0,0 +1,153 @@
+package model
+
+import "time"
+
+// 成就事件类型, 业务操作完成后发布, 由规则引擎异步评估徽章
+const (
+	EventReview   = "review"   // 发表书评
+	EventPost     = "post"     // 发帖
+	EventShelf    = "shelf"    // 书架变动
+	EventProgress = "progress" // 记录阅读进度, 读完时图书会移入"读过"书架
+	EventLoan     = "loan"     // 借阅, 借阅模块尚未接入, 暂无发布方
+)
+
+// 徽章规则统计的指标
+const (
+	MetricReviews    = "reviews"    // 书评数
+	MetricPosts      = "posts"      // 帖子数
+	MetricBooksRead  = "books_read" // 读过的书数
+	MetricCategories = "categories" // 读过的书覆盖的规范分类数
+	MetricLoans      = "loans"      // 借阅次数, 借阅模块接入前不可用
+)
+
+// MetricEvents 每个指标会被哪些事件改变, 引擎只评估与事件相关的徽章; 只有列出的指标可以用于徽章
+// 借阅模块发布EventLoan后再加入MetricLoans
+var MetricEvents = map[string][]string{
+	MetricReviews:    {EventReview},
+	MetricPosts:      {EventPost},
+	MetricBooksRead:  {EventShelf, EventProgress},
+	MetricCategories: {EventShelf, EventProgress},
+}
+
+// 通知类型
+const (
+	NotifyBadgeEarned        = "badge_earned"        // 获得徽章
+	NotifyChallengeCompleted = "challenge_completed" // 完成年度阅读挑战
+)
+
+// AchievementEvent 成就事件
+type AchievementEvent struct {
+	Type   string
+	UserId int64
+}
+
+// BadgeDTO 徽章DTO
+type BadgeDTO struct {
+	Id          int64      `json:"id"`
+	Code        string     `json:"code"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Icon        string     `json:"icon"`
+	Metric      string     `json:"metric"`
+	Threshold   int64      `json:"threshold"`
+	EarnedAt    *time.Time `json:"earned_at,omitempty"` // 仅在用户徽章列表中有值
+}
+
+// BadgeDO 徽章定义, 规则为"指标Metric达到Threshold"
+type BadgeDO struct {
+	Id          int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Code        string `gorm:"column:code;uniqueIndex;size:64" json:"code"`
+	Name        string `gorm:"column:name" json:"name"`
+	Description string `gorm:"column:description" json:"description"`
+	Icon        string `gorm:"column:icon" json:"icon"`
+	Metric      string `gorm:"column:metric;index" json:"metric"`
+	Threshold   int64  `gorm:"column:threshold" json:"threshold"`
+	Enabled     bool   `gorm:"column:enabled;default:true" json:"enabled"`
+}
+
+func (b BadgeDO) TableName() string {
+	return "badge"
+}
+
+// Transfer 将BadgeDO转换为BadgeDTO
+func (b *BadgeDO) Transfer() *BadgeDTO {
+	return &BadgeDTO{
+		Id:          b.Id,
+		Code:        b.Code,
+		Name:        b.Name,
+		Description: b.Description,
+		Icon:        b.Icon,
+		Metric:      b.Metric,
+		Threshold:   b.Threshold,
+	}
+}
+
+// DefaultBadges 内置徽章, 启动时不存在则创建
+var DefaultBadges = []BadgeDO{
+	{Code: "first_review", Name: "初试书评", Description: "发表第一篇书评", Metric: MetricReviews, Threshold: 1},
+	{Code: "reviewer_10", Name: "书评达人", Description: "评论过10本书", Metric: MetricReviews, Threshold: 10},
+	{Code: "first_post", Name: "初来乍到", Description: "发布第一个帖子", Metric: MetricPosts, Threshold: 1},
+	{Code: "reader_10", Name: "书虫", Description: "读完10本书", Metric: MetricBooksRead, Threshold: 10},
+	{Code: "explorer_5", Name: "博览群书", Description: "读过5个分类的书", Metric: MetricCategories, Threshold: 5},
+}
+
+// UserBadgeDO 用户获得的徽章
+type UserBadgeDO struct {
+	Id       int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId   int64     `gorm:"column:user_id;uniqueIndex:uk_user_badge" json:"user_id"`
+	BadgeId  int64     `gorm:"column:badge_id;uniqueIndex:uk_user_badge" json:"badge_id"`
+	EarnedAt time.Time `gorm:"column:earned_at" json:"earned_at"`
+}
+
+func (u UserBadgeDO) TableName() string {
+	return "user_badge"
+}
+
+// ReadingChallengeDO 年度阅读挑战
+type ReadingChallengeDO struct {
+	Id          int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId      int64      `gorm:"column:user_id;uniqueIndex:uk_user_year" json:"user_id"`
+	Year        int        `gorm:"column:year;uniqueIndex:uk_user_year" json:"year"`
+	Goal        int        `gorm:"column:goal" json:"goal"`
+	CompletedAt *time.Time `gorm:"column:completed_at" json:"completed_at"`
+}
+
+func (r ReadingChallengeDO) TableName() string {
+	return "reading_challenge"
+}
+
+// ReadingChallengeDTO 年度阅读挑战及进度
+type ReadingChallengeDTO struct {
+	Year        int        `json:"year"`
+	Goal        int        `json:"goal"`
+	Finished    int64      `json:"finished"` // 当年读完的书数
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// SetChallengeRequest 设置年度目标请求
+type SetChallengeRequest struct {
+	Goal int `json:"goal"`
+}
+
+// CreateBadgeRequest 创建徽章请求
+type CreateBadgeRequest struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Metric      string `json:"metric"`
+	Threshold   int64  `json:"threshold"`
+}
+
+// ChallengeResponse 年度阅读挑战返回
+type ChallengeResponse struct {
+	BaseResp
+	Challenge *ReadingChallengeDTO `json:"challenge"`
+}
+
+// BadgeListResponse 徽章列表返回
+type BadgeListResponse struct {
+	BaseResp
+	Badges []*BadgeDTO `json:"badges"`
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+// 附件类型, 图片可以在正文中内嵌显示, 其余只能作为链接下载
+const (
+	AttachmentImage = "image"
+	AttachmentFile  = "file"
+)
+
+// AttachmentURLPrefix 附件的访问路径前缀, 正文中通过该路径引用附件
+const AttachmentURLPrefix = "/api/forum/attachments/"
+
+// AllowedAttachmentTypes 允许上传的MIME类型及对应的附件类型, 按文件内容识别而不是扩展名
+var AllowedAttachmentTypes = map[string]string{
+	"image/jpeg":      AttachmentImage,
+	"image/png":       AttachmentImage,
+	"image/gif":       AttachmentImage,
+	"image/webp":      AttachmentImage,
+	"application/pdf": AttachmentFile,
+	"text/plain":      AttachmentFile,
+	"application/zip": AttachmentFile,
+}
+
+var attachmentRefRe = regexp.MustCompile(regexp.QuoteMeta(AttachmentURLPrefix) + `([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`)
+
+// ParseAttachmentRefs 解析Markdown正文中引用的附件Key, 如 ![封面](/api/forum/attachments/<key>)
+func ParseAttachmentRefs(content string) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, m := range attachmentRefRe.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			keys = append(keys, m[1])
+		}
+	}
+	return keys
+}
+
+var attachmentAttrRe = regexp.MustCompile(`"` + attachmentRefRe.String() + `(?:\?[^"]*)?"`)
+
+// RewriteAttachmentURLs 替换渲染后HTML中src/href属性里的附件地址, 原地址带的查询参数一并丢弃
+func RewriteAttachmentURLs(html string, rewrite func(key string) string) string {
+	return attachmentAttrRe.ReplaceAllStringFunc(html, func(attr string) string {
+		key := attachmentRefRe.FindStringSubmatch(attr)[1]
+		return `"` + rewrite(key) + `"`
+	})
+}
+
+// AttachmentDTO 论坛附件DTO
+type AttachmentDTO struct {
+	Key         string    `json:"key"`
+	URL         string    `json:"url"`                   // 正文中引用附件使用的路径
+	Markdown    string    `json:"markdown"`              // 可以直接插入正文的Markdown
+	PreviewURL  string    `json:"preview_url,omitempty"` // 带签名的临时地址, 发布前预览用, 不要写入正文
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Kind        string    `json:"kind"`
+	Size        int64     `json:"size"`
+	PostId      int64     `json:"post_id"`
+	CommentId   int64     `json:"comment_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AttachmentDO 论坛附件DO, 上传后未被引用时PostId为0; 只被草稿引用时记录DraftId, 草稿删除或过期后解除
+// 超过配置的时间仍未被已发布的帖子或评论引用、也不在草稿中的附件会被回收
+type AttachmentDO struct {
+	Id          int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Key         string     `gorm:"column:key;size:64;uniqueIndex" json:"key"` // MinIO中的对象名
+	UploaderId  int64      `gorm:"column:uploader_id;index" json:"uploader_id"`
+	FileName    string     `gorm:"column:file_name" json:"file_name"`
+	ContentType string     `gorm:"column:content_type" json:"content_type"`
+	Kind        string     `gorm:"column:kind" json:"kind"`
+	Size        int64      `gorm:"column:size" json:"size"`
+	PostId      int64      `gorm:"column:post_id;index" json:"post_id"`
+	CommentId   int64      `gorm:"column:comment_id" json:"comment_id"`
+	DraftId     int64      `gorm:"column:draft_id;index" json:"draft_id"`
+	BoundAt     *time.Time `gorm:"column:bound_at" json:"bound_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at;index" json:"created_at"`
+}
+
+func (a AttachmentDO) TableName() string {
+	return "attachment"
+}
+
+// Transfer 将AttachmentDO转换为AttachmentDTO
+func (a *AttachmentDO) Transfer() *AttachmentDTO {
+	url := AttachmentURLPrefix + a.Key
+	markdown := "[" + a.FileName + "](" + url + ")"
+	if a.Kind == AttachmentImage {
+		markdown = "!" + markdown
+	}
+	return &AttachmentDTO{
+		Key:         a.Key,
+		URL:         url,
+		Markdown:    markdown,
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Kind:        a.Kind,
+		Size:        a.Size,
+		PostId:      a.PostId,
+		CommentId:   a.CommentId,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+// AttachmentResponse 上传附件响应
+type AttachmentResponse struct {
+	BaseResp
+	Attachment *AttachmentDTO `json:"attachment"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	testKeyA = "0f8fad5b-d9cb-469f-a165-70867728950e"
+	testKeyB = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+)
+
+func TestParseAttachmentRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "没有附件", []string{}},
+		{"image", "![封面](" + AttachmentURLPrefix + testKeyA + ")", []string{testKeyA}},
+		{"file and image", "[a.pdf](" + AttachmentURLPrefix + testKeyB + ") ![x](" + AttachmentURLPrefix + testKeyA + ")", []string{testKeyB, testKeyA}},
+		{"duplicates", AttachmentURLPrefix + testKeyA + " " + AttachmentURLPrefix + testKeyA, []string{testKeyA}},
+		{"signed url", "![x](" + AttachmentURLPrefix + testKeyA + "?expires=1&sig=ab)", []string{testKeyA}},
+		{"uppercase key", AttachmentURLPrefix + "0F8FAD5B-D9CB-469F-A165-70867728950E", []string{}},
+		{"other prefix", "/api/forum/files/" + testKeyA, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAttachmentRefs(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAttachmentRefs(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteAttachmentURLs(t *testing.T) {
+	sign := func(key string) string { return "signed:" + key }
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"image", `<img src="` + AttachmentURLPrefix + testKeyA + `" alt="a">`, `<img src="signed:` + testKeyA + `" alt="a">`},
+		{"link", `<a href="` + AttachmentURLPrefix + testKeyB + `">b</a>`, `<a href="signed:` + testKeyB + `">b</a>`},
+		{"existing query dropped", `<img src="` + AttachmentURLPrefix + testKeyA + `?expires=1&amp;sig=ab">`, `<img src="signed:` + testKeyA + `">`},
+		{"text untouched", `<p>` + AttachmentURLPrefix + testKeyA + `</p>`, `<p>` + AttachmentURLPrefix + testKeyA + `</p>`},
+		{"extra path untouched", `<a href="` + AttachmentURLPrefix + testKeyA + `/x">x</a>`, `<a href="` + AttachmentURLPrefix + testKeyA + `/x">x</a>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RewriteAttachmentURLs(tt.html, sign); got != tt.want {
+				t.Errorf("RewriteAttachmentURLs(%q) = %q, want %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package model
+
+import "encoding/json"
+
+// AuthorDTO 作者DTO
+type AuthorDTO struct {
+	Id      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"` // 别名/译名
+	Bio     string   `json:"bio"`
+}
+
+// AuthorDO 作者数据库对象
+type AuthorDO struct {
+	Id      int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name    string `gorm:"column:name;type:varchar(191);uniqueIndex" json:"name"`
+	Aliases string `gorm:"column:aliases" json:"aliases"` // json数组
+	Bio     string `gorm:"column:bio;type:text" json:"bio"`
+}
+
+func (a AuthorDO) TableName() string {
+	return "author"
+}
+
+// Transfer 将AuthorDO转换为AuthorDTO
+func (a *AuthorDO) Transfer() *AuthorDTO {
+	dto := &AuthorDTO{
+		Id:   a.Id,
+		Name: a.Name,
+		Bio:  a.Bio,
+	}
+	_ = json.Unmarshal([]byte(a.Aliases), &dto.Aliases)
+	return dto
+}
+
+// Transfer 将AuthorDTO转换为AuthorDO
+func (a *AuthorDTO) Transfer() *AuthorDO {
+	aliases, _ := json.Marshal(a.Aliases)
+	return &AuthorDO{
+		Id:      a.Id,
+		Name:    a.Name,
+		Aliases: string(aliases),
+		Bio:     a.Bio,
+	}
+}
+
+// BookAuthorDO 图书与作者的关联
+type BookAuthorDO struct {
+	Id       int64 `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	BookId   int64 `gorm:"column:book_id;uniqueIndex:uk_book_author" json:"book_id"`
+	AuthorId int64 `gorm:"column:author_id;uniqueIndex:uk_book_author;index" json:"author_id"`
+	Position int   `gorm:"column:position" json:"position"` // 署名顺序
+}
+
+func (b BookAuthorDO) TableName() string {
+	return "book_author"
+}
+
+// WorkDO 作品, 同一作品的不同版本(book_info)通过work_id关联
+type WorkDO struct {
+	Id       int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Title    string `gorm:"column:title;type:varchar(191);index:idx_title_author" json:"title"`
+	AuthorId int64  `gorm:"column:author_id;index:idx_title_author" json:"author_id"` // 第一作者
+}
+
+func (w WorkDO) TableName() string {
+	return "work"
+}
+
+// SeriesDTO 丛书/系列DTO
+type SeriesDTO struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name"`
+	Intro string `json:"intro"`
+}
+
+// SeriesDO 丛书/系列数据库对象
+type SeriesDO struct {
+	Id    int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name  string `gorm:"column:name" json:"name"`
+	Intro string `gorm:"column:intro;type:text" json:"intro"`
+}
+
+func (s SeriesDO) TableName() string {
+	return "series"
+}
+
+// Transfer 将SeriesDO转换为SeriesDTO
+func (s *SeriesDO) Transfer() *SeriesDTO {
+	return &SeriesDTO{Id: s.Id, Name: s.Name, Intro: s.Intro}
+}
+
+// Transfer 将SeriesDTO转换为SeriesDO
+func (s *SeriesDTO) Transfer() *SeriesDO {
+	return &SeriesDO{Id: s.Id, Name: s.Name, Intro: s.Intro}
+}
+
+// AuthorPageResponse 作者页返回
+type AuthorPageResponse struct {
+	BaseResp
+	Author *AuthorDTO     `json:"author"`
+	Books  []*BookInfoDTO `json:"books"`
+}
+
+// SeriesPageResponse 系列页返回, 图书按系列内序号排列
+type SeriesPageResponse struct {
+	BaseResp
+	Series *SeriesDTO     `json:"series"`
+	Books  []*BookInfoDTO `json:"books"`
+}
+
+// SetBookWorkRequest 修改图书所属作品请求, WorkId为0时拆成新作品
+type SetBookWorkRequest struct {
+	WorkId int64 `json:"work_id"`
+}
+
+// SetBookWorkResponse 修改图书所属作品返回
+type SetBookWorkResponse struct {
+	BaseResp
+	WorkId int64 `json:"work_id"`
+}
+
+// AddBookToSeriesRequest 将图书加入系列请求
+type AddBookToSeriesRequest struct {
+	BookId int64 `json:"book_id"`
+	Index  int   `json:"index"` // 系列内序号
+}
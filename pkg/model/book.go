@@ -15,7 +15,11 @@ type BookInfoDTO struct {
 	ISBN        string  `json:"ISBN"`
 	Score       float64 `json:"score"`
 	Intro       string  `json:"intro"`
-	Category    string  `json:"Category"` //分类
+	Category    string  `json:"Category"`     //分类
+	WorkId      int64   `json:"work_id"`      //所属作品, 同一作品的不同版本共享
+	SeriesId    int64   `json:"series_id"`    //所属系列
+	SeriesIndex int     `json:"series_index"` //系列内序号
+	CategoryId  int64   `json:"category_id"`  //规范分类
 }
 
 // BookInfoDO 书信息数据库对象
@@ -30,6 +34,10 @@ type BookInfoDO struct {
 	Score       float64 `gorm:"column:score" json:"score"`
 	Intro       string  `gorm:"column:intro" json:"intro"`
 	Category    string  `json:"Category"` //分类
+	WorkId      int64   `gorm:"column:work_id;index" json:"work_id"`
+	SeriesId    int64   `gorm:"column:series_id;index" json:"series_id"`
+	SeriesIndex int     `gorm:"column:series_index" json:"series_index"`
+	CategoryId  int64   `gorm:"column:category_id;index" json:"category_id"`
 }
 
 func (b BookInfoDO) TableName() string {
@@ -49,6 +57,10 @@ func (bookInfoDO *BookInfoDO) Transfer() *BookInfoDTO {
 		Score:       bookInfoDO.Score,
 		Intro:       bookInfoDO.Intro,
 		Category:    bookInfoDO.Category,
+		WorkId:      bookInfoDO.WorkId,
+		SeriesId:    bookInfoDO.SeriesId,
+		SeriesIndex: bookInfoDO.SeriesIndex,
+		CategoryId:  bookInfoDO.CategoryId,
 	}
 }
 
@@ -65,6 +77,10 @@ func (bookInfoDTO *BookInfoDTO) TransformToDO() *BookInfoDO {
 		Score:       bookInfoDTO.Score,
 		Intro:       bookInfoDTO.Intro,
 		Category:    bookInfoDTO.Category,
+		WorkId:      bookInfoDTO.WorkId,
+		SeriesId:    bookInfoDTO.SeriesId,
+		SeriesIndex: bookInfoDTO.SeriesIndex,
+		CategoryId:  bookInfoDTO.CategoryId,
 	}
 }
 
@@ -72,6 +88,7 @@ func (bookInfoDTO *BookInfoDTO) TransformToDO() *BookInfoDO {
 type BookSearchRequest struct {
 	Keyword  string `json:"Keyword"`  //关键词
 	Category string `json:"Category"` //分类
+	Sort     string `json:"Sort"`     //排序, rating: 按评分, 默认按相关度
 	Page     int    `json:"Page"`     //页码
 	PageSize int    `json:"PageSize"` //页码数量
 }
@@ -79,37 +96,59 @@ type BookSearchRequest struct {
 // 搜索返回请求结构体
 type SearchResponse struct {
 	BaseResp
-	Books []*BookInfoDTO `json:"books"`
+	Books           []*BookInfoDTO `json:"books"`
+	SuggestPurchase bool           `json:"suggest_purchase"` // 没有搜到时提示用户可以荐购
+}
+
+// CreateBookResponse 图书入库返回
+type CreateBookResponse struct {
+	BaseResp
+	BookId int64 `json:"book_id"`
 }
 
 // BookDetailResponse 图书详情返回
 type BookDetailResponse struct {
 	BaseResp
-	Data BookInfoDTO `json:"data"` // 图书详情数据
+	Data          BookInfoDTO    `json:"data"`           // 图书详情数据
+	Rating        *BookRatingDTO `json:"rating"`         // 评分聚合
+	Authors       []*AuthorDTO   `json:"authors"`        // 作者
+	Series        *SeriesDTO     `json:"series"`         // 所属系列
+	OtherEditions []*BookInfoDTO `json:"other_editions"` // 同一作品的其他版本
+	TopQuotes     []*QuoteDTO    `json:"top_quotes"`     // 热门书摘
 }
 
 // BookCommentDTO 书评DTO
 type BookCommentDTO struct {
-	Id          int64     `json:"id"`           //书评id
-	BookId      int64     `json:"book_id"`      //书的id
-	PublisherId int64     `json:"publisher_id"` //发布者id
-	Content     string    `json:"content"`      //书评内容
-	Score       float64   `json:"score"`        //评分
-	PostTime    time.Time `json:"post_time"`    //发布时间
-	Like        int64     `json:"like"`         //赞数
-	Dislike     int64     `json:"dislike"`      //踩数
-}
-
-// BookCommentDO 书评数据库对象
+	Id           int64         `json:"id"`            //书评id
+	BookId       int64         `json:"book_id"`       //书的id
+	PublisherId  int64         `json:"publisher_id"`  //发布者id
+	Content      string        `json:"content"`       //书评内容, 不含剧透标记
+	Spoiler      bool          `json:"spoiler"`       //整篇书评含剧透
+	SpoilerSpans []SpoilerSpan `json:"spoiler_spans"` //正文中的剧透区间
+	Score        float64       `json:"score"`         //评分
+	PostTime     time.Time     `json:"post_time"`     //发布时间
+	EditedAt     *time.Time    `json:"edited_at"`     //最后修改时间, 未修改过为空
+	Like         int64         `json:"like"`          //赞数
+	Dislike      int64         `json:"dislike"`       //踩数
+	MyVote       int           `json:"my_vote"`       //当前用户的投票, 1赞 -1踩 0未投
+	ReplyCount   int64         `json:"reply_count"`   //回复数
+}
+
+// BookCommentDO 书评数据库对象, 每个用户对每本书只有一条书评
 type BookCommentDO struct {
-	Id          int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	BookId      int64     `gorm:"column:book_id" json:"book_id"`
-	PublisherId int64     `gorm:"column:publisher_id" json:"publisher_id"`
-	Content     string    `gorm:"column:content" json:"content"`
-	Score       float64   `gorm:"column:score" json:"score"`
-	PostTime    time.Time `gorm:"column:post_time" json:"post_time"`
-	Like        int64     `gorm:"column:like" json:"like"`
-	Dislike     int64     `gorm:"column:dislike" json:"dislike"`
+	Id           int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	BookId       int64      `gorm:"column:book_id;uniqueIndex:uk_book_publisher;index:idx_book_score;index:idx_book_helpfulness" json:"book_id"`
+	PublisherId  int64      `gorm:"column:publisher_id;uniqueIndex:uk_book_publisher" json:"publisher_id"`
+	Content      string     `gorm:"column:content" json:"content"`
+	Spoiler      bool       `gorm:"column:spoiler" json:"spoiler"`
+	SpoilerSpans string     `gorm:"column:spoiler_spans;type:text" json:"spoiler_spans"` // 剧透区间的JSON
+	Score        float64    `gorm:"column:score;index:idx_book_score" json:"score"`
+	PostTime     time.Time  `gorm:"column:post_time" json:"post_time"`
+	EditedAt     *time.Time `gorm:"column:edited_at" json:"edited_at"`
+	Like         int64      `gorm:"column:like" json:"like"`
+	Dislike      int64      `gorm:"column:dislike" json:"dislike"`
+	Helpfulness  float64    `gorm:"column:helpfulness;index:idx_book_helpfulness" json:"helpfulness"` // 赞踩的Wilson下界, 投票时更新
+	ReplyCount   int64      `gorm:"column:reply_count" json:"reply_count"`
 }
 
 func (b BookCommentDO) TableName() string {
@@ -119,35 +158,43 @@ func (b BookCommentDO) TableName() string {
 // TransformToDO 将BookCommentDTO转换为BookCommentDO
 func (bookCommentDTO *BookCommentDTO) Transfer() *BookCommentDO {
 	return &BookCommentDO{
-		Id:          bookCommentDTO.Id,
-		BookId:      bookCommentDTO.BookId,
-		PublisherId: bookCommentDTO.PublisherId,
-		Content:     bookCommentDTO.Content,
-		Score:       bookCommentDTO.Score,
-		PostTime:    bookCommentDTO.PostTime,
-		Like:        bookCommentDTO.Like,
-		Dislike:     bookCommentDTO.Dislike,
+		Id:           bookCommentDTO.Id,
+		BookId:       bookCommentDTO.BookId,
+		PublisherId:  bookCommentDTO.PublisherId,
+		Content:      bookCommentDTO.Content,
+		Spoiler:      bookCommentDTO.Spoiler,
+		SpoilerSpans: encodeSpoilerSpans(bookCommentDTO.SpoilerSpans),
+		Score:        bookCommentDTO.Score,
+		PostTime:     bookCommentDTO.PostTime,
+		EditedAt:     bookCommentDTO.EditedAt,
+		Like:         bookCommentDTO.Like,
+		Dislike:      bookCommentDTO.Dislike,
 	}
 }
 
 // TransformToDTO 将BookCommentDO转换为BookCommentDTO
 func (bookCommentDO *BookCommentDO) TransformToDTO() *BookCommentDTO {
 	return &BookCommentDTO{
-		Id:          bookCommentDO.Id,
-		BookId:      bookCommentDO.BookId,
-		PublisherId: bookCommentDO.PublisherId,
-		Content:     bookCommentDO.Content,
-		Score:       bookCommentDO.Score,
-		PostTime:    bookCommentDO.PostTime,
-		Like:        bookCommentDO.Like,
-		Dislike:     bookCommentDO.Dislike,
+		Id:           bookCommentDO.Id,
+		BookId:       bookCommentDO.BookId,
+		PublisherId:  bookCommentDO.PublisherId,
+		Content:      bookCommentDO.Content,
+		Spoiler:      bookCommentDO.Spoiler,
+		SpoilerSpans: decodeSpoilerSpans(bookCommentDO.SpoilerSpans),
+		Score:        bookCommentDO.Score,
+		PostTime:     bookCommentDO.PostTime,
+		EditedAt:     bookCommentDO.EditedAt,
+		Like:         bookCommentDO.Like,
+		Dislike:      bookCommentDO.Dislike,
+		ReplyCount:   bookCommentDO.ReplyCount,
 	}
 }
 
 // CreatReviewRequest 书评发布请求结构体
 type CreatReviewRequest struct {
 	BookId      int64   `json:"book_id"`      //图书id
-	Content     string  `json:"content"`      //书评内容
+	Content     string  `json:"content"`      //书评内容, 用||包住剧透内容
+	Spoiler     bool    `json:"spoiler"`      //整篇书评含剧透
 	Score       float64 `json:"score"`        //评分
 	PublisherId int64   `json:"publisher_id"` //111发布者id，这个接口文档里没有，但是不是需要啊，我不确定因为我看书评结构里存这个了
 }
@@ -155,12 +202,23 @@ type CreatReviewRequest struct {
 // CreatReviewResponse 书评发布返回结构体
 type CreatReviewResponse struct {
 	BaseResp
+	Review  *BookCommentDTO `json:"review"`
+	Created bool            `json:"created"` // false表示修改了已有的书评
+}
+
+// UpdateReviewRequest 修改书评请求
+type UpdateReviewRequest struct {
+	Content string  `json:"content"` // 用||包住剧透内容
+	Spoiler bool    `json:"spoiler"`
+	Score   float64 `json:"score"`
 }
 
 // ReviewsResponse 获取书评的返回结构体
 type ReviewsResponse struct {
 	BaseResp
-	Reviews []BookCommentDTO `json:"book_reviews"`
+	Reviews    []BookCommentDTO `json:"book_reviews"`
+	Total      int64            `json:"total"`
+	NextCursor string           `json:"next_cursor"` // 下一页的游标, 为空表示没有更多
 }
 
 // ClickLikeResponse 点赞/踩返回结构体
@@ -168,4 +226,5 @@ type ClickLikeResponse struct {
 	BaseResp
 	Like    int64 `json:"like"`
 	Dislike int64 `json:"dislike"`
+	MyVote  int   `json:"my_vote"` // 操作后当前用户的投票, 再次点击同一按钮会取消
 }
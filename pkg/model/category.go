@@ -0,0 +1,138 @@
+package model
+
+import "time"
+
+// CategoryDTO 分类DTO
+type CategoryDTO struct {
+	Id        int64          `json:"id"`
+	Name      string         `json:"name"`
+	ParentId  int64          `json:"parent_id"`
+	ClcCode   string         `json:"clc_code"`   // 中图法分类号, 如 I247
+	BookCount int64          `json:"book_count"` // 含子分类的图书数
+	Children  []*CategoryDTO `json:"children,omitempty"`
+}
+
+// CategoryDO 分类数据库对象, Path为从根到自身的id路径, 如 /1/5/
+type CategoryDO struct {
+	Id       int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name     string `gorm:"column:name" json:"name"`
+	ParentId int64  `gorm:"column:parent_id;index" json:"parent_id"`
+	ClcCode  string `gorm:"column:clc_code" json:"clc_code"`
+	Path     string `gorm:"column:path;type:varchar(255);index" json:"path"`
+	Sort     int    `gorm:"column:sort" json:"sort"`
+}
+
+func (c CategoryDO) TableName() string {
+	return "category"
+}
+
+// Transfer 将CategoryDO转换为CategoryDTO
+func (c *CategoryDO) Transfer() *CategoryDTO {
+	return &CategoryDTO{
+		Id:       c.Id,
+		Name:     c.Name,
+		ParentId: c.ParentId,
+		ClcCode:  c.ClcCode,
+	}
+}
+
+// CategoryAliasDO 分类别名, 如"Sci-Fi""科幻""science fiction"都指向同一分类
+type CategoryAliasDO struct {
+	Id         int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Alias      string `gorm:"column:alias;type:varchar(191);uniqueIndex" json:"alias"` // 归一化后的别名
+	CategoryId int64  `gorm:"column:category_id;index" json:"category_id"`
+}
+
+func (c CategoryAliasDO) TableName() string {
+	return "category_alias"
+}
+
+// 用户标签在图书上的状态
+const (
+	BookTagVisible = "visible"
+	BookTagHidden  = "hidden" // 被版主隐藏
+)
+
+// TagDTO 用户标签DTO
+type TagDTO struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"` // 打过该标签的人数
+}
+
+// TagDO 用户标签数据库对象
+type TagDO struct {
+	Id     int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name   string `gorm:"column:name;type:varchar(191);uniqueIndex" json:"name"`
+	Banned bool   `gorm:"column:banned" json:"banned"` // 被版主全局禁用
+}
+
+func (t TagDO) TableName() string {
+	return "tag"
+}
+
+// BookTagDO 用户给图书打的标签
+type BookTagDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	BookId    int64     `gorm:"column:book_id;uniqueIndex:uk_book_tag_user" json:"book_id"`
+	TagId     int64     `gorm:"column:tag_id;uniqueIndex:uk_book_tag_user;index" json:"tag_id"`
+	UserId    int64     `gorm:"column:user_id;uniqueIndex:uk_book_tag_user" json:"user_id"`
+	Status    string    `gorm:"column:status" json:"status"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (b BookTagDO) TableName() string {
+	return "book_tag"
+}
+
+// CreateCategoryRequest 创建分类请求
+type CreateCategoryRequest struct {
+	Name     string   `json:"name"`
+	ParentId int64    `json:"parent_id"`
+	ClcCode  string   `json:"clc_code"`
+	Sort     int      `json:"sort"`
+	Aliases  []string `json:"aliases"`
+}
+
+// AddCategoryAliasRequest 添加分类别名请求
+type AddCategoryAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+// MergeCategoryRequest 合并分类请求, 将路径中的分类合并进Target
+type MergeCategoryRequest struct {
+	TargetId int64 `json:"target_id"`
+}
+
+// CategoryTreeResponse 分类树返回
+type CategoryTreeResponse struct {
+	BaseResp
+	Categories []*CategoryDTO `json:"categories"`
+}
+
+// CategoryBooksResponse 分类下图书返回
+type CategoryBooksResponse struct {
+	BaseResp
+	Category *CategoryDTO   `json:"category"`
+	Books    []*BookInfoDTO `json:"books"`
+	Total    int64          `json:"total"`
+}
+
+// AddBookTagRequest 给图书打标签请求
+type AddBookTagRequest struct {
+	Name string `json:"name"`
+}
+
+// BookTagsResponse 图书标签返回
+type BookTagsResponse struct {
+	BaseResp
+	Tags []*TagDTO `json:"tags"`
+}
+
+// TagBooksResponse 标签下图书返回
+type TagBooksResponse struct {
+	BaseResp
+	Tag   *TagDTO        `json:"tag"`
+	Books []*BookInfoDTO `json:"books"`
+	Total int64          `json:"total"`
+}
@@ -0,0 +1,222 @@
+package model
+
+import "time"
+
+// 读书会加入方式
+const (
+	ClubJoinOpen     = "open"     // 直接加入
+	ClubJoinApproval = "approval" // 需管理员审批
+)
+
+// 读书会成员角色
+const (
+	ClubRoleOwner     = "owner"
+	ClubRoleModerator = "moderator"
+	ClubRoleMember    = "member"
+)
+
+// 读书会成员状态
+const (
+	ClubMemberActive  = "active"
+	ClubMemberPending = "pending"
+)
+
+// 通知类型
+const (
+	NotifyClubJoinRequest = "club_join_request" // 有人申请加入
+	NotifyClubJoinResult  = "club_join_result"  // 加入申请的审批结果
+	NotifyClubEvent       = "club_event"        // 读书会活动公告
+)
+
+// ClubDTO 读书会DTO
+type ClubDTO struct {
+	Id          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	OwnerId     int64              `json:"owner_id"`
+	JoinPolicy  string             `json:"join_policy"`
+	MemberCount int64              `json:"member_count"`
+	CurrentBook *BookInfoDTO       `json:"current_book"`
+	Schedule    []*ClubScheduleDTO `json:"schedule,omitempty"`
+	MyRole      string             `json:"my_role"`   // 当前用户的角色, 非成员为空
+	MyStatus    string             `json:"my_status"` // 当前用户的成员状态, 非成员为空
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// ClubDO 读书会数据库对象
+type ClubDO struct {
+	Id            int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name          string    `gorm:"column:name" json:"name"`
+	Description   string    `gorm:"column:description;type:text" json:"description"`
+	OwnerId       int64     `gorm:"column:owner_id;index" json:"owner_id"`
+	JoinPolicy    string    `gorm:"column:join_policy" json:"join_policy"`
+	CurrentBookId int64     `gorm:"column:current_book_id" json:"current_book_id"`
+	MemberCount   int64     `gorm:"column:member_count" json:"member_count"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (c ClubDO) TableName() string {
+	return "club"
+}
+
+// Transfer 将ClubDO转换为ClubDTO
+func (c *ClubDO) Transfer() *ClubDTO {
+	return &ClubDTO{
+		Id:          c.Id,
+		Name:        c.Name,
+		Description: c.Description,
+		OwnerId:     c.OwnerId,
+		JoinPolicy:  c.JoinPolicy,
+		MemberCount: c.MemberCount,
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
+// ClubMemberDTO 读书会成员DTO
+type ClubMemberDTO struct {
+	UserId   int64     `json:"user_id"`
+	Name     string    `json:"name"`
+	Role     string    `json:"role"`
+	Status   string    `json:"status"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ClubMemberDO 读书会成员, 申请中的成员Status为pending
+type ClubMemberDO struct {
+	Id       int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ClubId   int64     `gorm:"column:club_id;uniqueIndex:uk_club_user" json:"club_id"`
+	UserId   int64     `gorm:"column:user_id;uniqueIndex:uk_club_user;index" json:"user_id"`
+	Role     string    `gorm:"column:role" json:"role"`
+	Status   string    `gorm:"column:status" json:"status"`
+	JoinedAt time.Time `gorm:"column:joined_at" json:"joined_at"`
+}
+
+func (c ClubMemberDO) TableName() string {
+	return "club_member"
+}
+
+// IsModerator 是否可以管理读书会(群主或管理员)
+func (c *ClubMemberDO) IsModerator() bool {
+	return c.Status == ClubMemberActive && (c.Role == ClubRoleOwner || c.Role == ClubRoleModerator)
+}
+
+// ClubScheduleDTO 阅读计划DTO
+type ClubScheduleDTO struct {
+	Id        int64        `json:"id"`
+	ClubId    int64        `json:"club_id"`
+	Book      *BookInfoDTO `json:"book"`
+	Title     string       `json:"title"` // 本阶段的阅读内容, 如"第1-5章"
+	StartDate time.Time    `json:"start_date"`
+	EndDate   time.Time    `json:"end_date"`
+}
+
+// ClubScheduleDO 阅读计划中的一个阶段
+type ClubScheduleDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ClubId    int64     `gorm:"column:club_id;index" json:"club_id"`
+	BookId    int64     `gorm:"column:book_id" json:"book_id"`
+	Title     string    `gorm:"column:title" json:"title"`
+	StartDate time.Time `gorm:"column:start_date" json:"start_date"`
+	EndDate   time.Time `gorm:"column:end_date" json:"end_date"`
+}
+
+func (c ClubScheduleDO) TableName() string {
+	return "club_schedule"
+}
+
+// ClubEventDTO 读书会活动DTO
+type ClubEventDTO struct {
+	Id        int64     `json:"id"`
+	ClubId    int64     `json:"club_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Location  string    `json:"location"`
+	StartTime time.Time `json:"start_time"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ClubEventDO 读书会活动公告
+type ClubEventDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ClubId    int64     `gorm:"column:club_id;index" json:"club_id"`
+	Title     string    `gorm:"column:title" json:"title"`
+	Content   string    `gorm:"column:content;type:text" json:"content"`
+	Location  string    `gorm:"column:location" json:"location"`
+	StartTime time.Time `gorm:"column:start_time" json:"start_time"`
+	CreatedBy int64     `gorm:"column:created_by" json:"created_by"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (c ClubEventDO) TableName() string {
+	return "club_event"
+}
+
+// Transfer 将ClubEventDO转换为ClubEventDTO
+func (c *ClubEventDO) Transfer() *ClubEventDTO {
+	return &ClubEventDTO{
+		Id:        c.Id,
+		ClubId:    c.ClubId,
+		Title:     c.Title,
+		Content:   c.Content,
+		Location:  c.Location,
+		StartTime: c.StartTime,
+		CreatedBy: c.CreatedBy,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// CreateClubRequest 创建/修改读书会请求
+type CreateClubRequest struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	JoinPolicy    string `json:"join_policy"`
+	CurrentBookId int64  `json:"current_book_id"`
+}
+
+// SetClubMemberRoleRequest 设置成员角色请求
+type SetClubMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// CreateClubScheduleRequest 添加阅读计划请求
+type CreateClubScheduleRequest struct {
+	BookId    int64     `json:"book_id"`
+	Title     string    `json:"title"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+}
+
+// CreateClubEventRequest 发布活动请求
+type CreateClubEventRequest struct {
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Location  string    `json:"location"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// ClubResponse 读书会详情返回
+type ClubResponse struct {
+	BaseResp
+	Club *ClubDTO `json:"club"`
+}
+
+// ClubListResponse 读书会列表返回
+type ClubListResponse struct {
+	BaseResp
+	Clubs []*ClubDTO `json:"clubs"`
+	Total int64      `json:"total"`
+}
+
+// ClubMemberListResponse 成员列表返回
+type ClubMemberListResponse struct {
+	BaseResp
+	Members []*ClubMemberDTO `json:"members"`
+	Total   int64            `json:"total"`
+}
+
+// ClubEventListResponse 活动列表返回
+type ClubEventListResponse struct {
+	BaseResp
+	Events []*ClubEventDTO `json:"events"`
+}
@@ -36,9 +36,61 @@ type ESConfig struct {
 	Password  string
 }
 
+// MinioConfig 对象存储配置
+type MinioConfig struct {
+	Endpoint        string // 带http(s)://前缀时按前缀决定是否使用HTTPS
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// SeatConfig 阅览室座位预约配置
+type SeatConfig struct {
+	CheckInBeforeMinutes int // 开始前多少分钟可以签到
+	CheckInAfterMinutes  int // 开始后多少分钟内未签到视为爽约
+	DailyMaxBookings     int // 每人每天最多预约次数
+	DailyMaxHours        int // 每人每天最多预约时长(小时)
+	ReleaseIntervalSec   int // 爽约释放任务的扫描间隔(秒)
+}
+
+// AchievementConfig 阅读挑战与徽章配置
+type AchievementConfig struct {
+	DefaultYearlyGoal int // 用户未设置时的年度阅读目标(本)
+	MaxYearlyGoal     int // 年度阅读目标上限
+	QueueSize         int // 徽章规则引擎的事件队列长度
+}
+
+// ForumConfig 论坛配置
+type ForumConfig struct {
+	CommentEditWindowMinutes int // 评论发布后多少分钟内可以编辑, 0表示不限制
+	MaxDraftsPerUser         int // 每人最多保存的草稿数
+	DraftRetentionDays       int // 草稿多少天没有更新就清理
+}
+
+// AttachmentConfig 论坛附件配置
+type AttachmentConfig struct {
+	Bucket          string // 附件所在的存储桶
+	MaxImageSizeMB  int    // 图片大小上限(MB)
+	MaxFileSizeMB   int    // 其他文件大小上限(MB)
+	PendingTTLHours int    // 上传后多少小时内没有被已发布的帖子或评论引用就回收
+	GCIntervalMin   int    // 回收任务的扫描间隔(分钟)
+	SignSecret      string // 附件临时地址的签名密钥, 为空时每次启动随机生成, 多实例部署需配置
+	URLTTLMinutes   int    // 附件临时地址的有效期(分钟)
+}
+
+// AuthConfig 权限配置
+type AuthConfig struct {
+	Admins []string // 启动时授予管理员角色的用户名
+}
+
 type AppConfig struct {
-	DB     *DBConfig
-	Log    *LogConfig
-	Server *ServerConfig
-	ES     *ESConfig
+	DB          *DBConfig
+	Log         *LogConfig
+	Server      *ServerConfig
+	ES          *ESConfig
+	Minio       *MinioConfig
+	Seat        *SeatConfig
+	Achievement *AchievementConfig
+	Forum       *ForumConfig
+	Attachment  *AttachmentConfig
+	Auth        *AuthConfig
 }
@@ -7,11 +7,19 @@ type Condition struct {
 	Value  string
 }
 
+// SortField 排序字段
+type SortField struct {
+	Field        string
+	Desc         bool
+	UnmappedType string // 字段尚未出现在映射中时按该类型排序, 不设置时ES会拒绝查询
+}
+
 type EsQueryCondition struct {
 	Conditions         []Condition
 	MinimumShouldMatch int
 	From               int
 	Size               int
+	Sorts              []SortField // 为空时按相关度排序
 }
 
 // EsModel 定义了一个ES模型
@@ -59,6 +67,25 @@ type BookInfoES struct {
 	Score       float64 `json:"score"`
 	Intro       string  `json:"intro"`
 	Category    string  `json:"category"`
+	// 评分聚合, 用于按评分排序
+	RatingCount   int64   `json:"rating_count"`
+	WeightedScore float64 `json:"weighted_score"`
+}
+
+// TransformToES 将BookInfoDTO转换为BookInfoES
+func (bookInfoDTO *BookInfoDTO) TransformToES() *BookInfoES {
+	return &BookInfoES{
+		ID:          bookInfoDTO.Id,
+		Name:        bookInfoDTO.Name,
+		Author:      bookInfoDTO.Author,
+		CoverImage:  bookInfoDTO.CoverImage,
+		Publisher:   bookInfoDTO.Publisher,
+		PublishYear: bookInfoDTO.PublishYear,
+		ISBN:        bookInfoDTO.ISBN,
+		Score:       bookInfoDTO.Score,
+		Intro:       bookInfoDTO.Intro,
+		Category:    bookInfoDTO.Category,
+	}
 }
 
 // SetScore 设置评分
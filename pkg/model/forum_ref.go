@@ -0,0 +1,103 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NotifyForumMention 通知类型: 在帖子或评论中被@, RelatedId为帖子ID
+const NotifyForumMention = "forum_mention"
+
+// 正文中每类引用最多处理的个数, 超出的忽略
+const (
+	MaxMentionsPerBody = 10
+	MaxHashtagsPerBody = 10
+	MaxBookRefsPerBody = 20
+)
+
+var (
+	// @用户名, 前面不能是字母数字, 避免匹配邮箱
+	mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([\p{L}\p{N}_.\-]{1,32})`)
+	// #话题, 前面不能是字母数字或#&/, 避免匹配Markdown标题、HTML实体和链接锚点
+	hashtagRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&/])#([\p{L}\p{N}_]{1,32})`)
+	// [[book:123]] 图书引用
+	bookRefRe = regexp.MustCompile(`\[\[book:(\d{1,18})\]\]`)
+)
+
+// ParseMentions 解析正文中@的用户名, 去重后按出现顺序返回
+func ParseMentions(content string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, m := range mentionRe.FindAllStringSubmatch(content, -1) {
+		// 句末的点不算用户名
+		name := strings.TrimRight(m[1], ".-")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) >= MaxMentionsPerBody {
+			break
+		}
+	}
+	return names
+}
+
+// ParseHashtags 解析正文中的#话题, 统一转为小写, 去重后按出现顺序返回
+func ParseHashtags(content string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, m := range hashtagRe.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(m[1])
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) >= MaxHashtagsPerBody {
+			break
+		}
+	}
+	return tags
+}
+
+// ParseBookRefs 解析正文中[[book:123]]引用的图书ID, 去重后按出现顺序返回
+func ParseBookRefs(content string) []int64 {
+	ids := []int64{}
+	seen := map[int64]bool{}
+	for _, m := range bookRefRe.FindAllStringSubmatch(content, -1) {
+		id, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || id <= 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		if len(ids) >= MaxBookRefsPerBody {
+			break
+		}
+	}
+	return ids
+}
+
+// PostHashtagDO 帖子和评论中的话题索引, 复用图书标签的标签表; 帖子正文的CommentId为0
+type PostHashtagDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TagId     int64     `gorm:"column:tag_id;uniqueIndex:uk_tag_post_comment" json:"tag_id"`
+	PostId    int64     `gorm:"column:post_id;uniqueIndex:uk_tag_post_comment;index" json:"post_id"`
+	CommentId int64     `gorm:"column:comment_id;uniqueIndex:uk_tag_post_comment" json:"comment_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (p PostHashtagDO) TableName() string {
+	return "post_hashtag"
+}
+
+// HashtagPostsResponse 话题页响应, 帖子按最近一次使用该话题的时间倒序
+type HashtagPostsResponse struct {
+	BaseResp
+	Tag   *TagDTO    `json:"tag"`
+	Posts []*PostDTO `json:"posts"`
+	Total int64      `json:"total"`
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "没有提到任何人", []string{}},
+		{"basic", "hi @alice, and @bob", []string{"alice", "bob"}},
+		{"chinese name", "感谢 @小明。", []string{"小明"}},
+		{"trailing dot", "ask @alice.", []string{"alice"}},
+		{"email ignored", "mail bob@x.com", []string{}},
+		{"duplicates", "@alice @alice", []string{"alice"}},
+		{"line start", "@alice\n@bob", []string{"alice", "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMentions(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+
+	var b strings.Builder
+	for i := 0; i < MaxMentionsPerBody+5; i++ {
+		b.WriteString(" @user" + strconv.Itoa(i))
+	}
+	if got := ParseMentions(b.String()); len(got) != MaxMentionsPerBody {
+		t.Errorf("got %d mentions, want at most %d", len(got), MaxMentionsPerBody)
+	}
+}
+
+func TestParseHashtags(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "no tags", []string{}},
+		{"lowercased and deduplicated", "#Go and #go", []string{"go"}},
+		{"chinese", "推荐 #科幻 #推理小说", []string{"科幻", "推理小说"}},
+		{"markdown heading ignored", "# Title\n## Sub", []string{}},
+		{"html entity ignored", "it&#39;s", []string{}},
+		{"link anchor ignored", "[a](/x#frag)", []string{}},
+		{"inside word ignored", "C#sharp", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseHashtags(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHashtags(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBookRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []int64
+	}{
+		{"none", "[[book:]] [book:1]", []int64{}},
+		{"ordered and deduplicated", "see [[book:12]] and [[book:7]] [[book:12]]", []int64{12, 7}},
+		{"zero ignored", "[[book:0]]", []int64{}},
+		{"too long ignored", "[[book:1234567890123456789]]", []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseBookRefs(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBookRefs(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"strconv"
+	"time"
+
+	"yujian-backend/pkg/utils"
+)
+
+// 论坛搜索的结果类型
+const (
+	ForumSearchPost    = "post"
+	ForumSearchComment = "comment"
+)
+
+// 论坛搜索的排序方式
+const (
+	ForumSortRelevance = "relevance" // 按相关度
+	ForumSortRecent    = "recent"    // 按发布时间倒序
+)
+
+// PostCommentEsModel 帖子评论ES模型, 帖子的读书会和分类冗余进来用于过滤
+type PostCommentEsModel struct {
+	Id         string    `json:"id"`
+	PostId     int64     `json:"post_id"`
+	Content    string    `json:"content"`
+	AuthorId   int64     `json:"author_id"`
+	ClubId     int64     `json:"club_id"`
+	Category   string    `json:"category"`
+	CategoryId int64     `json:"category_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Score      float64   `json:"score"`
+}
+
+// NewPostCommentEsModel 根据评论和所属帖子构建评论的ES文档
+func NewPostCommentEsModel(comment *PostCommentDO, post *PostDO) *PostCommentEsModel {
+	return &PostCommentEsModel{
+		Id:         strconv.FormatInt(comment.Id, 10),
+		PostId:     comment.PostId,
+		Content:    utils.MarkdownToText(comment.Content),
+		AuthorId:   comment.AuthorId,
+		ClubId:     post.ClubId,
+		Category:   post.Category,
+		CategoryId: post.CategoryId,
+		CreatedAt:  comment.EditTime,
+	}
+}
+
+func (p *PostCommentEsModel) GetID() string {
+	return p.Id
+}
+
+func (p *PostCommentEsModel) SetScore(score float64) {
+	p.Score = score
+}
+
+func (p *PostCommentEsModel) GetScore() float64 {
+	return p.Score
+}
+
+func (p *PostCommentEsModel) GetIndexName() string {
+	return "post_comment"
+}
+
+func (p *PostCommentEsModel) GetContent() string {
+	return p.Content
+}
+
+func (p *PostCommentEsModel) GetTitle() string {
+	return ""
+}
+
+// ForumSearchQuery 论坛搜索条件
+type ForumSearchQuery struct {
+	Keyword    string
+	Type       string // post/comment, 为空时都搜
+	Category   string // 未能归一到规范分类时按原文过滤
+	CategoryId int64
+	AuthorId   int64
+	StartTime  time.Time
+	EndTime    time.Time
+	ClubIds    []int64 // 可见的读书会, 公开帖子总是可见
+	Sort       string
+	From       int
+	Size       int
+}
+
+// ForumSearchHit 论坛搜索的一条结果
+type ForumSearchHit struct {
+	Type       string    `json:"type"` // post或comment
+	PostId     int64     `json:"post_id"`
+	CommentId  int64     `json:"comment_id"` // 命中评论时填写
+	Title      string    `json:"title"`      // 帖子标题(已转义的HTML), 命中评论时为所在帖子的标题
+	Highlights []string  `json:"highlights"` // 命中的片段(已转义的HTML), 关键词用<em>包住
+	AuthorId   int64     `json:"author_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Score      float64   `json:"score"`
+}
+
+// ForumSearchResponse 论坛搜索响应
+type ForumSearchResponse struct {
+	BaseResp
+	Hits  []*ForumSearchHit `json:"hits"`
+	Total int64             `json:"total"`
+}
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// 通知类型
+const (
+	NotifySuggestionReviewed  = "suggestion_reviewed"  // 荐购审核结果
+	NotifySuggestionFulfilled = "suggestion_fulfilled" // 荐购图书已到馆
+)
+
+// NotificationDTO 站内通知DTO
+type NotificationDTO struct {
+	Id        int64     `json:"id"`
+	UserId    int64     `json:"user_id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	RelatedId int64     `json:"related_id"` // 关联对象ID, 含义由Type决定
+	IsRead    bool      `json:"is_read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationDO 站内通知数据库对象
+type NotificationDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId    int64     `gorm:"column:user_id;index:idx_user_read" json:"user_id"`
+	Type      string    `gorm:"column:type" json:"type"`
+	Title     string    `gorm:"column:title" json:"title"`
+	Content   string    `gorm:"column:content" json:"content"`
+	RelatedId int64     `gorm:"column:related_id" json:"related_id"`
+	IsRead    bool      `gorm:"column:is_read;index:idx_user_read" json:"is_read"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (n NotificationDO) TableName() string {
+	return "notification"
+}
+
+// Transfer 将NotificationDO转换为NotificationDTO
+func (n *NotificationDO) Transfer() *NotificationDTO {
+	return &NotificationDTO{
+		Id:        n.Id,
+		UserId:    n.UserId,
+		Type:      n.Type,
+		Title:     n.Title,
+		Content:   n.Content,
+		RelatedId: n.RelatedId,
+		IsRead:    n.IsRead,
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+// NotificationListResponse 通知列表返回
+type NotificationListResponse struct {
+	BaseResp
+	Notifications []*NotificationDTO `json:"notifications"`
+	Unread        int64              `json:"unread"`
+}
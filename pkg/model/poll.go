@@ -0,0 +1,153 @@
+package model
+
+import "time"
+
+// 帖子投票的选项数限制
+const (
+	MinPollOptions = 2
+	MaxPollOptions = 20
+)
+
+// PostPollDTO 帖子投票DTO
+type PostPollDTO struct {
+	Id             int64                `json:"id"`
+	PostId         int64                `json:"post_id"`
+	Question       string               `json:"question"`
+	MultipleChoice bool                 `json:"multiple_choice"`
+	MaxChoices     int                  `json:"max_choices"` // 多选时每人最多选几项
+	Anonymous      bool                 `json:"anonymous"`   // 匿名投票不公开投票人
+	ClosesAt       *time.Time           `json:"closes_at"`   // 截止时间, 为空时直到手动关闭
+	Closed         bool                 `json:"closed"`
+	VoterCount     int64                `json:"voter_count"`
+	Options        []*PostPollOptionDTO `json:"options"`
+	MyVotes        []int64              `json:"my_votes"` // 当前用户选择的选项, 未投票为空
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+// PostPollOptionDTO 帖子投票选项DTO
+type PostPollOptionDTO struct {
+	Id        int64      `json:"id"`
+	Text      string     `json:"text"`
+	BookId    int64      `json:"book_id"` // 关联的图书, 0表示普通选项
+	BookName  string     `json:"book_name"`
+	BookCover string     `json:"book_cover"`
+	VoteCount int64      `json:"vote_count"`
+	Voters    []*UserDTO `json:"voters,omitempty"` // 公开投票时的投票人
+}
+
+// PostPollDO 帖子投票DO, 每个帖子最多一个投票
+type PostPollDO struct {
+	Id             int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PostId         int64      `gorm:"column:post_id;uniqueIndex" json:"post_id"`
+	Question       string     `gorm:"column:question" json:"question"`
+	MultipleChoice bool       `gorm:"column:multiple_choice" json:"multiple_choice"`
+	MaxChoices     int        `gorm:"column:max_choices" json:"max_choices"`
+	Anonymous      bool       `gorm:"column:anonymous" json:"anonymous"`
+	ClosesAt       *time.Time `gorm:"column:closes_at" json:"closes_at"`
+	ClosedAt       *time.Time `gorm:"column:closed_at" json:"closed_at"` // 手动关闭的时间
+	VoterCount     int64      `gorm:"column:voter_count" json:"voter_count"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+func (p PostPollDO) TableName() string {
+	return "post_poll"
+}
+
+// IsClosed 投票是否已经结束
+func (p *PostPollDO) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || (p.ClosesAt != nil && !now.Before(*p.ClosesAt))
+}
+
+// Transfer 将PostPollDO转换为PostPollDTO, 不含选项
+func (p *PostPollDO) Transfer() *PostPollDTO {
+	return &PostPollDTO{
+		Id:             p.Id,
+		PostId:         p.PostId,
+		Question:       p.Question,
+		MultipleChoice: p.MultipleChoice,
+		MaxChoices:     p.MaxChoices,
+		Anonymous:      p.Anonymous,
+		ClosesAt:       p.ClosesAt,
+		Closed:         p.IsClosed(time.Now()),
+		VoterCount:     p.VoterCount,
+		Options:        []*PostPollOptionDTO{},
+		MyVotes:        []int64{},
+		CreatedAt:      p.CreatedAt,
+	}
+}
+
+// PostPollOptionDO 帖子投票选项DO
+type PostPollOptionDO struct {
+	Id        int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PollId    int64  `gorm:"column:poll_id;index" json:"poll_id"`
+	Position  int    `gorm:"column:position" json:"position"`
+	Text      string `gorm:"column:text" json:"text"`
+	BookId    int64  `gorm:"column:book_id" json:"book_id"`
+	VoteCount int64  `gorm:"column:vote_count" json:"vote_count"`
+}
+
+func (p PostPollOptionDO) TableName() string {
+	return "post_poll_option"
+}
+
+// Transfer 将PostPollOptionDO转换为PostPollOptionDTO
+func (p *PostPollOptionDO) Transfer() *PostPollOptionDTO {
+	return &PostPollOptionDTO{
+		Id:        p.Id,
+		Text:      p.Text,
+		BookId:    p.BookId,
+		VoteCount: p.VoteCount,
+	}
+}
+
+// PostPollBallotDO 用户在一个投票中的选票, 唯一索引保证每人只能投一次
+type PostPollBallotDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PollId    int64     `gorm:"column:poll_id;uniqueIndex:uk_poll_user" json:"poll_id"`
+	UserId    int64     `gorm:"column:user_id;uniqueIndex:uk_poll_user" json:"user_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (p PostPollBallotDO) TableName() string {
+	return "post_poll_ballot"
+}
+
+// PostPollVoteDO 选票中选择的一个选项
+type PostPollVoteDO struct {
+	Id       int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PollId   int64  `gorm:"column:poll_id;index" json:"poll_id"`
+	OptionId int64  `gorm:"column:option_id;uniqueIndex:uk_option_user" json:"option_id"`
+	UserId   int64  `gorm:"column:user_id;uniqueIndex:uk_option_user" json:"user_id"`
+	UserName string `gorm:"column:user_name" json:"user_name"`
+}
+
+func (p PostPollVoteDO) TableName() string {
+	return "post_poll_vote"
+}
+
+// CreatePostPollRequest 创建帖子投票请求
+type CreatePostPollRequest struct {
+	Question       string                  `json:"question"`
+	MultipleChoice bool                    `json:"multiple_choice"`
+	MaxChoices     int                     `json:"max_choices"` // 多选时每人最多选几项, 0表示不限
+	Anonymous      bool                    `json:"anonymous"`
+	ClosesAt       *time.Time              `json:"closes_at"`
+	Options        []PostPollOptionRequest `json:"options"` // 用值类型, null选项按缺少文字拒绝
+}
+
+// PostPollOptionRequest 投票选项, 关联图书时可以不填文字, 默认使用书名
+type PostPollOptionRequest struct {
+	Text   string `json:"text"`
+	BookId int64  `json:"book_id"`
+}
+
+// VotePostPollRequest 投票请求
+type VotePostPollRequest struct {
+	OptionIds []int64 `json:"option_ids"`
+}
+
+// PostPollResponse 帖子投票响应
+type PostPollResponse struct {
+	BaseResp
+	Poll *PostPollDTO `json:"poll"`
+}
@@ -1,48 +1,76 @@
 package model
 
-import (
-	"encoding/json"
-	"time"
-)
+import "time"
 
 // PostDTO 帖子DTO
 type PostDTO struct {
-	Id            int64             `json:"id"`
-	Author        *UserDTO          `json:"author"`
-	Title         string            `json:"title"`
-	EditTime      time.Time         `json:"edit_time"`
-	Category      string            `json:"category"`
-	Comments      []*PostCommentDTO `json:"comments"`
-	LikeUserIds   []int64           `json:"like_user_ids"`
-	UnlikeUserIds []int64           `json:"unlike_user_ids"`
+	Id           int64         `json:"id"`
+	Author       *UserDTO      `json:"author"`
+	Title        string        `json:"title"`
+	EditTime     time.Time     `json:"edit_time"`
+	Category     string        `json:"category"`
+	CategoryId   int64         `json:"category_id"`
+	ClubId       int64         `json:"club_id"`       // 所属读书会, 0为公开帖子
+	Spoiler      bool          `json:"spoiler"`       // 整篇帖子含剧透
+	SpoilerSpans []SpoilerSpan `json:"spoiler_spans"` // 正文中的剧透区间
+	Preview      string        `json:"preview"`       // 正文的纯文本摘要, 不含剧透
+	HasPoll      bool          `json:"has_poll"`      // 带投票, 投票通过单独的接口获取
+	EditedAt     *time.Time    `json:"edited_at"`     // 最后修改时间, 未修改过为空
+	Deleted      bool          `json:"deleted"`       // 已删除的帖子只保留占位
+	DeletedAt    *time.Time    `json:"deleted_at"`
+	DeletedBy    int64         `json:"deleted_by"`
+	CommentCount int64         `json:"comment_count"` // 评论数(含回复), 评论通过单独的分页接口获取
+	LikeCount    int64         `json:"like_count"`
+	DislikeCount int64         `json:"dislike_count"`
+	MyVote       int           `json:"my_vote"` // 当前用户的投票, 1赞 -1踩 0未投
 }
 
 // TransformToDO 将PostDTO转换为PostDO
 func (p *PostDTO) TransformToDO() *PostDO {
-	likeIds, _ := json.Marshal(p.LikeUserIds)
-	unlikeIds, _ := json.Marshal(p.UnlikeUserIds)
 	return &PostDO{
-		Id:            p.Id,
-		AuthorId:      p.Author.Id,
-		AuthorName:    p.Author.Name,
-		Title:         p.Title,
-		EditTime:      p.EditTime,
-		Category:      p.Category,
-		LikeUserIds:   string(likeIds),
-		UnlikeUserIds: string(unlikeIds),
+		Id:           p.Id,
+		AuthorId:     p.Author.Id,
+		AuthorName:   p.Author.Name,
+		Title:        p.Title,
+		EditTime:     p.EditTime,
+		Category:     p.Category,
+		CategoryId:   p.CategoryId,
+		ClubId:       p.ClubId,
+		Spoiler:      p.Spoiler,
+		SpoilerSpans: encodeSpoilerSpans(p.SpoilerSpans),
+		Preview:      p.Preview,
+		HasPoll:      p.HasPoll,
+		EditedAt:     p.EditedAt,
+		Deleted:      p.Deleted,
+		DeletedAt:    p.DeletedAt,
+		DeletedBy:    p.DeletedBy,
+		CommentCount: p.CommentCount,
+		LikeCount:    p.LikeCount,
+		DislikeCount: p.DislikeCount,
 	}
 }
 
 // PostDO 帖子DO
 type PostDO struct {
-	Id            int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	AuthorId      int64     `gorm:"column:author_id" json:"author_id"`
-	AuthorName    string    `gorm:"column:author_name" json:"author_name"`
-	Title         string    `gorm:"column:title" json:"title"`
-	Category      string    `gorm:"column:category" json:"category"`
-	EditTime      time.Time `gorm:"column:edit_time" json:"edit_time"`
-	LikeUserIds   string    `gorm:"like_user_ids" json:"likes"`
-	UnlikeUserIds string    `gorm:"unlike_user_ids" json:"unlike_user_ids"`
+	Id           int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	AuthorId     int64      `gorm:"column:author_id" json:"author_id"`
+	AuthorName   string     `gorm:"column:author_name" json:"author_name"`
+	Title        string     `gorm:"column:title" json:"title"`
+	Category     string     `gorm:"column:category" json:"category"`
+	CategoryId   int64      `gorm:"column:category_id;index" json:"category_id"`
+	ClubId       int64      `gorm:"column:club_id;index" json:"club_id"`
+	Spoiler      bool       `gorm:"column:spoiler" json:"spoiler"`
+	SpoilerSpans string     `gorm:"column:spoiler_spans;type:text" json:"spoiler_spans"` // 正文剧透区间的JSON, 正文在ES中
+	Preview      string     `gorm:"column:preview;size:512" json:"preview"`
+	HasPoll      bool       `gorm:"column:has_poll" json:"has_poll"`
+	EditTime     time.Time  `gorm:"column:edit_time" json:"edit_time"`
+	EditedAt     *time.Time `gorm:"column:edited_at" json:"edited_at"`
+	Deleted      bool       `gorm:"column:deleted;index" json:"deleted"`
+	DeletedAt    *time.Time `gorm:"column:deleted_at" json:"deleted_at"`
+	DeletedBy    int64      `gorm:"column:deleted_by" json:"deleted_by"` // 删除操作人, 作者本人或版主
+	CommentCount int64      `gorm:"column:comment_count" json:"comment_count"`
+	LikeCount    int64      `gorm:"column:like_count" json:"like_count"`
+	DislikeCount int64      `gorm:"column:dislike_count" json:"dislike_count"`
 }
 
 func (p PostDO) TableName() string {
@@ -50,26 +78,97 @@ func (p PostDO) TableName() string {
 }
 
 // TransformToDTO 将PostDO转换为PostDTO
-func (p *PostDO) TransformToDTO(userDTO *UserDTO, comments []*PostCommentDTO) *PostDTO {
-	dto := &PostDTO{
-		Id:       p.Id,
-		Author:   userDTO,
-		Title:    p.Title,
-		EditTime: p.EditTime,
-		Comments: comments,
-		Category: p.Category,
+func (p *PostDO) TransformToDTO(userDTO *UserDTO) *PostDTO {
+	return &PostDTO{
+		Id:           p.Id,
+		Author:       userDTO,
+		Title:        p.Title,
+		EditTime:     p.EditTime,
+		Category:     p.Category,
+		CategoryId:   p.CategoryId,
+		ClubId:       p.ClubId,
+		Spoiler:      p.Spoiler,
+		SpoilerSpans: decodeSpoilerSpans(p.SpoilerSpans),
+		Preview:      p.Preview,
+		HasPoll:      p.HasPoll,
+		EditedAt:     p.EditedAt,
+		Deleted:      p.Deleted,
+		DeletedAt:    p.DeletedAt,
+		DeletedBy:    p.DeletedBy,
+		CommentCount: p.CommentCount,
+		LikeCount:    p.LikeCount,
+		DislikeCount: p.DislikeCount,
 	}
-	_ = json.Unmarshal([]byte(p.UnlikeUserIds), &dto.UnlikeUserIds)
-	_ = json.Unmarshal([]byte(p.LikeUserIds), &dto.LikeUserIds)
-	return dto
 }
 
-// PostEsModel 帖子ES模型
+// Tombstone 已删除的帖子对外只保留占位, 不返回标题和剧透信息
+func (p *PostDTO) Tombstone() {
+	p.Title = PostTombstoneText
+	p.Preview = ""
+	p.Spoiler = false
+	p.SpoilerSpans = []SpoilerSpan{}
+}
+
+// PostTombstoneText 已删除帖子的标题和正文占位
+const PostTombstoneText = "[该帖子已删除]"
+
+// PostPreviewLength 帖子摘要的最大字符数
+const PostPreviewLength = 100
+
+// 帖子和评论的投票
+const (
+	PostVoteLike    = 1  // 赞
+	PostVoteDislike = -1 // 踩
+)
+
+// PostVoteDO 用户对帖子的投票, 每个用户对每个帖子只有一票
+type PostVoteDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PostId    int64     `gorm:"column:post_id;uniqueIndex:uk_post_user" json:"post_id"`
+	UserId    int64     `gorm:"column:user_id;uniqueIndex:uk_post_user" json:"user_id"`
+	Value     int       `gorm:"column:value" json:"value"` // PostVoteLike或PostVoteDislike
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (p PostVoteDO) TableName() string {
+	return "post_vote"
+}
+
+// PostCommentVoteDO 用户对帖子评论的投票, 每个用户对每条评论只有一票
+type PostCommentVoteDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CommentId int64     `gorm:"column:comment_id;uniqueIndex:uk_comment_user" json:"comment_id"`
+	UserId    int64     `gorm:"column:user_id;uniqueIndex:uk_comment_user" json:"user_id"`
+	Value     int       `gorm:"column:value" json:"value"` // PostVoteLike或PostVoteDislike
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (p PostCommentVoteDO) TableName() string {
+	return "post_comment_vote"
+}
+
+// PostVoteResponse 帖子或评论赞/踩的响应
+type PostVoteResponse struct {
+	BaseResp
+	LikeCount    int64 `json:"like_count"`
+	DislikeCount int64 `json:"dislike_count"`
+	MyVote       int   `json:"my_vote"` // 操作后当前用户的投票, 再次点击同一按钮会取消
+}
+
+// PostEsModel 帖子ES模型, 除正文外的字段用于论坛搜索的过滤
 type PostEsModel struct {
-	Id      string  `json:"id"`
-	Title   string  `json:"title"`
-	Content string  `json:"content"`
-	Score   float64 `json:"score"`
+	Id          string    `json:"id"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`      // 去掉剧透后正文的纯文本, 用于搜索
+	FullContent string    `json:"full_content"` // 完整的Markdown正文(不含剧透标记), 只用于展示和编辑, 不参与搜索
+	ContentHTML string    `json:"content_html"` // 完整正文渲染后的HTML
+	PostId      int64     `json:"post_id"`
+	AuthorId    int64     `json:"author_id"`
+	ClubId      int64     `json:"club_id"`
+	Category    string    `json:"category"`
+	CategoryId  int64     `json:"category_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Score       float64   `json:"score"`
 }
 
 func (p *PostEsModel) GetID() string {
@@ -96,27 +195,62 @@ func (p *PostEsModel) GetTitle() string {
 	return p.Title
 }
 
+// MaxPostCommentDepth 帖子评论的最大嵌套层数, 回复最深一层时挂到同一父评论下
+const MaxPostCommentDepth = 3
+
+// 帖子评论的排序方式, 只作用于顶层评论, 子回复始终按时间正序
+const (
+	PostCommentSortOldest = "oldest"
+	PostCommentSortNewest = "newest"
+	PostCommentSortTop    = "top" // 按点赞数
+)
+
+// PostCommentTombstoneText 已删除评论的内容占位
+const PostCommentTombstoneText = "[该评论已删除]"
+
+// PostCommentTombstoneHTML 已删除评论渲染后的占位
+const PostCommentTombstoneHTML = "<p>" + PostCommentTombstoneText + "</p>"
+
 // PostCommentDTO 帖子评论DTO
 type PostCommentDTO struct {
-	Id             int64     `json:"id"`
-	PostId         int64     `json:"post_id"`
-	Author         UserDTO   `json:"author"`
-	EditTime       time.Time `json:"edit_time"`
-	Content        string    `json:"content"`          // 评论的内容不会很长,直接存mysql
-	LikeUserIds    []int64   `json:"like_user_ids"`    // 点赞的用户ID列表
-	DislikeUserIds []int64   `json:"dislike_user_ids"` // 点踩的用户ID列表
+	Id           int64             `json:"id"`
+	PostId       int64             `json:"post_id"`
+	RootId       int64             `json:"root_id"`
+	ParentId     int64             `json:"parent_id"` // 0表示直接评论帖子
+	Depth        int               `json:"depth"`
+	Author       UserDTO           `json:"author"`
+	EditTime     time.Time         `json:"edit_time"`
+	Content      string            `json:"content"`      // Markdown原文, 评论的内容不会很长,直接存mysql
+	ContentHTML  string            `json:"content_html"` // 渲染后的HTML
+	LikeCount    int64             `json:"like_count"`
+	DislikeCount int64             `json:"dislike_count"`
+	MyVote       int               `json:"my_vote"`   // 当前用户的投票, 1赞 -1踩 0未投
+	EditedAt     *time.Time        `json:"edited_at"` // 最后修改时间, 未修改过为空
+	Deleted      bool              `json:"deleted"`   // 已删除但仍有回复的评论只保留占位
+	DeletedAt    *time.Time        `json:"deleted_at"`
+	DeletedBy    int64             `json:"deleted_by"`
+	Books        []*BookInfoDTO    `json:"books,omitempty"` // 正文中[[book:id]]引用的图书卡片
+	Replies      []*PostCommentDTO `json:"replies"`
 }
 
-// PostCommentDO 帖子评论DO
+// PostCommentDO 帖子评论DO, RootId为所在的顶层评论(顶层评论自身为0), 用于按顶层评论分页时一次取出整棵子树
 type PostCommentDO struct {
-	Id             int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	PostId         int64     `gorm:"column:post_id" json:"post_id"`
-	AuthorId       int64     `gorm:"column:author_id" json:"author_id"`
-	AuthorName     string    `gorm:"column:author_name" json:"author_name"`
-	EditTime       time.Time `gorm:"column:edit_time" json:"edit_time"`
-	Content        string    `gorm:"column:content" json:"content"` // 评论的内容不会很长,直接存mysql
-	LikeUserIds    string    `gorm:"column:like_user_ids" json:"like_user_ids"`
-	DislikeUserIds string    `gorm:"column:dislike_user_ids" json:"dislike_user_ids"`
+	Id           int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PostId       int64      `gorm:"column:post_id;index:idx_post_comment_root" json:"post_id"`
+	RootId       int64      `gorm:"column:root_id;index:idx_post_comment_root" json:"root_id"`
+	ParentId     int64      `gorm:"column:parent_id" json:"parent_id"`
+	Depth        int        `gorm:"column:depth;default:1" json:"depth"`
+	AuthorId     int64      `gorm:"column:author_id" json:"author_id"`
+	AuthorName   string     `gorm:"column:author_name" json:"author_name"`
+	EditTime     time.Time  `gorm:"column:edit_time" json:"edit_time"`
+	Content      string     `gorm:"column:content" json:"content"` // 评论的内容不会很长,直接存mysql
+	ContentHTML  string     `gorm:"column:content_html;type:text" json:"content_html"`
+	LikeCount    int64      `gorm:"column:like_count" json:"like_count"`
+	DislikeCount int64      `gorm:"column:dislike_count" json:"dislike_count"`
+	EditedAt     *time.Time `gorm:"column:edited_at" json:"edited_at"`
+	Deleted      bool       `gorm:"column:deleted" json:"deleted"`
+	DeletedAt    *time.Time `gorm:"column:deleted_at" json:"deleted_at"`
+	DeletedBy    int64      `gorm:"column:deleted_by" json:"deleted_by"` // 删除操作人, 作者本人或版主
 }
 
 func (p PostCommentDO) TableName() string {
@@ -125,31 +259,59 @@ func (p PostCommentDO) TableName() string {
 
 // TransformToDTO 将PostCommentDO转换为PostCommentDTO
 func (p *PostCommentDO) TransformToDTO() *PostCommentDTO {
-	return &PostCommentDTO{
-		Id:       p.Id,
-		PostId:   p.PostId,
-		Author:   UserDTO{Id: p.AuthorId, Name: p.AuthorName},
-		EditTime: p.EditTime,
-		Content:  p.Content,
+	dto := &PostCommentDTO{
+		Id:           p.Id,
+		PostId:       p.PostId,
+		RootId:       p.RootId,
+		ParentId:     p.ParentId,
+		Depth:        p.Depth,
+		Author:       UserDTO{Id: p.AuthorId, Name: p.AuthorName},
+		EditTime:     p.EditTime,
+		Content:      p.Content,
+		ContentHTML:  p.ContentHTML,
+		LikeCount:    p.LikeCount,
+		DislikeCount: p.DislikeCount,
+		EditedAt:     p.EditedAt,
+		Deleted:      p.Deleted,
+		DeletedAt:    p.DeletedAt,
+		DeletedBy:    p.DeletedBy,
+		Replies:      []*PostCommentDTO{},
 	}
+	if p.Deleted {
+		dto.Content = PostCommentTombstoneText
+		dto.ContentHTML = PostCommentTombstoneHTML
+	}
+	return dto
 }
 
 func (p *PostCommentDTO) TransformToDO() *PostCommentDO {
 	return &PostCommentDO{
-		Id:         p.Id,
-		PostId:     p.PostId,
-		AuthorId:   p.Author.Id,
-		AuthorName: p.Author.Name,
-		EditTime:   p.EditTime,
-		Content:    p.Content,
+		Id:           p.Id,
+		PostId:       p.PostId,
+		RootId:       p.RootId,
+		ParentId:     p.ParentId,
+		Depth:        p.Depth,
+		AuthorId:     p.Author.Id,
+		AuthorName:   p.Author.Name,
+		EditTime:     p.EditTime,
+		Content:      p.Content,
+		ContentHTML:  p.ContentHTML,
+		LikeCount:    p.LikeCount,
+		DislikeCount: p.DislikeCount,
+		EditedAt:     p.EditedAt,
+		Deleted:      p.Deleted,
+		DeletedAt:    p.DeletedAt,
+		DeletedBy:    p.DeletedBy,
 	}
 }
 
 // CreatePostRequestDTO 创建帖子请求DTO
 type CreatePostRequestDTO struct {
 	Title    string `json:"title"`
-	Content  string `json:"content"`
+	Content  string `json:"content"` // Markdown正文, 用||包住剧透内容
 	Category string `json:"category"`
+	ClubId   int64  `json:"club_id"` // 发到读书会内, 仅成员可见
+	Spoiler  bool   `json:"spoiler"` // 整篇帖子含剧透
 }
 
 // CreatePostResponseDTO 创建帖子响应DTO
@@ -165,6 +327,7 @@ type GetPostByTimeLineRequestDTO struct {
 	Page      int       `json:"page"`
 	PageSize  int       `json:"page_size"`
 	Category  string    `json:"category"`
+	ClubId    int64     `json:"club_id"` // 只看某个读书会的帖子
 }
 
 // GetPostByTimeLineResponseDTO 获取帖子时间线响应DTO
@@ -201,17 +364,117 @@ type GetPostByIdResponseDTO struct {
 
 // GetPostContentByPostIdRequestDTO 获取帖子内容请求DTO
 type GetPostContentByPostIdRequestDTO struct {
-	PostId int64 `json:"post_id"`
+	PostId         int64 `json:"post_id"`
+	RevealSpoilers bool  `json:"reveal_spoilers"` // 为true时返回完整内容, 否则隐藏剧透
 }
 
 // GetPostContentByPostIdResponseDTO 获取帖子内容响应DTO
 type GetPostContentByPostIdResponseDTO struct {
 	BaseResp
-	Content string `json:"content"`
+	Content      string         `json:"content"`      // Markdown原文
+	ContentHTML  string         `json:"content_html"` // 渲染后的HTML, 隐藏剧透时剧透内容已替换为占位
+	Spoiler      bool           `json:"spoiler"`
+	SpoilerSpans []SpoilerSpan  `json:"spoiler_spans"` // 返回内容中剧透(或剧透占位)的区间
+	Deleted      bool           `json:"deleted"`
+	Books        []*BookInfoDTO `json:"books"` // 正文中[[book:id]]引用的图书卡片, 隐藏的剧透中的引用不返回
+}
+
+// UpdatePostRequestDTO 编辑帖子请求DTO
+type UpdatePostRequestDTO struct {
+	Title   string `json:"title"`
+	Content string `json:"content"` // 用||包住剧透内容
+	Spoiler bool   `json:"spoiler"`
+}
+
+// UpdatePostResponseDTO 编辑帖子响应DTO
+type UpdatePostResponseDTO struct {
+	BaseResp
+	Post *PostDTO `json:"post"`
+}
+
+const (
+	PostRevisionEdit   = "edit"
+	PostRevisionDelete = "delete"
+)
+
+// PostRevisionDTO 帖子历史版本DTO
+type PostRevisionDTO struct {
+	Id           int64         `json:"id"`
+	PostId       int64         `json:"post_id"`
+	Version      int           `json:"version"`
+	Title        string        `json:"title"`
+	Content      string        `json:"content"`
+	Spoiler      bool          `json:"spoiler"`
+	SpoilerSpans []SpoilerSpan `json:"spoiler_spans"`
+	EditorId     int64         `json:"editor_id"`
+	EditorName   string        `json:"editor_name"`
+	Action       string        `json:"action"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// PostRevisionDO 帖子历史版本DO, 每次编辑或删除前保存一份旧的标题和正文
+type PostRevisionDO struct {
+	Id           int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PostId       int64     `gorm:"column:post_id;uniqueIndex:idx_post_revision_version,priority:1" json:"post_id"`
+	Version      int       `gorm:"column:version;uniqueIndex:idx_post_revision_version,priority:2" json:"version"`
+	Title        string    `gorm:"column:title" json:"title"`
+	Content      string    `gorm:"column:content;type:text" json:"content"`
+	Spoiler      bool      `gorm:"column:spoiler" json:"spoiler"`
+	SpoilerSpans string    `gorm:"column:spoiler_spans;type:text" json:"spoiler_spans"`
+	EditorId     int64     `gorm:"column:editor_id" json:"editor_id"` // 造成这次变更的用户
+	EditorName   string    `gorm:"column:editor_name" json:"editor_name"`
+	Action       string    `gorm:"column:action" json:"action"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (p PostRevisionDO) TableName() string {
+	return "post_revision"
+}
+
+// Transfer 将PostRevisionDO转换为PostRevisionDTO
+func (p *PostRevisionDO) Transfer() *PostRevisionDTO {
+	return &PostRevisionDTO{
+		Id:           p.Id,
+		PostId:       p.PostId,
+		Version:      p.Version,
+		Title:        p.Title,
+		Content:      p.Content,
+		Spoiler:      p.Spoiler,
+		SpoilerSpans: decodeSpoilerSpans(p.SpoilerSpans),
+		EditorId:     p.EditorId,
+		EditorName:   p.EditorName,
+		Action:       p.Action,
+		CreatedAt:    p.CreatedAt,
+	}
+}
+
+// PostRevisionListResponse 帖子历史版本响应
+type PostRevisionListResponse struct {
+	BaseResp
+	Revisions []*PostRevisionDTO `json:"revisions"`
 }
 
 // CreatePostCommentReq 创建帖子评论请求
 type CreatePostCommentReq struct {
-	Content string
-	PostId  int64
+	Content  string `json:"content"`
+	PostId   int64  `json:"post_id"`
+	ParentId int64  `json:"parent_id"` // 回复某条评论时填写
+}
+
+// UpdatePostCommentReq 编辑帖子评论请求
+type UpdatePostCommentReq struct {
+	Content string `json:"content"`
+}
+
+// PostCommentResponse 单条帖子评论响应
+type PostCommentResponse struct {
+	BaseResp
+	Comment *PostCommentDTO `json:"comment"`
+}
+
+// PostCommentListResponse 帖子评论列表响应, 按顶层评论分页, 每条顶层评论带完整的子回复
+type PostCommentListResponse struct {
+	BaseResp
+	Comments []*PostCommentDTO `json:"comments"`
+	Total    int64             `json:"total"` // 顶层评论数
 }
@@ -0,0 +1,93 @@
+package model
+
+import "time"
+
+// PostDraftMaxContentLength 草稿正文的最大字符数
+const PostDraftMaxContentLength = 100000
+
+// PostDraftDTO 帖子草稿DTO
+type PostDraftDTO struct {
+	Id        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"` // Markdown正文, 用||包住剧透内容
+	Category  string    `json:"category"`
+	ClubId    int64     `json:"club_id"`
+	Spoiler   bool      `json:"spoiler"`
+	Version   int64     `json:"version"` // 每次保存加一, 用于发现多个页面同时编辑
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PostDraftDO 帖子草稿DO, 超过配置的天数没有更新的草稿会被清理
+type PostDraftDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId    int64     `gorm:"column:user_id;index" json:"user_id"`
+	Title     string    `gorm:"column:title" json:"title"`
+	Content   string    `gorm:"column:content;type:mediumtext" json:"content"`
+	Category  string    `gorm:"column:category" json:"category"`
+	ClubId    int64     `gorm:"column:club_id" json:"club_id"`
+	Spoiler   bool      `gorm:"column:spoiler" json:"spoiler"`
+	Version   int64     `gorm:"column:version" json:"version"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;index" json:"updated_at"`
+	// PublishingAt 开始发布的时间, 发布中的草稿不能重复发布或修改
+	PublishingAt *time.Time `gorm:"column:publishing_at" json:"-"`
+}
+
+func (p PostDraftDO) TableName() string {
+	return "post_draft"
+}
+
+// Transfer 将PostDraftDO转换为PostDraftDTO
+func (p *PostDraftDO) Transfer() *PostDraftDTO {
+	return &PostDraftDTO{
+		Id:        p.Id,
+		Title:     p.Title,
+		Content:   p.Content,
+		Category:  p.Category,
+		ClubId:    p.ClubId,
+		Spoiler:   p.Spoiler,
+		Version:   p.Version,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+// SavePostDraftRequest 新建或整体保存草稿请求
+type SavePostDraftRequest struct {
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Category string `json:"category"`
+	ClubId   int64  `json:"club_id"`
+	Spoiler  bool   `json:"spoiler"`
+}
+
+// AutosavePostDraftRequest 自动保存草稿请求, 只更新传了的字段
+type AutosavePostDraftRequest struct {
+	Title    *string `json:"title"`
+	Content  *string `json:"content"`
+	Category *string `json:"category"`
+	ClubId   *int64  `json:"club_id"`
+	Spoiler  *bool   `json:"spoiler"`
+	Version  int64   `json:"version"` // 页面上草稿的版本, 与服务端不一致时拒绝保存; 为0时不检查
+}
+
+// PostDraftResponse 单个草稿响应
+type PostDraftResponse struct {
+	BaseResp
+	Draft *PostDraftDTO `json:"draft"`
+}
+
+// AutosavePostDraftResponse 自动保存响应, 不回传正文
+type AutosavePostDraftResponse struct {
+	BaseResp
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PostDraftListResponse 草稿列表响应, 列表中不含正文
+type PostDraftListResponse struct {
+	BaseResp
+	Drafts []*PostDraftDTO `json:"drafts"`
+	Total  int64           `json:"total"`
+}
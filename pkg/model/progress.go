@@ -0,0 +1,118 @@
+package model
+
+import "time"
+
+// ReadingProgressDTO 阅读进度记录DTO
+type ReadingProgressDTO struct {
+	Id         int64     `json:"id"`
+	UserId     int64     `json:"user_id"`
+	BookId     int64     `json:"book_id"`
+	Page       int       `json:"page"`        // 当前读到的页码
+	TotalPages int       `json:"total_pages"` // 用户填写的总页数, 不同版本页数不同
+	Percent    float64   `json:"percent"`     // 阅读百分比 0-100
+	PagesRead  int       `json:"pages_read"`  // 相比上一条记录新读的页数
+	Finished   bool      `json:"finished"`    // 本条记录是否表示读完
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ReadingProgressDO 阅读进度记录, 每次更新进度追加一条, 统计全部基于这些记录计算
+type ReadingProgressDO struct {
+	Id         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId     int64     `gorm:"column:user_id;index:idx_progress_user_time" json:"user_id"`
+	BookId     int64     `gorm:"column:book_id;index" json:"book_id"`
+	Page       int       `gorm:"column:page" json:"page"`
+	TotalPages int       `gorm:"column:total_pages" json:"total_pages"`
+	Percent    float64   `gorm:"column:percent" json:"percent"`
+	PagesRead  int       `gorm:"column:pages_read" json:"pages_read"`
+	Finished   bool      `gorm:"column:finished" json:"finished"`
+	Note       string    `gorm:"column:note;type:text" json:"note"`
+	CreatedAt  time.Time `gorm:"column:created_at;index:idx_progress_user_time" json:"created_at"`
+}
+
+func (p ReadingProgressDO) TableName() string {
+	return "reading_progress"
+}
+
+// Transfer 将ReadingProgressDO转换为ReadingProgressDTO
+func (p *ReadingProgressDO) Transfer() *ReadingProgressDTO {
+	return &ReadingProgressDTO{
+		Id:         p.Id,
+		UserId:     p.UserId,
+		BookId:     p.BookId,
+		Page:       p.Page,
+		TotalPages: p.TotalPages,
+		Percent:    p.Percent,
+		PagesRead:  p.PagesRead,
+		Finished:   p.Finished,
+		Note:       p.Note,
+		CreatedAt:  p.CreatedAt,
+	}
+}
+
+// LogProgressRequest 记录阅读进度请求, page和percent至少填一个
+type LogProgressRequest struct {
+	BookId     int64   `json:"book_id"`
+	Page       int     `json:"page"`
+	TotalPages int     `json:"total_pages"`
+	Percent    float64 `json:"percent"`
+	Finished   bool    `json:"finished"`
+	Note       string  `json:"note"`
+}
+
+// ProgressResponse 阅读进度返回
+type ProgressResponse struct {
+	BaseResp
+	Progress []*ReadingProgressDTO `json:"progress"`
+}
+
+// MonthlyReading 每月阅读量
+type MonthlyReading struct {
+	Month int `json:"month"`
+	Books int `json:"books"` // 当月读完的书数
+	Pages int `json:"pages"` // 当月阅读页数
+}
+
+// CategoryCount 规范分类及读完的书数
+type CategoryCount struct {
+	CategoryId int64  `json:"category_id"`
+	Category   string `json:"category"`
+	Books      int    `json:"books"`
+}
+
+// ReadingStatsDTO 年度阅读统计
+type ReadingStatsDTO struct {
+	Year             int              `json:"year"`
+	TotalBooks       int              `json:"total_books"`
+	TotalPages       int              `json:"total_pages"`
+	Monthly          []MonthlyReading `json:"monthly"`
+	FavoriteCategory []CategoryCount  `json:"favorite_categories"`
+	AverageRating    float64          `json:"average_rating"` // 当年所写书评的平均分
+	RatingCount      int              `json:"rating_count"`
+	ReadingDays      int              `json:"reading_days"`   // 有阅读记录的天数
+	LongestStreak    int              `json:"longest_streak"` // 当年最长连续阅读天数
+	CurrentStreak    int              `json:"current_streak"` // 截至今天(或昨天)的连续阅读天数
+}
+
+// ReadingStatsResponse 阅读统计返回
+type ReadingStatsResponse struct {
+	BaseResp
+	Stats *ReadingStatsDTO `json:"stats"`
+}
+
+// YearInReviewDTO 年度阅读总结
+type YearInReviewDTO struct {
+	Stats            *ReadingStatsDTO `json:"stats"`
+	FirstBook        *BookInfoDTO     `json:"first_book"`        // 当年读完的第一本书
+	LastBook         *BookInfoDTO     `json:"last_book"`         // 当年读完的最后一本书
+	TopRatedBook     *BookInfoDTO     `json:"top_rated_book"`    // 当年读完的书中自己打分最高的
+	MostActiveMonth  int              `json:"most_active_month"` // 阅读页数最多的月份, 无记录为0
+	TopCategory      string           `json:"top_category"`
+	AveragePagesADay float64          `json:"average_pages_a_day"` // 按有阅读记录的天数平均
+}
+
+// YearInReviewResponse 年度阅读总结返回
+type YearInReviewResponse struct {
+	BaseResp
+	Review *YearInReviewDTO `json:"review"`
+}
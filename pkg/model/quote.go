@@ -0,0 +1,141 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuoteDTO 书摘DTO
+type QuoteDTO struct {
+	Id            int64     `json:"id"`
+	BookId        int64     `json:"book_id"`
+	BookName      string    `json:"book_name"`
+	UserId        int64     `json:"user_id"`
+	Content       string    `json:"content"` // 摘录原文
+	Page          int       `json:"page"`    // 所在页码, 0表示未填写
+	Comment       string    `json:"comment"` // 摘录者的感想
+	LikeCount     int64     `json:"like_count"`
+	BookmarkCount int64     `json:"bookmark_count"`
+	Liked         bool      `json:"liked"`      // 当前用户是否已点赞
+	Bookmarked    bool      `json:"bookmarked"` // 当前用户是否已收藏
+	ShareText     string    `json:"share_text"` // 用于分享的文本
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// QuoteDO 书摘数据库对象
+type QuoteDO struct {
+	Id            int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	BookId        int64     `gorm:"column:book_id;index" json:"book_id"`
+	UserId        int64     `gorm:"column:user_id;index" json:"user_id"`
+	Content       string    `gorm:"column:content;type:text" json:"content"`
+	Page          int       `gorm:"column:page" json:"page"`
+	Comment       string    `gorm:"column:comment;type:text" json:"comment"`
+	LikeCount     int64     `gorm:"column:like_count" json:"like_count"`
+	BookmarkCount int64     `gorm:"column:bookmark_count" json:"bookmark_count"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (q QuoteDO) TableName() string {
+	return "quote"
+}
+
+// Transfer 将QuoteDO转换为QuoteDTO, book为空时不填充书名和分享文本
+func (q *QuoteDO) Transfer(book *BookInfoDTO) *QuoteDTO {
+	dto := &QuoteDTO{
+		Id:            q.Id,
+		BookId:        q.BookId,
+		UserId:        q.UserId,
+		Content:       q.Content,
+		Page:          q.Page,
+		Comment:       q.Comment,
+		LikeCount:     q.LikeCount,
+		BookmarkCount: q.BookmarkCount,
+		CreatedAt:     q.CreatedAt,
+	}
+	if book != nil {
+		dto.BookName = book.Name
+		dto.ShareText = fmt.Sprintf("「%s」—— %s《%s》", q.Content, book.Author, book.Name)
+		if q.Page > 0 {
+			dto.ShareText += fmt.Sprintf(" 第%d页", q.Page)
+		}
+	}
+	return dto
+}
+
+// QuoteLikeDO 书摘点赞, 每人每条只能点赞一次
+type QuoteLikeDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	QuoteId   int64     `gorm:"column:quote_id;uniqueIndex:uk_quote_like" json:"quote_id"`
+	UserId    int64     `gorm:"column:user_id;uniqueIndex:uk_quote_like" json:"user_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (q QuoteLikeDO) TableName() string {
+	return "quote_like"
+}
+
+// QuoteBookmarkDO 书摘收藏
+type QuoteBookmarkDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	QuoteId   int64     `gorm:"column:quote_id;uniqueIndex:uk_quote_bookmark" json:"quote_id"`
+	UserId    int64     `gorm:"column:user_id;uniqueIndex:uk_quote_bookmark;index" json:"user_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (q QuoteBookmarkDO) TableName() string {
+	return "quote_bookmark"
+}
+
+// QuoteEsModel ES中存储的书摘
+type QuoteEsModel struct {
+	Id      string  `json:"id"`
+	BookId  int64   `json:"book_id"`
+	Content string  `json:"content"`
+	Comment string  `json:"comment"`
+	Score   float64 `json:"score"`
+}
+
+func (q *QuoteEsModel) GetID() string {
+	return q.Id
+}
+
+func (q *QuoteEsModel) SetScore(score float64) {
+	q.Score = score
+}
+
+func (q *QuoteEsModel) GetScore() float64 {
+	return q.Score
+}
+
+func (q *QuoteEsModel) GetIndexName() string {
+	return "quote"
+}
+
+func (q *QuoteEsModel) GetContent() string {
+	return q.Content
+}
+
+func (q *QuoteEsModel) GetTitle() string {
+	return q.Comment
+}
+
+// CreateQuoteRequest 创建书摘请求
+type CreateQuoteRequest struct {
+	BookId  int64  `json:"book_id"`
+	Content string `json:"content"`
+	Page    int    `json:"page"`
+	Comment string `json:"comment"`
+}
+
+// QuoteResponse 单条书摘返回
+type QuoteResponse struct {
+	BaseResp
+	Quote *QuoteDTO `json:"quote"`
+}
+
+// QuoteListResponse 书摘列表返回
+type QuoteListResponse struct {
+	BaseResp
+	Quotes []*QuoteDTO `json:"quotes"`
+	Total  int64       `json:"total"`
+}
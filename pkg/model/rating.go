@@ -0,0 +1,88 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	MinReviewScore    = 1.0
+	MaxReviewScore    = 5.0
+	RatingPriorWeight = 10.0 // 贝叶斯加权时先验(全站均分)相当于多少条评分
+	DefaultPriorMean  = 3.0  // 全站还没有评分时使用的先验均分
+)
+
+// BookRatingDTO 图书评分聚合DTO
+type BookRatingDTO struct {
+	BookId        int64    `json:"book_id"`
+	Count         int64    `json:"count"`          // 评分人数
+	Mean          float64  `json:"mean"`           // 算术平均分
+	WeightedScore float64  `json:"weighted_score"` // 贝叶斯加权分, 用于排序
+	Histogram     [5]int64 `json:"histogram"`      // 1-5星各自的人数
+}
+
+// BookRatingDO 图书评分聚合数据库对象, 随书评增删改在同一事务内更新
+type BookRatingDO struct {
+	BookId        int64     `gorm:"column:book_id;primaryKey;autoIncrement:false" json:"book_id"`
+	Count         int64     `gorm:"column:count" json:"count"`
+	Sum           float64   `gorm:"column:sum" json:"sum"`
+	Mean          float64   `gorm:"column:mean" json:"mean"`
+	WeightedScore float64   `gorm:"column:weighted_score" json:"weighted_score"`
+	Star1         int64     `gorm:"column:star1" json:"star1"`
+	Star2         int64     `gorm:"column:star2" json:"star2"`
+	Star3         int64     `gorm:"column:star3" json:"star3"`
+	Star4         int64     `gorm:"column:star4" json:"star4"`
+	Star5         int64     `gorm:"column:star5" json:"star5"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (b BookRatingDO) TableName() string {
+	return "book_rating"
+}
+
+// Transfer 将BookRatingDO转换为BookRatingDTO
+func (b *BookRatingDO) Transfer() *BookRatingDTO {
+	return &BookRatingDTO{
+		BookId:        b.BookId,
+		Count:         b.Count,
+		Mean:          b.Mean,
+		WeightedScore: b.WeightedScore,
+		Histogram:     [5]int64{b.Star1, b.Star2, b.Star3, b.Star4, b.Star5},
+	}
+}
+
+// StarBucket 将评分四舍五入到1-5星
+func StarBucket(score float64) int {
+	star := int(math.Round(score))
+	if star < 1 {
+		star = 1
+	}
+	if star > 5 {
+		star = 5
+	}
+	return star
+}
+
+// Apply 增加(sign=1)或移除(sign=-1)一条评分, 并用全站均分priorMean重新计算均分和加权分
+func (b *BookRatingDO) Apply(score float64, sign int64, priorMean float64) {
+	b.Count += sign
+	b.Sum += float64(sign) * score
+	switch StarBucket(score) {
+	case 1:
+		b.Star1 += sign
+	case 2:
+		b.Star2 += sign
+	case 3:
+		b.Star3 += sign
+	case 4:
+		b.Star4 += sign
+	case 5:
+		b.Star5 += sign
+	}
+	if b.Count <= 0 {
+		b.Count, b.Sum, b.Mean = 0, 0, 0
+	} else {
+		b.Mean = b.Sum / float64(b.Count)
+	}
+	b.WeightedScore = (RatingPriorWeight*priorMean + b.Sum) / (RatingPriorWeight + float64(b.Count))
+}
@@ -2,32 +2,50 @@ package model
 
 import "encoding/json"
 
+// 用户行为的兴趣权重, 个性化推荐优先使用权重高的分类和关键词
+const (
+	ActionWeightView  = 1 // 浏览、搜索等
+	ActionWeightShelf = 3 // 加入或移动书架
+)
+
 type UserRecommendRecordDO struct {
 	Id       int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
 	UserId   int64  `gorm:"column:user_id" json:"user_id"`
 	Category string `gorm:"column:category" json:"category"`
 	KeyWords string `gorm:"column:key_words" json:"key_words"`
+	Weights  string `gorm:"column:weights;type:text" json:"weights"`
 }
 
 type UserRecommendRecordDTO struct {
-	Id       int64    `json:"id"`
-	UserId   int64    `json:"user_id"`
-	Category []string `json:"category"`
-	KeyWords []string `json:"key_words"`
+	Id       int64          `json:"id"`
+	UserId   int64          `json:"user_id"`
+	Category []string       `json:"category"`
+	KeyWords []string       `json:"key_words"`
+	Weights  map[string]int `json:"weights"` // 分类和关键词累计的兴趣权重, 没有记录的按ActionWeightView计
 }
 
 func (u UserRecommendRecordDO) TableName() string {
 	return "user_recommend"
 }
 
+// Weight 分类或关键词的兴趣权重
+func (u *UserRecommendRecordDTO) Weight(term string) int {
+	if w, ok := u.Weights[term]; ok {
+		return w
+	}
+	return ActionWeightView
+}
+
 func (u *UserRecommendRecordDTO) Convert2DO() *UserRecommendRecordDO {
 	categoryStr, _ := json.Marshal(u.Category)
 	keyWordsStr, _ := json.Marshal(u.KeyWords)
+	weightsStr, _ := json.Marshal(u.Weights)
 	return &UserRecommendRecordDO{
 		Id:       u.Id,
 		UserId:   u.UserId,
 		Category: string(categoryStr),
 		KeyWords: string(keyWordsStr),
+		Weights:  string(weightsStr),
 	}
 }
 
@@ -38,6 +56,10 @@ func (u *UserRecommendRecordDO) Convert2DTO() *UserRecommendRecordDTO {
 	}
 	_ = json.Unmarshal([]byte(u.Category), &dto.Category)
 	_ = json.Unmarshal([]byte(u.KeyWords), &dto.KeyWords)
+	_ = json.Unmarshal([]byte(u.Weights), &dto.Weights)
+	if dto.Weights == nil {
+		dto.Weights = map[string]int{}
+	}
 	return dto
 }
 
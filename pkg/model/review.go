@@ -0,0 +1,156 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// 书评投票
+const (
+	ReviewVoteLike    = 1  // 赞
+	ReviewVoteDislike = -1 // 踩
+)
+
+// 书评排序方式
+const (
+	ReviewSortNewest  = "newest"  // 最新
+	ReviewSortHighest = "highest" // 评分从高到低
+	ReviewSortLowest  = "lowest"  // 评分从低到高
+	ReviewSortHelpful = "helpful" // 最有帮助, 按赞踩的Wilson下界
+)
+
+// wilsonZ 95%置信度对应的z值
+const wilsonZ = 1.96
+
+// ReviewVoteDO 用户对书评的投票, 每个用户对每条书评只有一票
+type ReviewVoteDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ReviewId  int64     `gorm:"column:review_id;uniqueIndex:uk_review_user" json:"review_id"`
+	UserId    int64     `gorm:"column:user_id;uniqueIndex:uk_review_user" json:"user_id"`
+	Value     int       `gorm:"column:value" json:"value"` // ReviewVoteLike或ReviewVoteDislike
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (r ReviewVoteDO) TableName() string {
+	return "review_vote"
+}
+
+// WilsonLowerBound 赞在全部投票中占比的Wilson置信区间下界, 票数少时不会因一两个赞排到最前
+func WilsonLowerBound(like, dislike int64) float64 {
+	n := float64(like + dislike)
+	if like <= 0 || n <= 0 {
+		return 0
+	}
+	p := float64(like) / n
+	z2 := wilsonZ * wilsonZ
+	return (p + z2/(2*n) - wilsonZ*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+// ListReviewsQuery 书评列表查询条件
+type ListReviewsQuery struct {
+	BookId   int64
+	Sort     string
+	Stars    int           // 只看某个星级(与评分分布的四舍五入一致), 0为全部
+	Cursor   *ReviewCursor // 上一页返回的游标, 为空时按Page分页
+	Page     int
+	PageSize int
+}
+
+// ReviewCursor 书评列表游标, 记录上一页最后一条书评的排序值和ID
+type ReviewCursor struct {
+	Value float64
+	Id    int64
+}
+
+// Encode 编码为不透明的字符串返回给前端
+func (c *ReviewCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%v:%d", c.Value, c.Id)))
+}
+
+// DecodeReviewCursor 解析前端传回的游标
+func DecodeReviewCursor(s string) (*ReviewCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor ReviewCursor
+	if _, err = fmt.Sscanf(string(raw), "%g:%d", &cursor.Value, &cursor.Id); err != nil || cursor.Id <= 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// MaxReviewReplyDepth 书评回复的最大嵌套层数, 回复最深一层时挂到同一父回复下
+const MaxReviewReplyDepth = 3
+
+// NotifyReviewReply 通知类型: 书评或回复收到回复
+const NotifyReviewReply = "review_reply"
+
+// ReviewReplyDTO 书评回复DTO
+type ReviewReplyDTO struct {
+	Id            int64             `json:"id"`
+	ReviewId      int64             `json:"review_id"`
+	ParentId      int64             `json:"parent_id"` // 0表示直接回复书评
+	Depth         int               `json:"depth"`
+	UserId        int64             `json:"user_id"`
+	UserName      string            `json:"user_name"`
+	ReplyToUserId int64             `json:"reply_to_user_id"` // 被回复的人
+	Content       string            `json:"content"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Replies       []*ReviewReplyDTO `json:"replies"`
+}
+
+// ReviewReplyDO 书评回复, RootId为所在的顶层回复(顶层回复自身为0), 用于按顶层回复分页时一次取出整棵子树
+type ReviewReplyDO struct {
+	Id            int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ReviewId      int64     `gorm:"column:review_id;index:idx_review_root" json:"review_id"`
+	RootId        int64     `gorm:"column:root_id;index:idx_review_root" json:"root_id"`
+	ParentId      int64     `gorm:"column:parent_id" json:"parent_id"`
+	Depth         int       `gorm:"column:depth" json:"depth"`
+	UserId        int64     `gorm:"column:user_id" json:"user_id"`
+	ReplyToUserId int64     `gorm:"column:reply_to_user_id" json:"reply_to_user_id"`
+	Content       string    `gorm:"column:content;type:text" json:"content"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (r ReviewReplyDO) TableName() string {
+	return "review_reply"
+}
+
+// Transfer 将ReviewReplyDO转换为ReviewReplyDTO
+func (r *ReviewReplyDO) Transfer() *ReviewReplyDTO {
+	return &ReviewReplyDTO{
+		Id:            r.Id,
+		ReviewId:      r.ReviewId,
+		ParentId:      r.ParentId,
+		Depth:         r.Depth,
+		UserId:        r.UserId,
+		ReplyToUserId: r.ReplyToUserId,
+		Content:       r.Content,
+		CreatedAt:     r.CreatedAt,
+		Replies:       []*ReviewReplyDTO{},
+	}
+}
+
+// CreateReviewReplyRequest 回复书评请求
+type CreateReviewReplyRequest struct {
+	ReviewId int64  `json:"review_id"`
+	ParentId int64  `json:"parent_id"` // 回复某条回复时填写
+	Content  string `json:"content"`
+}
+
+// ReviewReplyResponse 单条回复返回
+type ReviewReplyResponse struct {
+	BaseResp
+	Reply *ReviewReplyDTO `json:"reply"`
+}
+
+// ReviewReplyListResponse 回复列表返回, 按顶层回复分页, 每条顶层回复带完整的子回复
+type ReviewReplyListResponse struct {
+	BaseResp
+	Replies []*ReviewReplyDTO `json:"replies"`
+	Total   int64             `json:"total"` // 顶层回复数
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestWilsonLowerBound(t *testing.T) {
+	tests := []struct {
+		name          string
+		like, dislike int64
+		min, max      float64
+	}{
+		{"no votes", 0, 0, 0, 0},
+		{"only dislikes", 0, 3, 0, 0},
+		{"single like", 1, 0, 0.20, 0.21},
+		{"ten likes", 10, 0, 0.72, 0.73},
+		{"even split", 5, 5, 0.23, 0.24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WilsonLowerBound(tt.like, tt.dislike); got < tt.min || got > tt.max {
+				t.Errorf("WilsonLowerBound(%d, %d) = %v, want in [%v, %v]", tt.like, tt.dislike, got, tt.min, tt.max)
+			}
+		})
+	}
+	// 同样的好评率, 票数多的下界更高
+	if WilsonLowerBound(100, 0) <= WilsonLowerBound(10, 0) {
+		t.Error("more votes at the same ratio should rank higher")
+	}
+}
+
+func TestReviewCursor(t *testing.T) {
+	for _, cursor := range []*ReviewCursor{{Value: 0.8123, Id: 42}, {Value: 0, Id: 1}, {Value: 1.7e9, Id: 7}} {
+		got, err := DecodeReviewCursor(cursor.Encode())
+		if err != nil || *got != *cursor {
+			t.Errorf("round trip of %+v = %+v, %v", cursor, got, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("abc")),
+		base64.RawURLEncoding.EncodeToString([]byte("0.5:0")),
+		base64.RawURLEncoding.EncodeToString([]byte("0.5:-3")),
+	}
+	for _, s := range invalid {
+		if _, err := DecodeReviewCursor(s); err == nil {
+			t.Errorf("DecodeReviewCursor(%q) should fail", s)
+		}
+	}
+}
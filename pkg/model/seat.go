@@ -0,0 +1,211 @@
+package model
+
+import "time"
+
+// 座位预约状态
+const (
+	SeatBookingBooked    = "booked"     // 已预约
+	SeatBookingCheckedIn = "checked_in" // 已签到
+	SeatBookingCancelled = "cancelled"  // 用户取消
+	SeatBookingReleased  = "released"   // 爽约被系统释放
+)
+
+// ReadingRoomDTO 阅览室DTO
+type ReadingRoomDTO struct {
+	Id        int64  `json:"id"`
+	Name      string `json:"name"`
+	Location  string `json:"location"`
+	OpenTime  string `json:"open_time"`  // 开放时间, 如 08:00
+	CloseTime string `json:"close_time"` // 关闭时间, 如 22:00
+	Intro     string `json:"intro"`
+}
+
+// ReadingRoomDO 阅览室数据库对象
+type ReadingRoomDO struct {
+	Id        int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name      string `gorm:"column:name" json:"name"`
+	Location  string `gorm:"column:location" json:"location"`
+	OpenTime  string `gorm:"column:open_time" json:"open_time"`
+	CloseTime string `gorm:"column:close_time" json:"close_time"`
+	Intro     string `gorm:"column:intro" json:"intro"`
+}
+
+func (r ReadingRoomDO) TableName() string {
+	return "reading_room"
+}
+
+// Transfer 将ReadingRoomDO转换为ReadingRoomDTO
+func (r *ReadingRoomDO) Transfer() *ReadingRoomDTO {
+	return &ReadingRoomDTO{
+		Id:        r.Id,
+		Name:      r.Name,
+		Location:  r.Location,
+		OpenTime:  r.OpenTime,
+		CloseTime: r.CloseTime,
+		Intro:     r.Intro,
+	}
+}
+
+// Transfer 将ReadingRoomDTO转换为ReadingRoomDO
+func (r *ReadingRoomDTO) Transfer() *ReadingRoomDO {
+	return &ReadingRoomDO{
+		Id:        r.Id,
+		Name:      r.Name,
+		Location:  r.Location,
+		OpenTime:  r.OpenTime,
+		CloseTime: r.CloseTime,
+		Intro:     r.Intro,
+	}
+}
+
+// SeatDTO 座位DTO
+type SeatDTO struct {
+	Id       int64  `json:"id"`
+	RoomId   int64  `json:"room_id"`
+	Code     string `json:"code"`      // 座位编号, 如 A-01
+	HasPower bool   `json:"has_power"` // 是否有电源
+}
+
+// SeatDO 座位数据库对象
+type SeatDO struct {
+	Id          int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	RoomId      int64  `gorm:"column:room_id;index" json:"room_id"`
+	Code        string `gorm:"column:code" json:"code"`
+	HasPower    bool   `gorm:"column:has_power" json:"has_power"`
+	CheckInCode string `gorm:"column:check_in_code" json:"check_in_code"` // 贴在座位上的签到码,不对普通用户返回
+}
+
+func (s SeatDO) TableName() string {
+	return "seat"
+}
+
+// Transfer 将SeatDO转换为SeatDTO
+func (s *SeatDO) Transfer() *SeatDTO {
+	return &SeatDTO{
+		Id:       s.Id,
+		RoomId:   s.RoomId,
+		Code:     s.Code,
+		HasPower: s.HasPower,
+	}
+}
+
+// SeatBookingDTO 座位预约DTO
+type SeatBookingDTO struct {
+	Id          int64      `json:"id"`
+	SeatId      int64      `json:"seat_id"`
+	UserId      int64      `json:"user_id"`
+	StartTime   time.Time  `json:"start_time"`
+	EndTime     time.Time  `json:"end_time"`
+	Status      string     `json:"status"`
+	CheckedInAt *time.Time `json:"checked_in_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// SeatBookingDO 座位预约数据库对象
+type SeatBookingDO struct {
+	Id          int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	SeatId      int64      `gorm:"column:seat_id;index:idx_seat_time" json:"seat_id"`
+	UserId      int64      `gorm:"column:user_id;index" json:"user_id"`
+	StartTime   time.Time  `gorm:"column:start_time;index:idx_seat_time" json:"start_time"`
+	EndTime     time.Time  `gorm:"column:end_time" json:"end_time"`
+	Status      string     `gorm:"column:status;index" json:"status"`
+	CheckedInAt *time.Time `gorm:"column:checked_in_at" json:"checked_in_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+func (s SeatBookingDO) TableName() string {
+	return "seat_booking"
+}
+
+// Transfer 将SeatBookingDO转换为SeatBookingDTO
+func (s *SeatBookingDO) Transfer() *SeatBookingDTO {
+	return &SeatBookingDTO{
+		Id:          s.Id,
+		SeatId:      s.SeatId,
+		UserId:      s.UserId,
+		StartTime:   s.StartTime,
+		EndTime:     s.EndTime,
+		Status:      s.Status,
+		CheckedInAt: s.CheckedInAt,
+		CreatedAt:   s.CreatedAt,
+	}
+}
+
+// CreateReadingRoomRequest 创建阅览室请求
+type CreateReadingRoomRequest struct {
+	Name      string `json:"name"`
+	Location  string `json:"location"`
+	OpenTime  string `json:"open_time"`
+	CloseTime string `json:"close_time"`
+	Intro     string `json:"intro"`
+}
+
+// CreateSeatRequest 创建座位请求
+type CreateSeatRequest struct {
+	Code     string `json:"code"`
+	HasPower bool   `json:"has_power"`
+}
+
+// CreateSeatResponse 创建座位返回,签到码仅在此处返回给管理员用于打印
+type CreateSeatResponse struct {
+	BaseResp
+	Seat        *SeatDTO `json:"seat"`
+	CheckInCode string   `json:"check_in_code"`
+}
+
+// ReadingRoomListResponse 阅览室列表返回
+type ReadingRoomListResponse struct {
+	BaseResp
+	Rooms []*ReadingRoomDTO `json:"rooms"`
+}
+
+// SeatListResponse 座位列表返回
+type SeatListResponse struct {
+	BaseResp
+	Seats []*SeatDTO `json:"seats"`
+}
+
+// BookSeatRequest 预约座位请求
+type BookSeatRequest struct {
+	SeatId    int64     `json:"seat_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// SeatCheckInRequest 签到请求
+type SeatCheckInRequest struct {
+	Code string `json:"code"` // 座位上的签到码
+}
+
+// SeatBookingResponse 单个预约返回
+type SeatBookingResponse struct {
+	BaseResp
+	Booking *SeatBookingDTO `json:"booking"`
+}
+
+// SeatBookingListResponse 预约列表返回
+type SeatBookingListResponse struct {
+	BaseResp
+	Bookings []*SeatBookingDTO `json:"bookings"`
+}
+
+// TimeSlot 时间段
+type TimeSlot struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// SeatAvailability 单个座位某天的占用与空闲情况
+type SeatAvailability struct {
+	Seat     *SeatDTO   `json:"seat"`
+	Occupied []TimeSlot `json:"occupied"`
+	Free     []TimeSlot `json:"free"`
+}
+
+// RoomAvailabilityResponse 阅览室日历可用性返回
+type RoomAvailabilityResponse struct {
+	BaseResp
+	Room  *ReadingRoomDTO     `json:"room"`
+	Date  string              `json:"date"`
+	Seats []*SeatAvailability `json:"seats"`
+}
@@ -0,0 +1,119 @@
+package model
+
+import "time"
+
+// 书架类型, 前三种为每个用户自带的状态书架, 同一本书只能在其中一个
+const (
+	ShelfWantToRead = "want_to_read" // 想读
+	ShelfReading    = "reading"      // 在读
+	ShelfRead       = "read"         // 读过
+	ShelfCustom     = "custom"       // 用户自建书单
+)
+
+// BuiltinShelves 自带书架及默认名称
+var BuiltinShelves = []struct {
+	Kind string
+	Name string
+}{
+	{ShelfWantToRead, "想读"},
+	{ShelfReading, "在读"},
+	{ShelfRead, "读过"},
+}
+
+// IsBuiltinShelf 是否为自带的状态书架
+func IsBuiltinShelf(kind string) bool {
+	return kind == ShelfWantToRead || kind == ShelfReading || kind == ShelfRead
+}
+
+// ShelfDTO 书架DTO
+type ShelfDTO struct {
+	Id        int64     `json:"id"`
+	UserId    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	IsPublic  bool      `json:"is_public"`
+	BookCount int64     `json:"book_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShelfDO 书架数据库对象
+type ShelfDO struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId    int64     `gorm:"column:user_id;index" json:"user_id"`
+	Name      string    `gorm:"column:name" json:"name"`
+	Kind      string    `gorm:"column:kind" json:"kind"`
+	IsPublic  bool      `gorm:"column:is_public" json:"is_public"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (s ShelfDO) TableName() string {
+	return "shelf"
+}
+
+// Transfer 将ShelfDO转换为ShelfDTO
+func (s *ShelfDO) Transfer() *ShelfDTO {
+	return &ShelfDTO{
+		Id:        s.Id,
+		UserId:    s.UserId,
+		Name:      s.Name,
+		Kind:      s.Kind,
+		IsPublic:  s.IsPublic,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// ShelfItemDO 书架中的图书
+type ShelfItemDO struct {
+	Id       int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ShelfId  int64     `gorm:"column:shelf_id;uniqueIndex:uk_shelf_book" json:"shelf_id"`
+	BookId   int64     `gorm:"column:book_id;uniqueIndex:uk_shelf_book" json:"book_id"`
+	UserId   int64     `gorm:"column:user_id;index" json:"user_id"`
+	Position int       `gorm:"column:position" json:"position"` // 书架内排序, 越小越靠前
+	AddedAt  time.Time `gorm:"column:added_at" json:"added_at"`
+}
+
+func (s ShelfItemDO) TableName() string {
+	return "shelf_item"
+}
+
+// ShelfBookDTO 书架中的图书
+type ShelfBookDTO struct {
+	Book     *BookInfoDTO `json:"book"`
+	Position int          `json:"position"`
+	AddedAt  time.Time    `json:"added_at"`
+}
+
+// CreateShelfRequest 创建/修改书单请求
+type CreateShelfRequest struct {
+	Name     string `json:"name"`
+	IsPublic bool   `json:"is_public"`
+}
+
+// AddShelfBookRequest 加入书架请求
+type AddShelfBookRequest struct {
+	BookId int64 `json:"book_id"`
+}
+
+// MoveShelfBookRequest 移动到其他书架请求
+type MoveShelfBookRequest struct {
+	TargetShelfId int64 `json:"target_shelf_id"`
+}
+
+// ReorderShelfRequest 书架内排序请求, 按给定顺序排列
+type ReorderShelfRequest struct {
+	BookIds []int64 `json:"book_ids"`
+}
+
+// ShelfListResponse 书架列表返回
+type ShelfListResponse struct {
+	BaseResp
+	Shelves []*ShelfDTO `json:"shelves"`
+}
+
+// ShelfBooksResponse 书架图书返回
+type ShelfBooksResponse struct {
+	BaseResp
+	Shelf *ShelfDTO       `json:"shelf"`
+	Books []*ShelfBookDTO `json:"books"`
+	Total int64           `json:"total"`
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// SpoilerMarker 正文中剧透内容的标记, 如 "凶手是||管家||"
+	SpoilerMarker = "||"
+	// SpoilerPlaceholder 隐藏剧透时的占位文本
+	SpoilerPlaceholder = "[剧透]"
+)
+
+// SpoilerSpan 正文中的一段剧透, 按字符(rune)计的左闭右开区间
+type SpoilerSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ParseSpoilers 解析正文中的剧透标记, 返回去掉标记后的正文和剧透区间; 没有闭合的标记按普通文本处理
+func ParseSpoilers(raw string) (string, []SpoilerSpan) {
+	var b strings.Builder
+	spans := []SpoilerSpan{}
+	pos := 0
+	for {
+		open := strings.Index(raw, SpoilerMarker)
+		if open < 0 {
+			break
+		}
+		rest := raw[open+len(SpoilerMarker):]
+		closeAt := strings.Index(rest, SpoilerMarker)
+		if closeAt < 0 {
+			break
+		}
+		before, inner := raw[:open], rest[:closeAt]
+		b.WriteString(before)
+		pos += utf8.RuneCountInString(before)
+		if inner != "" {
+			b.WriteString(inner)
+			n := utf8.RuneCountInString(inner)
+			spans = append(spans, SpoilerSpan{Start: pos, End: pos + n})
+			pos += n
+		}
+		raw = rest[closeAt+len(SpoilerMarker):]
+	}
+	b.WriteString(raw)
+	return b.String(), spans
+}
+
+// RedactSpoilers 将剧透区间替换为占位文本, 返回替换后的正文和占位文本所在的区间
+func RedactSpoilers(content string, spans []SpoilerSpan) (string, []SpoilerSpan) {
+	return replaceSpoilers(content, spans, SpoilerPlaceholder)
+}
+
+// StripSpoilers 去掉剧透部分, 用于写入搜索索引
+func StripSpoilers(content string, spans []SpoilerSpan) string {
+	stripped, _ := replaceSpoilers(content, spans, "")
+	return stripped
+}
+
+// SpoilerView 按是否展开剧透返回正文: 展开时原样返回; 否则整篇剧透的只返回占位文本, 其余隐藏剧透区间
+func SpoilerView(content string, spans []SpoilerSpan, wholeSpoiler, reveal bool) (string, []SpoilerSpan) {
+	if reveal {
+		return content, spans
+	}
+	if wholeSpoiler {
+		return SpoilerPlaceholder, []SpoilerSpan{{Start: 0, End: utf8.RuneCountInString(SpoilerPlaceholder)}}
+	}
+	return RedactSpoilers(content, spans)
+}
+
+func replaceSpoilers(content string, spans []SpoilerSpan, placeholder string) (string, []SpoilerSpan) {
+	runes := []rune(content)
+	placeholderLen := utf8.RuneCountInString(placeholder)
+	var b strings.Builder
+	replaced := []SpoilerSpan{}
+	last, pos := 0, 0
+	for _, span := range spans {
+		// 区间按顺序且不重叠, 越界的忽略
+		if span.Start < last || span.Start >= span.End || span.End > len(runes) {
+			continue
+		}
+		b.WriteString(string(runes[last:span.Start]))
+		pos += span.Start - last
+		b.WriteString(placeholder)
+		if placeholderLen > 0 {
+			replaced = append(replaced, SpoilerSpan{Start: pos, End: pos + placeholderLen})
+			pos += placeholderLen
+		}
+		last = span.End
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String(), replaced
+}
+
+// encodeSpoilerSpans 剧透区间序列化后存入数据库
+func encodeSpoilerSpans(spans []SpoilerSpan) string {
+	if len(spans) == 0 {
+		return ""
+	}
+	bytes, _ := json.Marshal(spans)
+	return string(bytes)
+}
+
+// decodeSpoilerSpans 解析数据库中的剧透区间
+func decodeSpoilerSpans(s string) []SpoilerSpan {
+	spans := []SpoilerSpan{}
+	if s != "" {
+		_ = json.Unmarshal([]byte(s), &spans)
+	}
+	return spans
+}
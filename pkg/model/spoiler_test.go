@@ -0,0 +1,58 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSpoilers(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		content string
+		spans   []SpoilerSpan
+	}{
+		{"no markers", "普通正文", "普通正文", []SpoilerSpan{}},
+		{"one spoiler", "凶手是||管家||", "凶手是管家", []SpoilerSpan{{Start: 3, End: 5}}},
+		{"two spoilers", "||a||b||cd||", "abcd", []SpoilerSpan{{Start: 0, End: 1}, {Start: 2, End: 4}}},
+		{"unclosed marker stays text", "凶手是||管家||, ||未闭合", "凶手是管家, ||未闭合", []SpoilerSpan{{Start: 3, End: 5}}},
+		{"empty spoiler dropped", "a||||b", "ab", []SpoilerSpan{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, spans := ParseSpoilers(tt.raw)
+			if content != tt.content || !reflect.DeepEqual(spans, tt.spans) {
+				t.Errorf("ParseSpoilers(%q) = %q, %v, want %q, %v", tt.raw, content, spans, tt.content, tt.spans)
+			}
+		})
+	}
+}
+
+func TestSpoilerView(t *testing.T) {
+	spans := []SpoilerSpan{{Start: 3, End: 5}}
+	tests := []struct {
+		name          string
+		whole, reveal bool
+		content       string
+		spans         []SpoilerSpan
+	}{
+		{"reveal", false, true, "凶手是管家", spans},
+		{"redact spans", false, false, "凶手是[剧透]", []SpoilerSpan{{Start: 3, End: 7}}},
+		{"whole spoiler", true, false, SpoilerPlaceholder, []SpoilerSpan{{Start: 0, End: 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, got := SpoilerView("凶手是管家", spans, tt.whole, tt.reveal)
+			if content != tt.content || !reflect.DeepEqual(got, tt.spans) {
+				t.Errorf("SpoilerView = %q, %v, want %q, %v", content, got, tt.content, tt.spans)
+			}
+		})
+	}
+}
+
+func TestStripSpoilersIgnoresInvalidSpans(t *testing.T) {
+	spans := []SpoilerSpan{{Start: 1, End: 2}, {Start: 0, End: 1}, {Start: 3, End: 99}}
+	if got := StripSpoilers("abcd", spans); got != "acd" {
+		t.Errorf("StripSpoilers = %q, want %q", got, "acd")
+	}
+}
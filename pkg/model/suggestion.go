@@ -0,0 +1,124 @@
+package model
+
+import "time"
+
+// 荐购状态
+const (
+	SuggestionPending   = "pending"   // 待审核
+	SuggestionApproved  = "approved"  // 已通过
+	SuggestionOrdered   = "ordered"   // 已下单采购
+	SuggestionRejected  = "rejected"  // 已拒绝
+	SuggestionFulfilled = "fulfilled" // 已到馆上架
+)
+
+// SuggestionTransitions 馆员审核允许的状态迁移, fulfilled只能由图书入库触发
+var SuggestionTransitions = map[string][]string{
+	SuggestionPending:  {SuggestionApproved, SuggestionRejected},
+	SuggestionApproved: {SuggestionOrdered, SuggestionRejected},
+	SuggestionOrdered:  {SuggestionRejected},
+}
+
+// PurchaseSuggestionDTO 荐购DTO
+type PurchaseSuggestionDTO struct {
+	Id            int64     `json:"id"`
+	RequesterId   int64     `json:"requester_id"`
+	Title         string    `json:"title"`
+	Author        string    `json:"author"`
+	ISBN          string    `json:"ISBN"`
+	Reason        string    `json:"reason"`
+	Status        string    `json:"status"`
+	ReviewerId    int64     `json:"reviewer_id"`
+	ReviewComment string    `json:"review_comment"` // 馆员审核意见
+	BookId        int64     `json:"book_id"`        // 到馆后对应的图书id
+	VoteCount     int64     `json:"vote_count"`
+	Voted         bool      `json:"voted"` // 当前用户是否已附议
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// PurchaseSuggestionDO 荐购数据库对象
+type PurchaseSuggestionDO struct {
+	Id            int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	RequesterId   int64     `gorm:"column:requester_id;index" json:"requester_id"`
+	Title         string    `gorm:"column:title" json:"title"`
+	Author        string    `gorm:"column:author" json:"author"`
+	ISBN          string    `gorm:"column:isbn;index" json:"ISBN"`
+	Reason        string    `gorm:"column:reason" json:"reason"`
+	Status        string    `gorm:"column:status;index" json:"status"`
+	ReviewerId    int64     `gorm:"column:reviewer_id" json:"reviewer_id"`
+	ReviewComment string    `gorm:"column:review_comment" json:"review_comment"`
+	BookId        int64     `gorm:"column:book_id" json:"book_id"`
+	VoteCount     int64     `gorm:"column:vote_count" json:"vote_count"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (s PurchaseSuggestionDO) TableName() string {
+	return "purchase_suggestion"
+}
+
+// Transfer 将PurchaseSuggestionDO转换为PurchaseSuggestionDTO
+func (s *PurchaseSuggestionDO) Transfer() *PurchaseSuggestionDTO {
+	return &PurchaseSuggestionDTO{
+		Id:            s.Id,
+		RequesterId:   s.RequesterId,
+		Title:         s.Title,
+		Author:        s.Author,
+		ISBN:          s.ISBN,
+		Reason:        s.Reason,
+		Status:        s.Status,
+		ReviewerId:    s.ReviewerId,
+		ReviewComment: s.ReviewComment,
+		BookId:        s.BookId,
+		VoteCount:     s.VoteCount,
+		CreatedAt:     s.CreatedAt,
+		UpdatedAt:     s.UpdatedAt,
+	}
+}
+
+// PurchaseSuggestionVoteDO 荐购附议记录, 每人每条荐购只能附议一次
+type PurchaseSuggestionVoteDO struct {
+	Id           int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	SuggestionId int64     `gorm:"column:suggestion_id;uniqueIndex:uk_suggestion_user" json:"suggestion_id"`
+	UserId       int64     `gorm:"column:user_id;uniqueIndex:uk_suggestion_user" json:"user_id"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (v PurchaseSuggestionVoteDO) TableName() string {
+	return "purchase_suggestion_vote"
+}
+
+// CreateSuggestionRequest 发起荐购请求
+type CreateSuggestionRequest struct {
+	Title  string `json:"title"`
+	Author string `json:"author"`
+	ISBN   string `json:"ISBN"`
+	Reason string `json:"reason"`
+}
+
+// ReviewSuggestionRequest 馆员审核荐购请求
+type ReviewSuggestionRequest struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+}
+
+// SuggestionListRequest 荐购列表查询参数
+type SuggestionListRequest struct {
+	Status   string `form:"status"`
+	Sort     string `form:"sort"` // votes: 按附议数, 默认按时间倒序
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// SuggestionResponse 单条荐购返回
+type SuggestionResponse struct {
+	BaseResp
+	Suggestion *PurchaseSuggestionDTO `json:"suggestion"`
+}
+
+// SuggestionListResponse 荐购列表返回
+type SuggestionListResponse struct {
+	BaseResp
+	Suggestions []*PurchaseSuggestionDTO `json:"suggestions"`
+	Total       int64                    `json:"total"`
+}
@@ -1,11 +1,20 @@
 package model
 
+// 用户角色
+const (
+	RoleUser      = "user"      // 普通用户
+	RoleLibrarian = "librarian" // 馆员
+	RoleModerator = "moderator" // 版主
+	RoleAdmin     = "admin"     // 管理员
+)
+
 // UserDTO `用户`DTO结构体
 type UserDTO struct {
 	Id       int64  `json:"id"`
 	Email    string `json:"email"`
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	Role     string `json:"role"`
 }
 
 // UserDO `用户`存储数据结构体
@@ -14,6 +23,7 @@ type UserDO struct {
 	Email    string `json:"email"`
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	Role     string `gorm:"column:role;default:user" json:"role"`
 }
 
 func (userDO UserDO) TableName() string {
@@ -26,6 +36,7 @@ func (userDTO *UserDTO) Transfer() *UserDO {
 		Email:    userDTO.Email,
 		Name:     userDTO.Name,
 		Password: userDTO.Password,
+		Role:     userDTO.Role,
 	}
 }
 
@@ -35,7 +46,30 @@ func (userDO *UserDO) Transfer() *UserDTO {
 		Email:    userDO.Email,
 		Name:     userDO.Name,
 		Password: userDO.Password,
+		Role:     userDO.Role,
+	}
+}
+
+// HasRole 判断用户是否拥有给定角色之一,管理员拥有所有角色
+func (userDTO *UserDTO) HasRole(roles ...string) bool {
+	if userDTO.Role == RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if userDTO.Role == role {
+			return true
+		}
 	}
+	return false
+}
+
+// IsValidRole 判断是否为已定义的角色
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleLibrarian, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
 }
 
 // GetUserByIdResponse 根据ID获取用户的返回体
@@ -74,3 +108,8 @@ type ChangePasswordRequest struct {
 type ChangePasswordResponse struct {
 	BaseResp
 }
+
+// SetUserRoleRequest 管理员修改用户角色的请求体
+type SetUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
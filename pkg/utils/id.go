@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"math/big"
+
 	"github.com/google/uuid"
 )
 
@@ -8,3 +11,16 @@ import (
 func GenerateUUID() string {
 	return uuid.New().String()
 }
+
+// GenerateDigitCode 生成n位随机数字码
+func GenerateDigitCode(n int) string {
+	code := make([]byte, n)
+	for i := range code {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			d = big.NewInt(0)
+		}
+		code[i] = byte('0' + d.Int64())
+	}
+	return string(code)
+}
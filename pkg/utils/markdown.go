@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 支持的Markdown子集:
+//   块级: 段落(单个换行保留为<br>), # 标题, > 引用, - / * / + 无序列表, 1. 有序列表, ``` 代码块, --- 分隔线
+//   行内: **粗体**, *斜体* / _斜体_, ~~删除线~~, `代码`, [文字](链接), ![说明](站内图片), 裸露的http(s)链接, \ 转义
+// 输出的HTML只由渲染器生成, 用户输入一律转义, 不透传任何原始HTML:
+//   标签白名单: p br h1-h6 strong em del code pre blockquote ul ol li hr a img
+//   属性白名单: a的href/rel/target, img的src/alt/loading; 链接只允许http/https/mailto和站内路径, 图片只允许站内路径
+
+// maxQuoteDepth 引用的最大嵌套层数, 超出的部分按普通文本处理
+const maxQuoteDepth = 5
+
+var (
+	headingRe     = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	hrRe          = regexp.MustCompile(`^ {0,3}((-[ \t]*){3,}|(\*[ \t]*){3,}|(_[ \t]*){3,})$`)
+	unorderedRe   = regexp.MustCompile(`^ {0,3}[-*+][ \t]+(.*)$`)
+	orderedRe     = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)][ \t]+(.*)$`)
+	fenceRe       = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	quoteRe       = regexp.MustCompile(`^ {0,3}>[ \t]?(.*)$`)
+	blankLinesRe  = regexp.MustCompile(`\n{2,}`)
+	allowedScheme = regexp.MustCompile(`^(?i)(https?:|mailto:)`)
+)
+
+// RenderMarkdown 将Markdown渲染为经过白名单过滤的HTML
+func RenderMarkdown(src string) string {
+	r := &markdownRenderer{}
+	r.blocks(splitLines(src), 0)
+	return strings.TrimSpace(r.b.String())
+}
+
+// MarkdownToText 提取Markdown的纯文本, 用于搜索索引和摘要
+func MarkdownToText(src string) string {
+	r := &markdownRenderer{text: true}
+	r.blocks(splitLines(src), 0)
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(r.b.String(), "\n"))
+}
+
+// TruncateText 按字符截断文本, 截断时加省略号
+func TruncateText(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// markdownRenderer text为true时只输出纯文本, 否则输出HTML
+type markdownRenderer struct {
+	text bool
+	b    strings.Builder
+}
+
+func splitLines(src string) []string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	return strings.Split(src, "\n")
+}
+
+// tag 输出标签, 纯文本模式下忽略
+func (r *markdownRenderer) tag(s string) {
+	if !r.text {
+		r.b.WriteString(s)
+	}
+}
+
+// blockEnd 结束一个块, 纯文本模式下用换行分隔
+func (r *markdownRenderer) blockEnd(s string) {
+	if r.text {
+		r.b.WriteString("\n")
+	} else {
+		r.b.WriteString(s)
+	}
+}
+
+func (r *markdownRenderer) blocks(lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+		case fenceRe.MatchString(line):
+			fence := fenceRe.FindStringSubmatch(line)[1]
+			var code []string
+			i++
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+				code = append(code, lines[i])
+				i++
+			}
+			i++ // 跳过结束标记, 没有结束标记时代码块到文末为止
+			r.tag("<pre><code>")
+			r.b.WriteString(r.escape(strings.Join(code, "\n")))
+			r.blockEnd("</code></pre>\n")
+		case headingRe.MatchString(trimmed):
+			m := headingRe.FindStringSubmatch(trimmed)
+			level := strconv.Itoa(len(m[1]))
+			r.tag("<h" + level + ">")
+			r.inline(m[2])
+			r.blockEnd("</h" + level + ">\n")
+			i++
+		case hrRe.MatchString(line):
+			r.tag("<hr>")
+			r.blockEnd("\n")
+			i++
+		case quoteRe.MatchString(line) && depth < maxQuoteDepth:
+			var quoted []string
+			for i < len(lines) && quoteRe.MatchString(lines[i]) {
+				quoted = append(quoted, quoteRe.FindStringSubmatch(lines[i])[1])
+				i++
+			}
+			r.tag("<blockquote>\n")
+			r.blocks(quoted, depth+1)
+			r.blockEnd("</blockquote>\n")
+		case unorderedRe.MatchString(line):
+			i = r.list(lines, i, unorderedRe, 1, "ul")
+		case orderedRe.MatchString(line):
+			i = r.list(lines, i, orderedRe, 2, "ol")
+		default:
+			// 段落一直延续到空行或其他块开始
+			var para []string
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" && (len(para) == 0 || !startsBlock(lines[i], depth)) {
+				para = append(para, strings.TrimSpace(lines[i]))
+				i++
+			}
+			r.tag("<p>")
+			for j, p := range para {
+				if j > 0 {
+					r.tag("<br>")
+					r.b.WriteString("\n")
+				}
+				r.inline(p)
+			}
+			r.blockEnd("</p>\n")
+		}
+	}
+}
+
+// startsBlock 该行是否会开始一个新的块
+func startsBlock(line string, depth int) bool {
+	return fenceRe.MatchString(line) || headingRe.MatchString(strings.TrimSpace(line)) || hrRe.MatchString(line) ||
+		(quoteRe.MatchString(line) && depth < maxQuoteDepth) || unorderedRe.MatchString(line) || orderedRe.MatchString(line)
+}
+
+// list 渲染连续的列表项, 缩进的后续行并入上一项; 返回列表之后的行号
+func (r *markdownRenderer) list(lines []string, i int, itemRe *regexp.Regexp, group int, tag string) int {
+	r.tag("<" + tag + ">\n")
+	for i < len(lines) && itemRe.MatchString(lines[i]) {
+		item := itemRe.FindStringSubmatch(lines[i])[group]
+		i++
+		for i < len(lines) && strings.TrimSpace(lines[i]) != "" && (lines[i][0] == ' ' || lines[i][0] == '\t') &&
+			!itemRe.MatchString(lines[i]) {
+			item += " " + strings.TrimSpace(lines[i])
+			i++
+		}
+		r.tag("<li>")
+		r.inline(item)
+		r.blockEnd("</li>\n")
+	}
+	r.tag("</" + tag + ">\n")
+	return i
+}
+
+func (r *markdownRenderer) escape(s string) string {
+	if r.text {
+		return s
+	}
+	return html.EscapeString(s)
+}
+
+// inline 渲染行内元素
+func (r *markdownRenderer) inline(s string) {
+	for len(s) > 0 {
+		switch {
+		case s[0] == '\\' && len(s) > 1 && strings.ContainsRune("\\`*_~[]()#+-.!>|", rune(s[1])):
+			r.b.WriteString(r.escape(s[1:2]))
+			s = s[2:]
+			continue
+		case s[0] == '`':
+			run := len(s) - len(strings.TrimLeft(s, "`"))
+			if end := strings.Index(s[run:], s[:run]); end >= 0 {
+				r.tag("<code>")
+				r.b.WriteString(r.escape(strings.TrimSpace(s[run : run+end])))
+				r.tag("</code>")
+				s = s[run+end+run:]
+				continue
+			}
+		case strings.HasPrefix(s, "**") || strings.HasPrefix(s, "__"):
+			if rest, ok := r.span(s, s[:2], "strong"); ok {
+				s = rest
+				continue
+			}
+		case strings.HasPrefix(s, "~~"):
+			if rest, ok := r.span(s, "~~", "del"); ok {
+				s = rest
+				continue
+			}
+		case s[0] == '*' || s[0] == '_':
+			if rest, ok := r.span(s, s[:1], "em"); ok {
+				s = rest
+				continue
+			}
+		case strings.HasPrefix(s, "!["):
+			if rest, ok := r.image(s); ok {
+				s = rest
+				continue
+			}
+		case s[0] == '[':
+			if rest, ok := r.link(s); ok {
+				s = rest
+				continue
+			}
+		case strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://"):
+			end := strings.IndexFunc(s, func(c rune) bool {
+				return unicode.IsSpace(c) || c == '<' || c == '>' || c == '"'
+			})
+			if end < 0 {
+				end = len(s)
+			}
+			url := strings.TrimRight(s[:end], ".,;:!?)")
+			r.anchor(url, func() { r.b.WriteString(r.escape(url)) })
+			s = s[len(url):]
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s)
+		r.b.WriteString(r.escape(s[:size]))
+		s = s[size:]
+	}
+}
+
+// span 渲染由delim包住的行内元素, 找不到结束标记或内容为空时返回false
+func (r *markdownRenderer) span(s, delim, tag string) (string, bool) {
+	inner := s[len(delim):]
+	end := strings.Index(inner, delim)
+	if end <= 0 || inner[0] == ' ' || inner[end-1] == ' ' {
+		return s, false
+	}
+	r.tag("<" + tag + ">")
+	r.inline(inner[:end])
+	r.tag("</" + tag + ">")
+	return inner[end+len(delim):], true
+}
+
+// image 渲染![说明](图片), 只内嵌站内图片避免外链追踪, 站外图片按链接处理
+func (r *markdownRenderer) image(s string) (string, bool) {
+	text, url, rest, ok := splitLink(s[1:])
+	if !ok {
+		return s, false
+	}
+	switch {
+	case !localURL(url):
+		if safeURL(url) {
+			r.anchor(url, func() { r.b.WriteString(r.escape(text)) })
+		} else {
+			r.b.WriteString(r.escape(text))
+		}
+	case r.text:
+		r.b.WriteString(text)
+	default:
+		r.b.WriteString(`<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(text) + `" loading="lazy">`)
+	}
+	return rest, true
+}
+
+// link 渲染[文字](链接), 不允许的链接只保留文字
+func (r *markdownRenderer) link(s string) (string, bool) {
+	text, url, rest, ok := splitLink(s)
+	if !ok {
+		return s, false
+	}
+	if safeURL(url) {
+		r.anchor(url, func() { r.inline(text) })
+	} else {
+		r.inline(text)
+	}
+	return rest, true
+}
+
+// splitLink 拆分[文字](链接), 返回文字、链接和之后的内容
+func splitLink(s string) (string, string, string, bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 0 {
+		return "", "", s, false
+	}
+	// 链接中允许成对的括号
+	closeUrl, depth := -1, 0
+	for i, c := range s[closeText+2:] {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth == 0 {
+				closeUrl = i
+				break
+			}
+			depth--
+		}
+	}
+	if closeUrl < 0 {
+		return "", "", s, false
+	}
+	text := s[1:closeText]
+	url := strings.TrimSpace(s[closeText+2 : closeText+2+closeUrl])
+	return text, url, s[closeText+2+closeUrl+1:], true
+}
+
+func (r *markdownRenderer) anchor(url string, text func()) {
+	r.tag(`<a href="` + html.EscapeString(url) + `" rel="nofollow noopener noreferrer" target="_blank">`)
+	text()
+	r.tag("</a>")
+}
+
+// safeURL 链接只允许http/https/mailto和站内路径
+func safeURL(url string) bool {
+	if url == "" || strings.ContainsFunc(url, unsafeURLRune) {
+		return false
+	}
+	return allowedScheme.MatchString(url) || localURL(url)
+}
+
+// localURL 是否为站内路径; 浏览器会把/\开头的地址同//一样当作协议相对地址, 所以也不算站内
+func localURL(url string) bool {
+	if !strings.HasPrefix(url, "/") || strings.HasPrefix(url, "//") || strings.HasPrefix(url, "/\\") {
+		return false
+	}
+	return !strings.ContainsFunc(url, unsafeURLRune) && !strings.Contains(url, "\\")
+}
+
+// unsafeURLRune 链接中不允许的字符: 空白、控制字符(浏览器解析地址时会忽略)和会破坏属性的引号尖括号
+func unsafeURLRune(r rune) bool {
+	return r <= ' ' || r == 0x7f || strings.ContainsRune("<>\"'`", r)
+}
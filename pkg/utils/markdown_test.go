@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"paragraph with line break", "text\nline2", "<p>text<br>\nline2</p>"},
+		{"heading", "# T", "<h1>T</h1>"},
+		{"inline styles", "**b** *i* ~~d~~ `c`", "<p><strong>b</strong> <em>i</em> <del>d</del> <code>c</code></p>"},
+		{"unordered list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>"},
+		{"ordered list", "1. a\n2. b", "<ol>\n<li>a</li>\n<li>b</li>\n</ol>"},
+		{"horizontal rule", "---", "<hr>"},
+		{"code block is escaped", "```\n<b>\n```", "<pre><code>&lt;b&gt;</code></pre>"},
+		{"link", "[ok](https://a.com/?q=1&r=2)", `<p><a href="https://a.com/?q=1&amp;r=2" rel="nofollow noopener noreferrer" target="_blank">ok</a></p>`},
+		{"local image", "![a](/img/a.png)", `<p><img src="/img/a.png" alt="a" loading="lazy"></p>`},
+		{"external image becomes link", "![x](https://evil.com/a.png)", `<p><a href="https://evil.com/a.png" rel="nofollow noopener noreferrer" target="_blank">x</a></p>`},
+		{"escape", `\*not em\*`, "<p>*not em*</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.src); got != tt.want {
+				t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownSanitizes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"mixed case javascript link", "[x](JaVaScRiPt:alert(1))", "<p>x</p>"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>"},
+		{"data image", "![x](data:image/png;base64,AAAA)", "<p>x</p>"},
+		{"vbscript link", "[x](vbscript:msgbox(1))", "<p>x</p>"},
+		{"protocol relative link", "[x](//evil.com)", "<p>x</p>"},
+		{"backslash protocol relative link", `[x](/\evil.com)`, "<p>x</p>"},
+		{"backslash protocol relative image", `![x](/\evil.com/a.png)`, "<p>x</p>"},
+		{"tab in local link", "[x](/\t/evil.com)", "<p>x</p>"},
+		{"quote breaking out of href", `[x](/a"onmouseover="alert(1))`, "<p>x</p>"},
+		{"quote in alt", `![a"b](/img/a.png)`, `<p><img src="/img/a.png" alt="a&#34;b" loading="lazy"></p>`},
+		{"raw script", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"raw img", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{"autolink stops at quote", `http://a.com/"><script>`, `<p><a href="http://a.com/" rel="nofollow noopener noreferrer" target="_blank">http://a.com/</a>&#34;&gt;&lt;script&gt;</p>`},
+		{"raw html in heading", "# <b onclick=x>", "<h1>&lt;b onclick=x&gt;</h1>"},
+		{"raw html in link text", "[<i>x</i>](https://a.com)", `<p><a href="https://a.com" rel="nofollow noopener noreferrer" target="_blank">&lt;i&gt;x&lt;/i&gt;</a></p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.src); got != tt.want {
+				t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownQuoteDepth(t *testing.T) {
+	src := strings.Repeat("> ", maxQuoteDepth+2) + "deep"
+	got := RenderMarkdown(src)
+	if n := strings.Count(got, "<blockquote>"); n != maxQuoteDepth {
+		t.Errorf("got %d nested blockquotes, want %d: %q", n, maxQuoteDepth, got)
+	}
+	if !strings.Contains(got, "<p>&gt; &gt; deep</p>") {
+		t.Errorf("quote markers beyond the max depth should stay as text: %q", got)
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://a.com", true},
+		{"HTTP://a.com", true},
+		{"mailto:a@b.com", true},
+		{"/api/forum/attachments/k", true},
+		{"", false},
+		{"javascript:alert(1)", false},
+		{"data:text/html,x", false},
+		{"//evil.com", false},
+		{`/\evil.com`, false},
+		{`/a\b`, false},
+		{"/a\x00b", false},
+		{"/a b", false},
+		{"relative/path", false},
+	}
+	for _, tt := range tests {
+		if got := safeURL(tt.url); got != tt.want {
+			t.Errorf("safeURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownToText(t *testing.T) {
+	if got, want := MarkdownToText("# T\n\n**b** [l](https://x)"), "T\nb l"; got != want {
+		t.Errorf("MarkdownToText = %q, want %q", got, want)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 3, "abc"},
+		{"abcd", 3, "abc…"},
+		{"读书会活动", 2, "读书…"},
+	}
+	for _, tt := range tests {
+		if got := TruncateText(tt.s, tt.n); got != tt.want {
+			t.Errorf("TruncateText(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}